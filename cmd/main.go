@@ -4,11 +4,21 @@ import (
 	"database/sql"
 	"log"
 	"mail_helper_bot/internal/bot"
+	digestRepository "mail_helper_bot/internal/pkg/digest/repository"
+	"mail_helper_bot/internal/pkg/digest/scheduler"
 	groupPostgres "mail_helper_bot/internal/pkg/group/repository"
+	"mail_helper_bot/internal/pkg/media"
+	"mail_helper_bot/internal/pkg/notify/notify_service"
+	notifyRepository "mail_helper_bot/internal/pkg/notify/repository"
 	"mail_helper_bot/internal/pkg/oauth/oauth_service"
 	"mail_helper_bot/internal/pkg/session/postgres_storage"
+	queueRepository "mail_helper_bot/internal/pkg/upload_queue/repository"
+	"mail_helper_bot/internal/pkg/upload_queue/worker"
 	"mail_helper_bot/internal/pkg/web_server/web_server_service"
 	"os"
+	"strconv"
+	// Часовые пояса сводок не зависят от базы поясов в образе
+	_ "time/tzdata"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -38,6 +48,32 @@ func main() {
 
 	redirectURI := baseURL + "/oauth/callback/"
 
+	// Сервер Bot API: публичный по умолчанию или свой, в том числе в режиме --local
+	botAPILocal := false
+	if v := os.Getenv("TELEGRAM_API_LOCAL"); v != "" {
+		botAPILocal, err = strconv.ParseBool(v)
+		if err != nil {
+			log.Fatalf("invalid TELEGRAM_API_LOCAL: %v", err)
+		}
+	}
+	botAPIConfig := media.NewBotAPIConfig(os.Getenv("TELEGRAM_API_URL"), botAPILocal)
+
+	uploadWorkers := 4
+	if v := os.Getenv("UPLOAD_WORKERS"); v != "" {
+		uploadWorkers, err = strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("invalid UPLOAD_WORKERS: %v", err)
+		}
+	}
+
+	notifyMaxPerHour := notify_service.DefaultMaxPerHour
+	if v := os.Getenv("NOTIFY_MAX_PER_HOUR"); v != "" {
+		notifyMaxPerHour, err = strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("invalid NOTIFY_MAX_PER_HOUR: %v", err)
+		}
+	}
+
 	dbConnStr := os.Getenv("POSTGRES_DSN")
 	if dbConnStr == "" {
 		dbConnStr = "postgres://mail_bot:mail_bot_pass@db:5432/mail_helper?sslmode=disable"
@@ -53,6 +89,9 @@ func main() {
 	// ----------------- Storage -----------------
 	storage := postgres_storage.NewPostgresStorage(db)
 	groupStorage := groupPostgres.NewGroupStorage(db)
+	queueStorage := queueRepository.NewQueueStorage(db)
+	notificationStorage := notifyRepository.NewNotificationStorage(db)
+	digestStorage := digestRepository.NewDigestStorage(db)
 
 	// ----------------- OAuth Service -----------------
 	oauthService := oauth_service.NewOAuthService(
@@ -63,11 +102,31 @@ func main() {
 	)
 
 	// ----------------- Bot -----------------
-	b := bot.New(token, storage, groupStorage)
+	b := bot.New(token, botAPIConfig, storage, groupStorage, queueStorage)
 	b.SetOAuthService(oauthService)
 
+	notifier := notify_service.NewNotifier(b.Api, notificationStorage, oauthService)
+	notifier.SetMaxPerHour(notifyMaxPerHour)
+	b.SetNotifier(notifier)
+	b.SetDigestRepository(digestStorage)
+	if importDir := os.Getenv("IMPORT_DIR"); importDir != "" {
+		b.SetImportDir(importDir)
+	}
+
+	// ----------------- Upload workers -----------------
+	uploadPool := worker.NewPool(queueStorage, b.ProcessUploadJob, uploadWorkers)
+	b.SetUploadPool(uploadPool)
+	uploadPool.Start()
+	defer uploadPool.Stop()
+
+	// ----------------- Digests -----------------
+	digestScheduler := scheduler.NewScheduler(digestStorage, b.SendDigest)
+	digestScheduler.Start()
+	defer digestScheduler.Stop()
+
 	// ----------------- Web server -----------------
 	webServer := web_server_service.NewWebServer(oauthService, b.Api, webPort)
+	webServer.SetLoginHandler(b.HandleOwnerLogin)
 	go func() {
 		if err := webServer.Start(); err != nil {
 			log.Fatalf("Failed to start web server: %v", err)
@@ -4,13 +4,42 @@ import (
 	"fmt"
 	"log"
 	"mail_helper_bot/internal/pkg/mock-api/handlers"
+	"mail_helper_bot/internal/pkg/mock-api/storage"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
 )
 
 func main() {
+	// Хранилище загруженных файлов
+	storageDir := os.Getenv("MOCK_STORAGE_DIR")
+	if storageDir == "" {
+		storageDir = filepath.Join(os.TempDir(), "mock_cloud")
+	}
+
+	store, err := storage.NewBlobStore(storageDir)
+	if err != nil {
+		log.Fatalf("failed to init blob store: %v", err)
+	}
+
+	// Место в облаке: при переполнении add отвечает 507
+	if quota := os.Getenv("MOCK_QUOTA_BYTES"); quota != "" {
+		bytes, err := strconv.ParseInt(quota, 10, 64)
+		if err != nil {
+			log.Fatalf("invalid MOCK_QUOTA_BYTES: %v", err)
+		}
+		store.SetQuota(bytes)
+	}
+	handlers.SetBlobStore(store)
+
 	// Настройка маршрутов
+	http.HandleFunc("/upload/", handlers.UploadHandler)
 	http.HandleFunc("/api/v1/private/mkdir/", handlers.MkdirHandler)
 	http.HandleFunc("/api/v1/private/add", handlers.AddHandler)
+	http.HandleFunc("/api/v1/private/file/", handlers.FileHandler)
+	http.HandleFunc("/api/v1/private/download/", handlers.DownloadHandler)
+	http.HandleFunc("/api/v1/private/remove/", handlers.RemoveHandler)
 	http.HandleFunc("/api/v1/private/share/", handlers.ShareHandler)
 	http.HandleFunc("/api/v1/private/unshare/", handlers.UnshareHandler)
 
@@ -26,8 +55,12 @@ func main() {
 	port := ":8082"
 	fmt.Printf("Mock API Server запущен на порту %s\n", port)
 	fmt.Println("Доступные эндпоинты:")
+	fmt.Println("   PUT  /upload/")
 	fmt.Println("   POST /api/v1/private/mkdir/{path}")
 	fmt.Println("   POST /api/v1/private/add")
+	fmt.Println("   GET  /api/v1/private/file/{path}")
+	fmt.Println("   GET  /api/v1/private/download/{path}")
+	fmt.Println("   POST /api/v1/private/remove/{path}")
 	fmt.Println("   POST /api/v1/private/share/{path}")
 	fmt.Println("   POST /api/v1/private/unshare/{path}")
 	fmt.Println("   GET  /health")
@@ -0,0 +1,26 @@
+-- =====================================================
+-- ОЧЕРЕДЬ ЗАГРУЗОК
+-- =====================================================
+
+-- Задания на загрузку медиа в облако, которые разбирают фоновые воркеры
+CREATE TABLE IF NOT EXISTS upload_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    group_id BIGINT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('queued', 'running', 'done', 'failed')) DEFAULT 'queued',
+    payload JSONB NOT NULL,  -- Сериализованный media.MediaInfo
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    FOREIGN KEY (group_id) REFERENCES group_sessions(group_id) ON DELETE CASCADE
+    );
+
+-- Индексы для upload_jobs
+CREATE INDEX idx_upload_jobs_group_id ON upload_jobs(group_id);
+CREATE INDEX idx_upload_jobs_queued ON upload_jobs(id) WHERE status = 'queued';
+
+CREATE TRIGGER update_upload_jobs_updated_at
+    BEFORE UPDATE ON upload_jobs
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,26 @@
+-- =====================================================
+-- ПОВТОРЫ ЗАГРУЗОК И DEAD LETTER
+-- =====================================================
+
+-- Время, раньше которого задание не берется в работу (экспоненциальная задержка повторов)
+ALTER TABLE upload_jobs ADD COLUMN IF NOT EXISTS run_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now();
+
+DROP INDEX IF EXISTS idx_upload_jobs_queued;
+CREATE INDEX idx_upload_jobs_queued ON upload_jobs(run_at, id) WHERE status = 'queued';
+
+-- Загрузки, которые не удалось выполнить за все попытки
+CREATE TABLE IF NOT EXISTS upload_dead_letters (
+    id BIGSERIAL PRIMARY KEY,
+    job_id BIGINT NOT NULL UNIQUE,
+    group_id BIGINT NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL,
+    last_error TEXT,
+    permanent BOOLEAN NOT NULL DEFAULT FALSE,  -- Ошибка не временная, повторы не выполнялись
+    failed_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    FOREIGN KEY (job_id) REFERENCES upload_jobs(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_id) REFERENCES group_sessions(group_id) ON DELETE CASCADE
+    );
+
+-- Индексы для upload_dead_letters
+CREATE INDEX idx_upload_dead_letters_group_id ON upload_dead_letters(group_id);
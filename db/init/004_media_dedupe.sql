@@ -0,0 +1,27 @@
+-- =====================================================
+-- ДЕДУПЛИКАЦИЯ МЕДИА
+-- =====================================================
+
+-- Один и тот же файл может быть загружен в несколько групп,
+-- поэтому file_unique_id уникален только в пределах группы
+ALTER TABLE processed_media DROP CONSTRAINT IF EXISTS processed_media_file_unique_id_key;
+ALTER TABLE processed_media ADD CONSTRAINT processed_media_group_file_key UNIQUE (group_id, file_unique_id);
+
+-- SHA1 содержимого, загруженного в облако
+ALTER TABLE processed_media ADD COLUMN IF NOT EXISTS content_hash TEXT;
+
+CREATE INDEX idx_processed_media_content_hash ON processed_media(content_hash);
+
+-- Общий для всех групп индекс: file_unique_id Telegram -> SHA1 содержимого
+CREATE TABLE IF NOT EXISTS media_content_index (
+    file_unique_id TEXT PRIMARY KEY,
+    content_hash TEXT NOT NULL,
+    file_size_bytes BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+    );
+
+-- Не ставим в очередь файл, который уже ждет загрузки в эту группу
+ALTER TABLE upload_jobs ADD COLUMN IF NOT EXISTS file_unique_id TEXT;
+
+CREATE UNIQUE INDEX idx_upload_jobs_pending_file ON upload_jobs(group_id, file_unique_id)
+    WHERE status IN ('queued', 'running');
@@ -0,0 +1,19 @@
+-- =====================================================
+-- МЕТАДАННЫЕ ЗАГРУЖЕННЫХ МЕДИА
+-- =====================================================
+
+ALTER TABLE processed_media
+    ADD COLUMN IF NOT EXISTS cloud_path TEXT,           -- Итоговый путь файла в облаке
+    ADD COLUMN IF NOT EXISTS mime_type TEXT,
+    ADD COLUMN IF NOT EXISTS width INT,
+    ADD COLUMN IF NOT EXISTS height INT,
+    ADD COLUMN IF NOT EXISTS duration_seconds INT,
+    ADD COLUMN IF NOT EXISTS message_id BIGINT,         -- ID сообщения в группе
+    ADD COLUMN IF NOT EXISTS message_date TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS sender_id BIGINT,          -- Telegram ID отправителя
+    ADD COLUMN IF NOT EXISTS sender_name TEXT,
+    ADD COLUMN IF NOT EXISTS caption TEXT;
+
+-- Индексы для processed_media
+CREATE INDEX idx_processed_media_group_sender ON processed_media(group_id, sender_id);
+CREATE INDEX idx_processed_media_group_message ON processed_media(group_id, message_id);
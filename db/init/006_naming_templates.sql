@@ -0,0 +1,13 @@
+-- =====================================================
+-- ШАБЛОНЫ ИМЕН ФАЙЛОВ
+-- =====================================================
+
+-- Шаблон имени файла группы, NULL - шаблон по умолчанию
+ALTER TABLE group_sessions ADD COLUMN IF NOT EXISTS naming_template TEXT;
+
+-- Путь в облаке, зарезервированный заданием, для разрешения коллизий имен
+ALTER TABLE upload_jobs ADD COLUMN IF NOT EXISTS cloud_path TEXT;
+
+CREATE INDEX idx_upload_jobs_group_cloud_path ON upload_jobs(group_id, cloud_path)
+    WHERE status IN ('queued', 'running');
+CREATE INDEX idx_processed_media_group_cloud_path ON processed_media(group_id, cloud_path);
@@ -0,0 +1,6 @@
+-- =====================================================
+-- РАСКЛАДКА ФАЙЛОВ ПО ПОДПАПКАМ
+-- =====================================================
+
+ALTER TABLE group_sessions ADD COLUMN IF NOT EXISTS folder_layout TEXT NOT NULL DEFAULT 'flat'
+    CHECK (folder_layout IN ('flat', 'year_month', 'date', 'sender', 'media_type'));
@@ -0,0 +1,26 @@
+-- =====================================================
+-- АЛЬБОМЫ (media_group_id)
+-- =====================================================
+
+-- Складывать ли альбомы в отдельную подпапку по подписи
+ALTER TABLE group_sessions ADD COLUMN IF NOT EXISTS album_subfolders BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Альбом группы и счетчики его загрузки для итогового уведомления
+CREATE TABLE IF NOT EXISTS media_albums (
+    group_id BIGINT NOT NULL REFERENCES group_sessions(group_id) ON DELETE CASCADE,
+    media_group_id TEXT NOT NULL,
+    caption TEXT,
+    folder_path TEXT NOT NULL,
+    collecting BOOLEAN NOT NULL DEFAULT TRUE,    -- Задания альбома еще ставятся в очередь
+    expected_count INTEGER NOT NULL DEFAULT 0,
+    uploaded_count INTEGER NOT NULL DEFAULT 0,
+    failed_count INTEGER NOT NULL DEFAULT 0,
+    summary_sent BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    PRIMARY KEY (group_id, media_group_id)
+);
+
+ALTER TABLE processed_media ADD COLUMN IF NOT EXISTS album_id TEXT;
+
+CREATE INDEX idx_processed_media_album ON processed_media(group_id, album_id) WHERE album_id IS NOT NULL;
@@ -0,0 +1,25 @@
+-- =====================================================
+-- КАТЕГОРИИ МЕДИА
+-- =====================================================
+
+-- group_sessions.media_type - список категорий через запятую вместо photos/videos/all.
+-- Прежнее значение all загружало только фото и видео, поэтому переводится в photo,video.
+ALTER TABLE group_sessions DROP CONSTRAINT IF EXISTS group_sessions_media_type_check;
+
+UPDATE group_sessions
+SET media_type = CASE media_type
+                     WHEN 'photos' THEN 'photo'
+                     WHEN 'videos' THEN 'video'
+                     WHEN 'all' THEN 'photo,video'
+                     ELSE media_type
+                 END;
+
+ALTER TABLE group_sessions ALTER COLUMN media_type SET DEFAULT 'photo';
+ALTER TABLE group_sessions ADD CONSTRAINT group_sessions_media_type_check CHECK (
+    media_type ~ '^(photo|video|animation|video_note|audio|voice|document)(,(photo|video|animation|video_note|audio|voice|document))*$'
+);
+
+ALTER TABLE processed_media DROP CONSTRAINT IF EXISTS processed_media_media_type_check;
+ALTER TABLE processed_media ADD CONSTRAINT processed_media_media_type_check CHECK (
+    media_type IN ('photo', 'video', 'animation', 'video_note', 'audio', 'voice', 'document')
+);
@@ -0,0 +1,6 @@
+-- =====================================================
+-- ПРАВИЛА ФИЛЬТРАЦИИ МЕДИА
+-- =====================================================
+
+-- Структура правил - filter.Rules, пустой объект ничего не ограничивает
+ALTER TABLE group_sessions ADD COLUMN IF NOT EXISTS filter_rules JSONB NOT NULL DEFAULT '{}'::jsonb;
@@ -0,0 +1,9 @@
+-- =====================================================
+-- МАРШРУТИЗАЦИЯ ПО ХЕШТЕГАМ И ПОДПИСЯМ
+-- =====================================================
+
+-- Структура правил - routing.Rules, пустой объект не меняет папку медиа
+ALTER TABLE group_sessions ADD COLUMN IF NOT EXISTS routing_rules JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+-- Сработавшее правило: #тег, prefix:<префикс> или default
+ALTER TABLE processed_media ADD COLUMN IF NOT EXISTS matched_route TEXT;
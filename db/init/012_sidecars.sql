@@ -0,0 +1,12 @@
+-- =====================================================
+-- ФАЙЛЫ-СПУТНИКИ И МАНИФЕСТ ПАПКИ
+-- =====================================================
+
+-- Формат спутника рядом с каждым файлом: json, txt или NULL - не писать
+ALTER TABLE group_sessions ADD COLUMN IF NOT EXISTS sidecar_format TEXT;
+
+-- Вести index.json со всеми файлами в каждой папке группы
+ALTER TABLE group_sessions ADD COLUMN IF NOT EXISTS folder_index BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Описание файла с контекстом сообщения (sidecar.Record), из него собирается index.json
+ALTER TABLE processed_media ADD COLUMN IF NOT EXISTS sidecar JSONB;
@@ -0,0 +1,9 @@
+-- =====================================================
+-- ПРИВАТНОСТЬ: МЕТАДАННЫЕ ИЗОБРАЖЕНИЙ
+-- =====================================================
+
+-- Убирать из JPEG/PNG геолокацию, данные устройства и прочие метаданные перед загрузкой
+ALTER TABLE group_sessions ADD COLUMN IF NOT EXISTS strip_metadata BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Хранить оригиналы с метаданными в отдельной папке без публичной ссылки
+ALTER TABLE group_sessions ADD COLUMN IF NOT EXISTS keep_originals BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,11 @@
+-- =====================================================
+-- ВЕРСИИ ОТРЕДАКТИРОВАННЫХ МЕДИА
+-- =====================================================
+
+-- Новая версия медиа: version - рядом с прошлой с суффиксом _vN, replace - вместо нее
+ALTER TABLE group_sessions ADD COLUMN IF NOT EXISTS edit_mode TEXT NOT NULL DEFAULT 'version'
+    CHECK (edit_mode IN ('version', 'replace'));
+
+-- Цепочка версий медиа одного сообщения
+ALTER TABLE processed_media ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE processed_media ADD COLUMN IF NOT EXISTS previous_id INTEGER REFERENCES processed_media(id) ON DELETE SET NULL;
@@ -0,0 +1,15 @@
+-- =====================================================
+-- ТЕМЫ ФОРУМОВ (message_thread_id)
+-- =====================================================
+
+-- Тема форум-группы: название для подпапки и собственные настройки загрузки
+CREATE TABLE IF NOT EXISTS group_topics (
+    group_id BIGINT NOT NULL REFERENCES group_sessions(group_id) ON DELETE CASCADE,
+    thread_id INTEGER NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    media_type TEXT NOT NULL DEFAULT '',    -- Пусто - категории группы
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    PRIMARY KEY (group_id, thread_id)
+);
@@ -0,0 +1,6 @@
+-- =====================================================
+-- КАНАЛЫ (channel_post)
+-- =====================================================
+
+-- Канал подключается из личного чата; уведомления о нем получает владелец
+ALTER TABLE group_sessions ADD COLUMN IF NOT EXISTS is_channel BOOLEAN NOT NULL DEFAULT FALSE;
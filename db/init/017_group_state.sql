@@ -0,0 +1,11 @@
+-- =====================================================
+-- ПАУЗА И ПРОБНЫЙ РЕЖИМ
+-- =====================================================
+
+-- active - загрузка, paused - медиа не принимаются, dry_run - медиа только учитываются
+ALTER TABLE group_sessions ADD COLUMN IF NOT EXISTS state TEXT NOT NULL DEFAULT 'active'
+    CHECK (state IN ('active', 'paused', 'dry_run'));
+
+-- Что было бы загружено с последнего включения пробного режима
+ALTER TABLE group_sessions ADD COLUMN IF NOT EXISTS dry_run_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE group_sessions ADD COLUMN IF NOT EXISTS dry_run_bytes BIGINT NOT NULL DEFAULT 0;
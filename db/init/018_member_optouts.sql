@@ -0,0 +1,11 @@
+-- =====================================================
+-- ОТКАЗ УЧАСТНИКОВ ОТ ВЫГРУЗКИ
+-- =====================================================
+
+-- Участник группы, чьи медиа не загружаются в облако владельца
+CREATE TABLE IF NOT EXISTS member_optouts (
+    group_id BIGINT NOT NULL REFERENCES group_sessions(group_id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    PRIMARY KEY (group_id, user_id)
+);
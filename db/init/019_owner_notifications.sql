@@ -0,0 +1,19 @@
+-- =====================================================
+-- УВЕДОМЛЕНИЯ ВЛАДЕЛЬЦАМ
+-- =====================================================
+
+-- Последнее отправленное уведомление каждого вида: по нему повторы
+-- одного и того же события не отправляются чаще заданного интервала
+CREATE TABLE IF NOT EXISTS owner_notifications (
+    owner_chat_id BIGINT NOT NULL,
+    kind TEXT NOT NULL,
+    dedupe_key TEXT NOT NULL DEFAULT '',
+    -- NULL - событие было, но уведомление не отправлено из-за общего лимита
+    last_sent_at TIMESTAMP WITH TIME ZONE,
+    -- Сколько таких событий пропущено с последнего отправленного уведомления
+    suppressed_count INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (owner_chat_id, kind, dedupe_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_owner_notifications_sent
+    ON owner_notifications (owner_chat_id, last_sent_at);
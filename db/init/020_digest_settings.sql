@@ -0,0 +1,23 @@
+-- =====================================================
+-- ДАЙДЖЕСТЫ ВЛАДЕЛЬЦАМ
+-- =====================================================
+
+-- Расписание сводки по группам владельца
+CREATE TABLE IF NOT EXISTS user_digest_settings (
+    chat_id BIGINT PRIMARY KEY,
+    -- off, daily или weekly
+    frequency TEXT NOT NULL DEFAULT 'off',
+    -- Час отправки по местному времени владельца
+    send_hour INTEGER NOT NULL DEFAULT 9,
+    -- День недели еженедельной сводки: 0 - воскресенье, как в time.Weekday
+    weekday INTEGER NOT NULL DEFAULT 1,
+    -- Имя из базы часовых поясов IANA или смещение вида UTC+3
+    timezone TEXT NOT NULL DEFAULT 'Europe/Moscow',
+    last_sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE TRIGGER update_user_digest_settings_updated_at
+    BEFORE UPDATE ON user_digest_settings
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,11 @@
+-- =====================================================
+-- ФАЙЛЫ ПРОБНОГО РЕЖИМА
+-- =====================================================
+
+-- Файлы, уже учтенные в счетчиках пробного режима: повторно присланный
+-- файл не увеличивает dry_run_count и dry_run_bytes
+CREATE TABLE IF NOT EXISTS dry_run_media (
+    group_id BIGINT NOT NULL REFERENCES group_sessions(group_id) ON DELETE CASCADE,
+    file_unique_id TEXT NOT NULL,
+    PRIMARY KEY (group_id, file_unique_id)
+);
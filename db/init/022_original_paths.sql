@@ -0,0 +1,7 @@
+-- =====================================================
+-- ПУТИ ОРИГИНАЛОВ
+-- =====================================================
+
+-- Где лежит оригинал с метаданными, если файл загружен очищенным и группа
+-- хранит оригиналы. Нужен, чтобы удалять оригинал вместе с файлом.
+ALTER TABLE processed_media ADD COLUMN IF NOT EXISTS original_path TEXT;
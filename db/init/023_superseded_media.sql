@@ -0,0 +1,15 @@
+-- =====================================================
+-- ЗАМЕНЕННЫЕ ВЕРСИИ МЕДИА
+-- =====================================================
+
+-- Версия, файл которой перезаписан следующей версией в режиме replace.
+-- Ее содержимого в облаке больше нет: она не учитывается в статистике,
+-- index.json и поиске дубликатов, но остается в цепочке версий.
+ALTER TABLE processed_media ADD COLUMN IF NOT EXISTS superseded BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE processed_media pm
+SET superseded = TRUE
+WHERE EXISTS (
+    SELECT 1 FROM processed_media next
+    WHERE next.previous_id = pm.id AND next.cloud_path = pm.cloud_path
+);
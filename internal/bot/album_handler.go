@@ -0,0 +1,278 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mail_helper_bot/internal/pkg/group/domain"
+	"mail_helper_bot/internal/pkg/media"
+	"mail_helper_bot/internal/pkg/naming"
+)
+
+// albumCollectWindow - сколько ждать следующую часть альбома перед постановкой в очередь
+const albumCollectWindow = 2 * time.Second
+
+// maxAlbumFolderName - ограничение длины имени папки альбома в символах
+const maxAlbumFolderName = 60
+
+// pendingAlbum - альбом, части которого еще приходят
+type pendingAlbum struct {
+	group  *domain.GroupSession
+	items  []*media.MediaInfo
+	timer  *time.Timer
+	stored *domain.MediaAlbum // Сохраненный при первой части альбом
+	late   bool               // Альбом уже ставился в очередь, это поздние части
+}
+
+// collectAlbumItem добавляет часть альбома и откладывает его постановку в очередь,
+// пока части приходят чаще, чем раз в albumCollectWindow
+func (b *Bot) collectAlbumItem(group *domain.GroupSession, mediaInfo *media.MediaInfo) {
+	key := fmt.Sprintf("%d:%s", group.GroupID, mediaInfo.AlbumID)
+
+	b.albumsMu.Lock()
+	if album, ok := b.albums[key]; ok {
+		// Пока альбом сохраняется, таймера еще нет - он запустится после
+		if album.timer != nil {
+			album.timer.Reset(albumCollectWindow)
+		}
+		album.items = append(album.items, mediaInfo)
+		b.albumsMu.Unlock()
+		return
+	}
+	album := &pendingAlbum{group: group, items: []*media.MediaInfo{mediaInfo}}
+	b.albums[key] = album
+	b.albumsMu.Unlock()
+
+	// Запрос к базе идет без блокировки, чтобы не задерживать другие альбомы
+	stored, late := b.openAlbum(group, mediaInfo)
+
+	b.albumsMu.Lock()
+	album.stored, album.late = stored, late
+	album.timer = time.AfterFunc(albumCollectWindow, func() { b.flushAlbum(key, album) })
+	b.albumsMu.Unlock()
+}
+
+// openAlbum сохраняет альбом при первой части, чтобы части, пришедшие после
+// постановки альбома в очередь или после перезапуска бота, попали в его папку,
+// а не в новый альбом. Возвращает true, если альбом уже был сохранен.
+func (b *Bot) openAlbum(group *domain.GroupSession, first *media.MediaInfo) (*domain.MediaAlbum, bool) {
+	stored := &domain.MediaAlbum{
+		GroupID:      group.GroupID,
+		MediaGroupID: first.AlbumID,
+		Caption:      first.Caption,
+		FolderPath:   placeAlbumItems(group, []*media.MediaInfo{first}, first.Caption),
+	}
+
+	late, err := b.groupRepo.OpenAlbum(stored)
+	if err != nil {
+		log.Printf("Error saving album %s: %v", first.AlbumID, err)
+	}
+	return stored, late
+}
+
+// flushAlbum ставит собранный альбом в очередь одним набором заданий
+func (b *Bot) flushAlbum(key string, album *pendingAlbum) {
+	b.albumsMu.Lock()
+	if b.albums[key] != album {
+		b.albumsMu.Unlock()
+		return
+	}
+	delete(b.albums, key)
+	b.albumsMu.Unlock()
+
+	group := album.group
+	stored := album.stored
+
+	if album.late {
+		// Подпись была у первых частей, поэтому поздние части маршрутизируются
+		// по сохраненной подписи и кладутся в подпапку альбома внутри своей
+		// папки, как и остальные части в placeAlbumItems
+		for _, item := range album.items {
+			routeMedia(group, item, stored.Caption, namingFields(item))
+			if group.AlbumSubfolders {
+				item.CloudFolderPath = path.Join(item.CloudFolderPath, path.Base(stored.FolderPath))
+			}
+		}
+		if err := b.groupRepo.ReopenAlbum(group.GroupID, stored.MediaGroupID); err != nil {
+			log.Printf("Error reopening album %s: %v", stored.MediaGroupID, err)
+		}
+	} else {
+		caption := ""
+		for _, item := range album.items {
+			if item.Caption != "" {
+				caption = item.Caption
+				break
+			}
+		}
+
+		// Подпись обычно есть только у первой части, но может прийти и с
+		// другой - тогда папка, сохраненная при первой части, уточняется
+		folder := placeAlbumItems(group, album.items, caption)
+		if caption != stored.Caption || folder != stored.FolderPath {
+			stored.Caption = caption
+			stored.FolderPath = folder
+			if err := b.groupRepo.UpdateAlbumFolder(stored); err != nil {
+				log.Printf("Error updating album %s: %v", stored.MediaGroupID, err)
+			}
+		}
+	}
+
+	queued := 0
+	for _, item := range album.items {
+		if b.enqueueMedia(group, item, namingFields(item)) {
+			queued++
+		}
+	}
+	log.Printf("Queued %d of %d items of album %s in group %d", queued, len(album.items), stored.MediaGroupID, group.GroupID)
+
+	completed, err := b.groupRepo.CloseAlbum(group.GroupID, stored.MediaGroupID, queued)
+	if err != nil {
+		log.Printf("Error closing album %s: %v", stored.MediaGroupID, err)
+		return
+	}
+	if completed != nil {
+		b.sendAlbumSummary(completed)
+	}
+}
+
+// placeAlbumItems выбирает папки частей альбома: весь альбом маршрутизируется
+// по общей подписи и, если включено, кладется в подпапку альбома. Возвращает
+// папку первой части.
+func placeAlbumItems(group *domain.GroupSession, items []*media.MediaInfo, caption string) string {
+	for _, item := range items {
+		routeMedia(group, item, caption, namingFields(item))
+	}
+
+	if group.AlbumSubfolders {
+		folder := albumFolderName(caption, items[0])
+		for _, item := range items {
+			item.CloudFolderPath = path.Join(item.CloudFolderPath, folder)
+		}
+	}
+	return items[0].CloudFolderPath
+}
+
+// finishAlbumItem учитывает результат загрузки части альбома и, если альбом
+// завершен, отправляет в группу итоговое уведомление
+func (b *Bot) finishAlbumItem(groupID int64, mediaInfo *media.MediaInfo, uploaded bool) {
+	// Новые версии отредактированных частей в итог альбома не входят
+	if mediaInfo.AlbumID == "" || mediaInfo.Version > 1 {
+		return
+	}
+
+	completed, err := b.groupRepo.FinishAlbumItem(groupID, mediaInfo.AlbumID, uploaded)
+	if err != nil {
+		log.Printf("Error updating album %s: %v", mediaInfo.AlbumID, err)
+		return
+	}
+	if completed != nil {
+		b.sendAlbumSummary(completed)
+	}
+}
+
+// sendAlbumSummary отправляет в группу одно уведомление на весь альбом.
+// Итог альбома канала получает владелец: сообщения бота в канале видны подписчикам.
+func (b *Bot) sendAlbumSummary(album *domain.MediaAlbum) {
+	if album.ExpectedCount == 0 {
+		return
+	}
+
+	chatID := album.GroupID
+	text := "🖼 Альбом сохранен в облако"
+	if group, err := b.groupRepo.GetGroupSession(album.GroupID); err == nil && group != nil {
+		if group.IsChannel {
+			chatID = group.OwnerChatID
+			text = fmt.Sprintf("🖼 Альбом канала \"%s\" сохранен в облако", group.GroupTitle)
+		}
+		if groupState(group) == domain.StateDryRun {
+			text = "🧪 Пробный режим: " + strings.Replace(text, "сохранен", "был бы сохранен", 1)
+		}
+	}
+	if album.Caption != "" {
+		text += fmt.Sprintf("\n\n«%s»", truncateText(album.Caption, 100))
+	}
+	text += fmt.Sprintf("\n\n✅ Загружено: %d из %d", album.UploadedCount, album.ExpectedCount)
+	if album.FailedCount > 0 {
+		text += fmt.Sprintf("\n❌ Не удалось: %d (список - /failed в личном чате владельца)", album.FailedCount)
+	}
+	text += fmt.Sprintf("\n📁 Папка: %s", album.FolderPath)
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	if _, err := b.Api.Send(msg); err != nil {
+		log.Printf("Error sending album summary: %v", err)
+	}
+}
+
+// albumFolderName возвращает имя подпапки альбома: первая строка подписи
+// или, если подписи нет, дата и ID альбома
+func albumFolderName(caption string, first *media.MediaInfo) string {
+	name := strings.TrimSpace(strings.SplitN(caption, "\n", 2)[0])
+	name = naming.Sanitize(name)
+	if runes := []rune(name); len(runes) > maxAlbumFolderName {
+		name = strings.TrimSpace(string(runes[:maxAlbumFolderName]))
+	}
+	if name != "" {
+		return name
+	}
+
+	date := time.Unix(first.MessageDate, 0).Format("2006-01-02")
+	return fmt.Sprintf("album_%s_%s", date, naming.Sanitize(first.AlbumID))
+}
+
+// handleAlbumsCommand включает и выключает отдельные подпапки для альбомов.
+// /albums - текущая настройка, /albums on|off - изменить
+func (b *Bot) handleAlbumsCommand(msg *tgbotapi.Message) {
+	group, err := b.groupRepo.GetGroupSession(msg.Chat.ID)
+	if err != nil || group == nil {
+		reply := tgbotapi.NewMessage(msg.Chat.ID,
+			"❌ Группа не настроена. Используйте /setup_group для настройки.")
+		b.Api.Send(reply)
+		return
+	}
+
+	switch strings.ToLower(strings.TrimSpace(msg.CommandArguments())) {
+	case "":
+		status := "выключены"
+		if group.AlbumSubfolders {
+			status = "включены"
+		}
+		reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf(`🖼 Подпапки для альбомов: %s
+
+Когда включены, каждый альбом сохраняется в отдельную подпапку, названную по подписи альбома.
+
+Включить: /albums on
+Выключить: /albums off`, status))
+		b.Api.Send(reply)
+		return
+	case "on":
+		group.AlbumSubfolders = true
+	case "off":
+		group.AlbumSubfolders = false
+	default:
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Используйте /albums on или /albums off")
+		b.Api.Send(reply)
+		return
+	}
+
+	if !b.checkGroupAdmin(msg) {
+		return
+	}
+
+	if err := b.groupRepo.SaveGroupSession(group); err != nil {
+		log.Printf("Error saving album settings: %v", err)
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Ошибка при сохранении настроек.")
+		b.Api.Send(reply)
+		return
+	}
+
+	text := "✅ Альбомы будут сохраняться в отдельные подпапки."
+	if !group.AlbumSubfolders {
+		text = "✅ Альбомы будут сохраняться вместе с остальными файлами."
+	}
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	b.Api.Send(reply)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"mail_helper_bot/internal/pkg/group/domain"
+	"mail_helper_bot/internal/pkg/naming"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -87,7 +88,7 @@ func (b *Bot) startGroupSetupFromCommand(msg *tgbotapi.Message) {
 		GroupID:         msg.Chat.ID,
 		GroupTitle:      msg.Chat.Title,
 		OwnerChatID:     msg.From.ID,
-		MediaType:       "photos", // по умолчанию
+		MediaType:       domain.DefaultMediaTypes,
 		CloudFolderPath: cloudFolderPath,
 	}
 
@@ -99,24 +100,8 @@ func (b *Bot) startGroupSetupFromCommand(msg *tgbotapi.Message) {
 		return
 	}
 
-	// Отправляем выбор типа медиа
-	text := fmt.Sprintf(`⚙️ Настройка бота для группы "%s"
-
-Выберите тип медиа для автоматической загрузки в облако:`, msg.Chat.Title)
-
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📷 Только фото", fmt.Sprintf("media_type_settings:%d:photos", msg.Chat.ID)),
-			tgbotapi.NewInlineKeyboardButtonData("🎥 Только видео", fmt.Sprintf("media_type_settings:%d:videos", msg.Chat.ID)),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📷🎥 Все медиа", fmt.Sprintf("media_type_settings:%d:all", msg.Chat.ID)),
-		),
-	)
-
-	msgConfig := tgbotapi.NewMessage(msg.Chat.ID, text)
-	msgConfig.ReplyMarkup = keyboard
-	b.Api.Send(msgConfig)
+	// Отправляем выбор категорий медиа
+	b.sendMediaTypeSelection(group.GroupID, group)
 }
 
 // showCurrentSettingsWithOptions показывает текущие настройки и предлагает изменить
@@ -128,24 +113,18 @@ func (b *Bot) showCurrentSettingsWithOptions(chatID int64, group *domain.GroupSe
 		stats = &domain.GroupStats{}
 	}
 
-	mediaTypeText := map[string]string{
-		"photos": "📷 Только фото",
-		"videos": "🎥 Только видео",
-		"all":    "📷🎥 Все медиа",
-	}
-
 	text := fmt.Sprintf(`⚙️ Текущие настройки группы "%s"
 
 📊 **Статистика:**
-• Тип медиа: %s
-• Загружено фото: %d
-• Загружено видео: %d
-• Облачная папка: %s`,
+• Типы медиа: %s
+• Загружено: %s
+• Облачная папка: %s
+• Подпапки: %s`,
 		group.GroupTitle,
-		mediaTypeText[group.MediaType],
-		stats.PhotosCount,
-		stats.VideosCount,
-		group.CloudFolderPath)
+		domain.MediaTypesTitle(group.MediaType),
+		formatMediaCounts(stats),
+		group.CloudFolderPath,
+		naming.LayoutTitle(group.FolderLayout))
 
 	if group.PublicURL != "" {
 		text += fmt.Sprintf("\n• 🔗 Публичная ссылка: %s", group.PublicURL)
@@ -155,11 +134,7 @@ func (b *Bot) showCurrentSettingsWithOptions(chatID int64, group *domain.GroupSe
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📷 Только фото", fmt.Sprintf("media_type_settings:%d:photos", group.GroupID)),
-			tgbotapi.NewInlineKeyboardButtonData("🎥 Только видео", fmt.Sprintf("media_type_settings:%d:videos", group.GroupID)),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📷🎥 Все медиа", fmt.Sprintf("media_type_settings:%d:all", group.GroupID)),
+			tgbotapi.NewInlineKeyboardButtonData("🗂 Типы медиа", fmt.Sprintf("media_types:%d", group.GroupID)),
 		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("📊 Обновить статистику", fmt.Sprintf("refresh_stats:%d", group.GroupID)),
@@ -4,22 +4,51 @@ import (
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"log"
+	digestRepository "mail_helper_bot/internal/pkg/digest/repository"
 	"mail_helper_bot/internal/pkg/group/repository"
 	"mail_helper_bot/internal/pkg/media"
+	"mail_helper_bot/internal/pkg/notify/notify_service"
 	"mail_helper_bot/internal/pkg/oauth/oauth_service"
+	queueRepository "mail_helper_bot/internal/pkg/upload_queue/repository"
+	"mail_helper_bot/internal/pkg/upload_queue/worker"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 )
 
 type Bot struct {
 	Api            *tgbotapi.BotAPI
 	oauth          *oauth_service.OAuthService
+	tokens         *oauth_service.TokenProvider
 	storage        oauth_service.Storage
 	groupRepo      repository.GroupRepository
+	queueRepo      queueRepository.QueueRepository
+	uploadPool     *worker.Pool
 	mediaProcessor *media.MediaProcessor
+	notifier       *notify_service.Notifier
+	digestRepo     digestRepository.DigestRepository
+
+	// Сериализует подбор имени файла и постановку в очередь
+	enqueueMu sync.Mutex
+
+	// Альбомы, части которых еще собираются, ключ - группа и media_group_id
+	albumsMu sync.Mutex
+	albums   map[string]*pendingAlbum
+
+	// Отложенные пересборки index.json, ключ - группа и папка
+	indexMu sync.Mutex
+	indexes map[string]*pendingIndex
+
+	// Архивы экспорта истории, ключ - владелец
+	importDir string
+	importsMu sync.Mutex
+	imports   map[int64]*pendingImport
 }
 
-func New(token string, storage oauth_service.Storage, groupRepo repository.GroupRepository) *Bot {
-	bot, err := tgbotapi.NewBotAPI(token)
+func New(token string, apiConfig media.BotAPIConfig, storage oauth_service.Storage, groupRepo repository.GroupRepository,
+	queueRepo queueRepository.QueueRepository) *Bot {
+	bot, err := tgbotapi.NewBotAPIWithAPIEndpoint(token, apiConfig.Endpoint())
 	if err != nil {
 		log.Fatalf("failed to create bot: %v", err)
 	}
@@ -28,12 +57,24 @@ func New(token string, storage oauth_service.Storage, groupRepo repository.Group
 		Api:            bot,
 		storage:        storage,
 		groupRepo:      groupRepo,
-		mediaProcessor: media.NewMediaProcessor(bot),
+		queueRepo:      queueRepo,
+		mediaProcessor: media.NewMediaProcessor(bot, apiConfig),
+		albums:         make(map[string]*pendingAlbum),
+		indexes:        make(map[string]*pendingIndex),
+		importDir:      filepath.Join(os.TempDir(), "mail_helper_imports"),
+		imports:        make(map[int64]*pendingImport),
 	}
 }
 
 func (b *Bot) SetOAuthService(oauth *oauth_service.OAuthService) {
 	b.oauth = oauth
+	b.tokens = oauth_service.NewTokenProvider(oauth)
+}
+
+// SetUploadPool задает пул воркеров, который разбирает очередь загрузок
+func (b *Bot) SetUploadPool(pool *worker.Pool) {
+	b.uploadPool = pool
+	pool.SetDeadLetterHandler(b.handleUploadDeadLetter)
 }
 
 func (b *Bot) GetMediaProcessor() *media.MediaProcessor {
@@ -44,14 +85,20 @@ func (b *Bot) Start() {
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
 
-	updates := b.Api.GetUpdatesChan(u)
+	updates := b.receiveUpdates(u)
 
 	log.Printf("Authorized on account %s", b.Api.Self.UserName)
 
 	for update := range updates {
 		switch {
 		case update.Message != nil:
-			b.handleMessage(update.Message)
+			b.handleMessage(update.Message, update.thread)
+		case update.EditedMessage != nil:
+			b.handleEditedUpdate(update.EditedMessage, update.thread)
+		case update.ChannelPost != nil:
+			b.handleChannelPost(update.ChannelPost)
+		case update.EditedChannelPost != nil:
+			b.handleEditedChannelPost(update.EditedChannelPost)
 		case update.CallbackQuery != nil:
 			b.handleCallback(update.CallbackQuery)
 		case update.MyChatMember != nil:
@@ -60,11 +107,16 @@ func (b *Bot) Start() {
 	}
 }
 
-func (b *Bot) handleMessage(msg *tgbotapi.Message) {
+func (b *Bot) handleMessage(msg *tgbotapi.Message, thread threadInfo) {
 	log.Println("handle message:", msg)
+	if thread.Service {
+		b.handleTopicServiceMessage(msg, thread)
+		return
+	}
+
 	if msg.IsCommand() {
 		log.Println("handle command:", msg)
-		b.handleCommand(msg)
+		b.handleCommand(msg, thread)
 		return
 	}
 
@@ -75,16 +127,43 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 		return
 	}
 
+	// Пересланный пост канала подключает канал
+	if msg.Chat.IsPrivate() && msg.ForwardFromChat != nil && msg.ForwardFromChat.IsChannel() {
+		log.Println("handle channel forward:", msg)
+		b.handleChannelForward(msg)
+		return
+	}
+
+	// Архив экспорта Telegram Desktop для импорта истории группы
+	if msg.Chat.IsPrivate() && isExportArchive(msg.Document) {
+		log.Println("handle import archive:", msg)
+		b.handleImportArchive(msg)
+		return
+	}
+
 	if b.containsMedia(msg) {
 		log.Println("handle media:", msg)
-		b.handleMediaMessage(msg)
+		b.handleMediaMessage(msg, thread)
 	}
 }
 
-func (b *Bot) handleCommand(msg *tgbotapi.Message) {
+// handleEditedUpdate обрабатывает отредактированные сообщения с медиа в группах
+func (b *Bot) handleEditedUpdate(msg *tgbotapi.Message, thread threadInfo) {
+	if (msg.Chat.IsGroup() || msg.Chat.IsSuperGroup()) && b.containsMedia(msg) {
+		log.Println("handle edited media:", msg)
+		b.handleEditedMessage(msg, thread)
+	}
+}
+
+func (b *Bot) handleCommand(msg *tgbotapi.Message, thread threadInfo) {
+	// Команды без автора (от имени канала) не от кого проверять
+	if msg.From == nil {
+		return
+	}
+
 	// Определяем доступные команды в зависимости от типа чата
 	if msg.Chat.IsGroup() || msg.Chat.IsSuperGroup() {
-		b.handleGroupCommand(msg)
+		b.handleGroupCommand(msg, thread)
 	} else {
 		b.handlePrivateCommand(msg)
 	}
@@ -103,6 +182,14 @@ func (b *Bot) handlePrivateCommand(msg *tgbotapi.Message) {
 		handleLogoutCommand(b, msg)
 	case "my_groups":
 		b.handleMyGroups(msg)
+	case "failed":
+		b.handleFailedUploads(msg)
+	case "import":
+		b.handleImportCommand(msg)
+	case "add_channel":
+		b.handleAddChannelCommand(msg)
+	case "digest":
+		b.handleDigestCommand(msg)
 	default:
 		reply := tgbotapi.NewMessage(msg.Chat.ID, "Неизвестная команда 🤔")
 		b.Api.Send(reply)
@@ -110,7 +197,7 @@ func (b *Bot) handlePrivateCommand(msg *tgbotapi.Message) {
 }
 
 // handleGroupCommand обрабатывает команды в группе
-func (b *Bot) handleGroupCommand(msg *tgbotapi.Message) {
+func (b *Bot) handleGroupCommand(msg *tgbotapi.Message, thread threadInfo) {
 	switch msg.Command() {
 	case "group_status":
 		b.handleGroupStatus(msg)
@@ -120,6 +207,38 @@ func (b *Bot) handleGroupCommand(msg *tgbotapi.Message) {
 		b.handleSetupGroup(msg)
 	case "bot_settings":
 		b.handleBotSettings(msg) // Оставляем для администраторов
+	case "naming":
+		b.handleNamingCommand(msg)
+	case "layout":
+		b.handleLayoutCommand(msg)
+	case "albums":
+		b.handleAlbumsCommand(msg)
+	case "filters":
+		b.handleFiltersCommand(msg)
+	case "routes":
+		b.handleRoutesCommand(msg)
+	case "sidecar":
+		b.handleSidecarCommand(msg)
+	case "privacy":
+		b.handlePrivacyCommand(msg)
+	case "edits":
+		b.handleEditsCommand(msg)
+	case "topics":
+		b.handleTopicsCommand(msg)
+	case "save":
+		b.handleSaveCommand(msg, thread)
+	case "forget":
+		b.handleForgetCommand(msg)
+	case "pause":
+		b.handlePauseCommand(msg)
+	case "resume":
+		b.handleResumeCommand(msg)
+	case "dryrun":
+		b.handleDryRunCommand(msg)
+	case "optout":
+		b.handleOptOutCommand(msg)
+	case "optin":
+		b.handleOptInCommand(msg)
 	case "start":
 		// В группе команда start работает как добавление бота
 		b.handleBotAddedToGroup(msg)
@@ -129,7 +248,23 @@ func (b *Bot) handleGroupCommand(msg *tgbotapi.Message) {
 				"📋 Доступные команды:\n"+
 				"/group_status - Статус группы\n"+
 				"/share - Публичная ссылка (только для администратора)\n"+
-				"/bot_settings - Настройки (только для администратора)"+
+				"/bot_settings - Настройки (только для администратора)\n"+
+				"/naming - Шаблон имен файлов (только для администратора)\n"+
+				"/layout - Подпапки (только для администратора)\n"+
+				"/albums - Папки для альбомов (только для администратора)\n"+
+				"/filters - Правила отбора медиа (только для администратора)\n"+
+				"/routes - Папки по хештегам и подписям (только для администратора)\n"+
+				"/sidecar - Файлы с подписями и index.json (только для администратора)\n"+
+				"/privacy - Удаление геолокации из фото (только для администратора)\n"+
+				"/edits - Версии отредактированных медиа (только для администратора)\n"+
+				"/topics - Подпапки и настройки тем форума (только для администратора)\n"+
+				"/save - Загрузить медиа из сообщения, на которое вы отвечаете (только для администратора)\n"+
+				"/forget - Удалить из облака медиа сообщения, на которое вы отвечаете (только для администратора)\n"+
+				"/pause - Приостановить выгрузку (только для администратора)\n"+
+				"/resume - Возобновить выгрузку (только для администратора)\n"+
+				"/dryrun - Пробный режим без загрузки (только для администратора)\n"+
+				"/optout - Не загружать мои медиа в облако\n"+
+				"/optin - Снова загружать мои медиа\n"+
 				"/setup_group - Принудительная настройка")
 		b.Api.Send(reply)
 	}
@@ -140,14 +275,28 @@ func (b *Bot) handleCallback(query *tgbotapi.CallbackQuery) {
 	chatID := query.Message.Chat.ID
 	messageID := query.Message.MessageID
 
-	if strings.HasPrefix(data, "media_type:") {
-		b.handleMediaTypeSelection(chatID, data, messageID)
-	} else if strings.HasPrefix(data, "media_type_settings:") {
-		b.handleMediaTypeSettings(chatID, data, messageID)
+	if strings.HasPrefix(data, "media_types:") {
+		b.handleMediaTypesOpen(query, data)
+	} else if strings.HasPrefix(data, "media_toggle:") {
+		b.handleMediaTypeToggle(query, data)
+	} else if strings.HasPrefix(data, "media_done:") {
+		b.handleMediaTypesDone(query, data)
 	} else if strings.HasPrefix(data, "refresh_stats:") {
 		b.handleRefreshStats(chatID, data, messageID)
 	} else if strings.HasPrefix(data, "copy_link:") {
 		b.handleCopyLink(chatID, data, messageID)
+	} else if strings.HasPrefix(data, "dlq_retry:") {
+		b.handleDeadLetterRetry(chatID, data, messageID)
+	} else if data == "dlq_retry_all" {
+		b.handleDeadLetterRetryAll(chatID, messageID)
+	} else if strings.HasPrefix(data, "layout:") {
+		b.handleLayoutSelection(query, data)
+	} else if strings.HasPrefix(data, "import:") {
+		b.handleImportSelection(query, data)
+	} else if strings.HasPrefix(data, "optout_purge:") {
+		b.handleOptOutPurge(query, data)
+	} else if strings.HasPrefix(data, "optout_keep:") {
+		b.handleOptOutKeep(query, data)
 	}
 
 	callback := tgbotapi.NewCallback(query.ID, "")
@@ -177,7 +326,9 @@ func (b *Bot) handleCopyLink(chatID int64, data string, messageID int) {
 
 func (b *Bot) handleChatMemberUpdate(update *tgbotapi.ChatMemberUpdated) {
 	if update.NewChatMember.User.ID == b.Api.Self.ID {
-		if update.NewChatMember.Status == "member" {
+		if update.Chat.IsChannel() && update.NewChatMember.Status == "administrator" {
+			b.suggestAddChannel(update)
+		} else if update.NewChatMember.Status == "member" {
 			msg := &tgbotapi.Message{
 				Chat: &update.Chat,
 				From: &update.From,
@@ -191,7 +342,8 @@ func (b *Bot) handleChatMemberUpdate(update *tgbotapi.ChatMemberUpdated) {
 }
 
 func (b *Bot) containsMedia(msg *tgbotapi.Message) bool {
-	return msg.Photo != nil || msg.Video != nil || msg.Document != nil
+	return msg.Photo != nil || msg.Video != nil || msg.Document != nil ||
+		msg.Animation != nil || msg.VideoNote != nil || msg.Voice != nil || msg.Audio != nil
 }
 
 func (b *Bot) sendErrorMessage(chatID int64, message string) {
@@ -199,47 +351,6 @@ func (b *Bot) sendErrorMessage(chatID int64, message string) {
 	b.Api.Send(msg)
 }
 
-func (b *Bot) handleMediaTypeSettings(chatID int64, data string, messageID int) {
-	// Формат: media_type_settings:{groupID}:{mediaType}
-	parts := strings.Split(data, ":")
-	if len(parts) != 3 {
-		return
-	}
-
-	var groupID int64
-	fmt.Sscanf(parts[1], "%d", &groupID)
-	mediaType := parts[2]
-
-	// Обновляем настройки группы
-	group, err := b.groupRepo.GetGroupSession(groupID)
-	if err != nil || group == nil {
-		b.sendErrorMessage(chatID, "❌ Группа не найдена")
-		return
-	}
-
-	group.MediaType = mediaType
-	if err := b.groupRepo.SaveGroupSession(group); err != nil {
-		log.Printf("Error updating group media type: %v", err)
-		b.sendErrorMessage(chatID, "❌ Ошибка при сохранении настроек")
-		return
-	}
-
-	// Обновляем сообщение
-	mediaTypeText := map[string]string{
-		"photos": "📷 фото",
-		"videos": "🎥 видео",
-		"all":    "📷🎥 все медиафайлы",
-	}
-
-	text := fmt.Sprintf("✅ Настройки обновлены!\n\nГруппа: %s\nНовый тип медиа: %s\n\nБот теперь будет загружать %s в ваше облако Mail.ru.",
-		group.GroupTitle,
-		mediaTypeText[mediaType],
-		mediaTypeText[mediaType])
-
-	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, text)
-	b.Api.Send(editMsg)
-}
-
 // handleRefreshStats обновляет статистику группы
 func (b *Bot) handleRefreshStats(chatID int64, data string, messageID int) {
 	parts := strings.Split(data, ":")
@@ -0,0 +1,156 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mail_helper_bot/internal/pkg/group/domain"
+)
+
+// handleChannelPost загружает медиа из постов подключенного канала тем же
+// путем, что и медиа из групп. У постов канала нет From, автор - сам канал.
+// Команды в канале не выполняются: канал настраивается из личного чата.
+func (b *Bot) handleChannelPost(msg *tgbotapi.Message) {
+	if msg.IsCommand() {
+		return
+	}
+	if b.containsMedia(msg) {
+		log.Println("handle channel media:", msg)
+		b.handleMediaMessage(msg, threadInfo{})
+	}
+}
+
+// handleEditedChannelPost загружает новую версию медиа отредактированного поста
+func (b *Bot) handleEditedChannelPost(msg *tgbotapi.Message) {
+	if b.containsMedia(msg) {
+		log.Println("handle edited channel media:", msg)
+		b.handleEditedMessage(msg, threadInfo{})
+	}
+}
+
+// handleAddChannelCommand подключает канал из личного чата.
+// /add_channel @username или /add_channel -100..., для закрытого канала
+// можно просто переслать боту любой пост из него.
+func (b *Bot) handleAddChannelCommand(msg *tgbotapi.Message) {
+	arg := strings.TrimSpace(msg.CommandArguments())
+	if arg == "" {
+		text := fmt.Sprintf(`📢 Подключение канала
+
+1. Добавьте @%s в канал администратором - иначе бот не получает посты
+2. Пришлите сюда /add_channel @имя_канала
+   или перешлите сюда любой пост из канала, если у него нет публичного имени
+
+Подключить канал может только его администратор, авторизованный через /login. Облако канала будет вашим.
+
+В канале настраиваются только категории медиа, остальные настройки групп для каналов недоступны.`,
+			b.Api.Self.UserName)
+		b.Api.Send(tgbotapi.NewMessage(msg.Chat.ID, text))
+		return
+	}
+
+	config := tgbotapi.ChatInfoConfig{}
+	if id, err := strconv.ParseInt(arg, 10, 64); err == nil {
+		config.ChatID = id
+	} else {
+		config.SuperGroupUsername = "@" + strings.TrimPrefix(strings.TrimPrefix(arg, "https://t.me/"), "@")
+	}
+
+	channel, err := b.Api.GetChat(config)
+	if err != nil {
+		log.Printf("Error getting channel %s: %v", arg, err)
+		b.sendErrorMessage(msg.Chat.ID,
+			"❌ Канал не найден. Проверьте имя и что бот добавлен в канал администратором.")
+		return
+	}
+
+	b.addChannel(msg, &channel)
+}
+
+// handleChannelForward подключает канал, пост из которого переслали в личный чат
+func (b *Bot) handleChannelForward(msg *tgbotapi.Message) {
+	b.addChannel(msg, msg.ForwardFromChat)
+}
+
+// addChannel проверяет права бота и пользователя в канале и создает запись
+// канала. Дальше канал настраивается так же, как группа, но из личного чата.
+func (b *Bot) addChannel(msg *tgbotapi.Message, channel *tgbotapi.Chat) {
+	if !channel.IsChannel() {
+		b.sendErrorMessage(msg.Chat.ID,
+			"❌ Это не канал. Группы подключаются добавлением бота в группу.")
+		return
+	}
+
+	botAdmin, err := b.isChatAdmin(channel.ID, b.Api.Self.ID)
+	if err != nil || !botAdmin {
+		b.sendErrorMessage(msg.Chat.ID, fmt.Sprintf(
+			"❌ Бот не администратор канала \"%s\". Добавьте @%s в администраторы канала и повторите.",
+			channel.Title, b.Api.Self.UserName))
+		return
+	}
+
+	isAdmin, err := b.isChatAdmin(channel.ID, msg.From.ID)
+	if err != nil {
+		log.Printf("Error getting chat member: %v", err)
+		b.sendErrorMessage(msg.Chat.ID, "❌ Ошибка при проверке прав.")
+		return
+	}
+	if !isAdmin {
+		b.sendErrorMessage(msg.Chat.ID, "❌ Только администратор канала может подключить его к боту.")
+		return
+	}
+
+	session, err := b.oauth.GetUserSession(msg.From.ID)
+	if err != nil || session == nil || session.AccessToken == "" {
+		b.sendErrorMessage(msg.Chat.ID,
+			"❌ Для подключения канала необходимо авторизоваться.\n\nИспользуйте команду /login.")
+		return
+	}
+
+	existing, err := b.groupRepo.GetGroupSession(channel.ID)
+	if err == nil && existing != nil {
+		if existing.OwnerChatID != msg.From.ID {
+			b.sendErrorMessage(msg.Chat.ID, fmt.Sprintf(
+				"❌ Канал \"%s\" уже подключен другим администратором.", existing.GroupTitle))
+			return
+		}
+		b.sendGroupAlreadySetupMessage(msg.Chat.ID, existing)
+		return
+	}
+
+	group := &domain.GroupSession{
+		GroupID:         channel.ID,
+		GroupTitle:      channel.Title,
+		OwnerChatID:     msg.From.ID,
+		MediaType:       domain.DefaultMediaTypes,
+		CloudFolderPath: b.mediaProcessor.GenerateCloudFolderPath(channel.ID, channel.Title),
+		IsChannel:       true,
+	}
+	if err := b.groupRepo.SaveGroupSession(group); err != nil {
+		log.Printf("Error saving channel session: %v", err)
+		b.sendErrorMessage(msg.Chat.ID, "❌ Ошибка при сохранении настроек канала.")
+		return
+	}
+
+	log.Printf("Channel %d connected by user %d", channel.ID, msg.From.ID)
+
+	// Выбор категорий отправляется в личный чат: сообщения бота в канале видны подписчикам
+	b.sendMediaTypeSelection(msg.Chat.ID, group)
+}
+
+// suggestAddChannel подсказывает администратору, добавившему бота в канал,
+// как его подключить. Если пользователь не писал боту, сообщение не дойдет.
+func (b *Bot) suggestAddChannel(update *tgbotapi.ChatMemberUpdated) {
+	group, err := b.groupRepo.GetGroupSession(update.Chat.ID)
+	if err == nil && group != nil {
+		return
+	}
+
+	text := fmt.Sprintf("📢 Бот добавлен в канал \"%s\".\n\nЧтобы загружать медиа из постов в облако, подключите канал: /add_channel %d",
+		update.Chat.Title, update.Chat.ID)
+	if _, err := b.Api.Send(tgbotapi.NewMessage(update.From.ID, text)); err != nil {
+		log.Printf("Error sending channel hint to user %d: %v", update.From.ID, err)
+	}
+}
@@ -0,0 +1,247 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	digestDomain "mail_helper_bot/internal/pkg/digest/domain"
+	digestRepository "mail_helper_bot/internal/pkg/digest/repository"
+)
+
+// digestTopSenders - сколько самых активных участников показывается в сводке
+const digestTopSenders = 3
+
+var weekdayNames = map[string]time.Weekday{
+	"mon": time.Monday, "пн": time.Monday,
+	"tue": time.Tuesday, "вт": time.Tuesday,
+	"wed": time.Wednesday, "ср": time.Wednesday,
+	"thu": time.Thursday, "чт": time.Thursday,
+	"fri": time.Friday, "пт": time.Friday,
+	"sat": time.Saturday, "сб": time.Saturday,
+	"sun": time.Sunday, "вс": time.Sunday,
+}
+
+var weekdayTitles = [...]string{"воскресенье", "понедельник", "вторник", "среду", "четверг", "пятницу", "субботу"}
+
+// SetDigestRepository задает хранилище расписаний сводок
+func (b *Bot) SetDigestRepository(repo digestRepository.DigestRepository) {
+	b.digestRepo = repo
+}
+
+// handleDigestCommand настраивает сводку по группам владельца.
+// /digest daily [час], /digest weekly [день] [час], /digest off,
+// /digest tz Europe/Moscow|UTC+3, /digest now - прислать сводку сейчас
+func (b *Bot) handleDigestCommand(msg *tgbotapi.Message) {
+	settings, err := b.digestRepo.GetSettings(msg.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting digest settings of %d: %v", msg.Chat.ID, err)
+		b.sendErrorMessage(msg.Chat.ID, "❌ Ошибка при загрузке настроек.")
+		return
+	}
+	if settings == nil {
+		settings = digestDomain.NewSettings(msg.Chat.ID)
+	}
+
+	args := strings.Fields(strings.ToLower(msg.CommandArguments()))
+	if len(args) == 0 {
+		b.sendDigestInfo(msg.Chat.ID, settings)
+		return
+	}
+
+	usage := "❌ Используйте /digest daily [час], /digest weekly [день] [час], /digest off, /digest tz Europe/Moscow или /digest now"
+	now := time.Now()
+
+	switch args[0] {
+	case "now":
+		text, err := b.buildDigest(settings, now)
+		if err != nil {
+			log.Printf("Error building digest for %d: %v", msg.Chat.ID, err)
+			b.sendErrorMessage(msg.Chat.ID, "❌ Ошибка при подготовке сводки.")
+			return
+		}
+		if text == "" {
+			text = "📰 За период сводки в ваших группах ничего не загружалось."
+		}
+		reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+		reply.DisableWebPagePreview = true
+		b.Api.Send(reply)
+		return
+	case "off":
+		if len(args) != 1 {
+			b.Api.Send(tgbotapi.NewMessage(msg.Chat.ID, usage))
+			return
+		}
+		settings.Frequency = digestDomain.FrequencyOff
+	case "daily":
+		if len(args) > 2 || (len(args) == 2 && !parseDigestHour(args[1], settings)) {
+			b.Api.Send(tgbotapi.NewMessage(msg.Chat.ID, usage))
+			return
+		}
+		settings.Frequency = digestDomain.FrequencyDaily
+	case "weekly":
+		for _, arg := range args[1:] {
+			if weekday, ok := weekdayNames[arg]; ok {
+				settings.Weekday = weekday
+			} else if !parseDigestHour(arg, settings) {
+				b.Api.Send(tgbotapi.NewMessage(msg.Chat.ID, usage))
+				return
+			}
+		}
+		settings.Frequency = digestDomain.FrequencyWeekly
+	case "tz":
+		if len(args) != 2 {
+			b.Api.Send(tgbotapi.NewMessage(msg.Chat.ID, usage))
+			return
+		}
+		// Имена поясов IANA чувствительны к регистру
+		name := strings.Fields(msg.CommandArguments())[1]
+		loc, err := digestDomain.ParseTimezone(name)
+		if err != nil || loc == time.Local {
+			b.Api.Send(tgbotapi.NewMessage(msg.Chat.ID,
+				"❌ Неизвестный часовой пояс. Укажите имя вроде Europe/Moscow или смещение вроде UTC+3."))
+			return
+		}
+		settings.Timezone = loc.String()
+	default:
+		b.Api.Send(tgbotapi.NewMessage(msg.Chat.ID, usage))
+		return
+	}
+
+	// Первая сводка после изменения расписания - в ближайшее время по нему, а не сразу
+	settings.LastSentAt = &now
+	if err := b.digestRepo.SaveSettings(settings); err != nil {
+		log.Printf("Error saving digest settings of %d: %v", msg.Chat.ID, err)
+		b.sendErrorMessage(msg.Chat.ID, "❌ Ошибка при сохранении настроек.")
+		return
+	}
+
+	b.sendDigestInfo(msg.Chat.ID, settings)
+}
+
+// parseDigestHour принимает час отправки: 9 или 9:00
+func parseDigestHour(arg string, settings *digestDomain.Settings) bool {
+	hour, err := strconv.Atoi(strings.TrimSuffix(arg, ":00"))
+	if err != nil || hour < 0 || hour > 23 {
+		return false
+	}
+	settings.Hour = hour
+	return true
+}
+
+func (b *Bot) sendDigestInfo(chatID int64, settings *digestDomain.Settings) {
+	text := "📰 Сводка по группам\n\n"
+	switch settings.Frequency {
+	case digestDomain.FrequencyDaily:
+		text += fmt.Sprintf("Каждый день в %02d:00", settings.Hour)
+	case digestDomain.FrequencyWeekly:
+		text += fmt.Sprintf("Каждую неделю в %s в %02d:00", weekdayTitles[settings.Weekday], settings.Hour)
+	default:
+		text += "Выключена"
+	}
+	text += fmt.Sprintf("\nЧасовой пояс: %s", settings.Timezone)
+
+	if settings.Enabled() {
+		next := settings.NextScheduled(time.Now()).In(settings.Location())
+		text += fmt.Sprintf("\nСледующая сводка: %s", next.Format("02.01.2006 15:04"))
+	}
+
+	text += `
+
+В сводке - сколько файлов и какого объема загружено в каждую группу, сколько не загрузилось, самые активные участники и публичная ссылка. Если за период ничего не произошло, сводка не отправляется.
+
+Каждый день: /digest daily 9
+Каждую неделю: /digest weekly mon 9
+Часовой пояс: /digest tz Europe/Moscow или /digest tz UTC+3
+Выключить: /digest off
+Прислать сейчас: /digest now`
+
+	b.Api.Send(tgbotapi.NewMessage(chatID, text))
+}
+
+// SendDigest отправляет владельцу сводку по его группам за период
+// расписания. Ошибкой считается только сбой чтения статистики:
+// недоставленное сообщение повторять бессмысленно.
+func (b *Bot) SendDigest(settings *digestDomain.Settings, now time.Time) error {
+	text, err := b.buildDigest(settings, now)
+	if err != nil {
+		return err
+	}
+	if text == "" {
+		log.Printf("Digest for %d skipped: nothing happened", settings.ChatID)
+		return nil
+	}
+
+	reply := tgbotapi.NewMessage(settings.ChatID, text)
+	reply.DisableWebPagePreview = true
+	if _, err := b.Api.Send(reply); err != nil {
+		log.Printf("Error sending digest to %d: %v", settings.ChatID, err)
+	}
+	return nil
+}
+
+// buildDigest собирает текст сводки. Пустая строка - за период в группах
+// ничего не загружалось и не сломалось.
+func (b *Bot) buildDigest(settings *digestDomain.Settings, now time.Time) (string, error) {
+	groups, err := b.groupRepo.GetUserGroups(settings.ChatID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get groups: %w", err)
+	}
+
+	since := now.Add(-settings.Period())
+	period := "сутки"
+	if settings.Frequency == digestDomain.FrequencyWeekly {
+		period = "неделю"
+	}
+
+	var sections []string
+	quiet := 0
+	for _, group := range groups {
+		stats, err := b.groupRepo.GetGroupPeriodStats(group.GroupID, since, digestTopSenders)
+		if err != nil {
+			return "", fmt.Errorf("failed to get stats of group %d: %w", group.GroupID, err)
+		}
+		failed, err := b.queueRepo.CountGroupDeadLetters(group.GroupID, since)
+		if err != nil {
+			return "", fmt.Errorf("failed to count dead letters of group %d: %w", group.GroupID, err)
+		}
+		if stats.Count == 0 && failed == 0 {
+			quiet++
+			continue
+		}
+
+		section := fmt.Sprintf("📁 %s\n• Загружено: %d, %s", group.GroupTitle, stats.Count, formatBytes(stats.TotalSizeBytes))
+		if failed > 0 {
+			section += fmt.Sprintf("\n• Не загрузилось: %d, повторить: /failed", failed)
+		}
+		if len(stats.TopSenders) > 0 {
+			var senders []string
+			for _, sender := range stats.TopSenders {
+				name := sender.SenderName
+				if name == "" {
+					name = strconv.FormatInt(sender.SenderID, 10)
+				}
+				senders = append(senders, fmt.Sprintf("%s (%d)", name, sender.Count))
+			}
+			section += "\n• Больше всех: " + strings.Join(senders, ", ")
+		}
+		if group.PublicURL != "" {
+			section += "\n• 🔗 " + group.PublicURL
+		}
+		sections = append(sections, section)
+	}
+
+	if len(sections) == 0 {
+		return "", nil
+	}
+
+	text := fmt.Sprintf("📰 Сводка за %s\n\n%s", period, strings.Join(sections, "\n\n"))
+	if quiet > 0 {
+		text += fmt.Sprintf("\n\nБез новых файлов: групп - %d", quiet)
+	}
+	text += "\n\nНастроить сводку: /digest"
+	return text, nil
+}
@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mail_helper_bot/internal/pkg/group/domain"
+	"mail_helper_bot/internal/pkg/naming"
+)
+
+// handleEditedMessage загружает новую версию медиа, если в сообщении заменили
+// файл. Правка только подписи пропускается: файл уже загружен.
+func (b *Bot) handleEditedMessage(msg *tgbotapi.Message, thread threadInfo) {
+	group, mediaInfo, fields, ok := b.acceptMedia(msg, thread)
+	if !ok {
+		return
+	}
+
+	previous, err := b.groupRepo.GetLatestMediaVersion(group.GroupID, msg.MessageID)
+	if err != nil {
+		log.Printf("Error getting previous version of message %d in group %d: %v", msg.MessageID, group.GroupID, err)
+		return
+	}
+
+	// Прошлая версия не загружалась - например, тип медиа раньше не выбирался
+	// или файл не прошел фильтры - или путь к ней неизвестен. Загружаем как
+	// обычное медиа, без альбома: альбом уже учтен и закрыт.
+	if previous == nil || previous.CloudPath == "" {
+		mediaInfo.AlbumID = ""
+		b.enqueueMedia(group, mediaInfo, fields)
+		return
+	}
+
+	mediaInfo.AlbumID = previous.AlbumID
+	mediaInfo.Version = max(previous.Version, 1) + 1
+	mediaInfo.PreviousID = previous.ID
+	mediaInfo.CloudFolderPath = path.Dir(previous.CloudPath)
+
+	previousName := path.Base(previous.CloudPath)
+	ext := naming.ExtFor(mediaInfo.FileName, mediaInfo.MimeType, mediaInfo.Type)
+	if group.EditMode == domain.EditModeReplace {
+		// Файл с тем же расширением заменяется, с другим - кладется рядом под тем же именем
+		mediaInfo.FileName = strings.TrimSuffix(previousName, path.Ext(previousName)) + "." + ext
+		mediaInfo.Overwrite = mediaInfo.CloudFilePath() == previous.CloudPath
+	} else {
+		mediaInfo.FileName = naming.WithVersion(previousName, mediaInfo.Version, ext)
+	}
+
+	log.Printf("Message %d in group %d edited: uploading version %d of %s as %s",
+		msg.MessageID, group.GroupID, mediaInfo.Version, previous.CloudPath, mediaInfo.CloudFilePath())
+
+	b.enqueueMedia(group, mediaInfo, fields)
+}
+
+// handleEditsCommand показывает и меняет обработку отредактированных медиа.
+// /edits - текущий режим, /edits version|replace - изменить
+func (b *Bot) handleEditsCommand(msg *tgbotapi.Message) {
+	group, err := b.groupRepo.GetGroupSession(msg.Chat.ID)
+	if err != nil || group == nil {
+		reply := tgbotapi.NewMessage(msg.Chat.ID,
+			"❌ Группа не настроена. Используйте /setup_group для настройки.")
+		b.Api.Send(reply)
+		return
+	}
+
+	mode := strings.ToLower(strings.TrimSpace(msg.CommandArguments()))
+	if mode == "" {
+		reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf(`✏️ Отредактированные медиа: %s
+
+Когда в сообщении заменяют файл, бот загружает новую версию:
+• version - рядом с прошлой, с суффиксом _v2, _v3...
+• replace - вместо прошлой (файл с другим расширением кладется рядом)
+
+Все версии связываются в истории загрузок.
+
+Изменить: /edits version или /edits replace`, editModeTitle(group.EditMode)))
+		b.Api.Send(reply)
+		return
+	}
+
+	if !domain.IsEditMode(mode) {
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Используйте /edits version или /edits replace")
+		b.Api.Send(reply)
+		return
+	}
+
+	if !b.checkGroupAdmin(msg) {
+		return
+	}
+
+	group.EditMode = mode
+	if err := b.groupRepo.SaveGroupSession(group); err != nil {
+		log.Printf("Error saving edit mode: %v", err)
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Ошибка при сохранении настроек.")
+		b.Api.Send(reply)
+		return
+	}
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("✅ Отредактированные медиа: %s", editModeTitle(mode)))
+	b.Api.Send(reply)
+}
+
+func editModeTitle(mode string) string {
+	if mode == domain.EditModeReplace {
+		return "заменяют прошлую версию"
+	}
+	return "сохраняются как новые версии"
+}
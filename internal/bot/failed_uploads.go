@@ -0,0 +1,136 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxShownDeadLetters - сколько неудачных загрузок показывать в одном сообщении
+const maxShownDeadLetters = 10
+
+// handleFailedUploads показывает владельцу загрузки, не прошедшие за все попытки
+func (b *Bot) handleFailedUploads(msg *tgbotapi.Message) {
+	letters, err := b.queueRepo.GetOwnerDeadLetters(msg.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting dead letters: %v", err)
+		b.sendErrorMessage(msg.Chat.ID, "❌ Ошибка при получении списка неудачных загрузок.")
+		return
+	}
+
+	if len(letters) == 0 {
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "✅ Неудачных загрузок нет.")
+		b.Api.Send(reply)
+		return
+	}
+
+	text := fmt.Sprintf("⚠️ Неудачные загрузки: %d\n\n", len(letters))
+	var rows [][]tgbotapi.InlineKeyboardButton
+	imported := false
+
+	for i, letter := range letters {
+		if i == maxShownDeadLetters {
+			text += fmt.Sprintf("… и еще %d\n", len(letters)-maxShownDeadLetters)
+			break
+		}
+
+		reason := "попытки исчерпаны"
+		if letter.Permanent {
+			reason = "ошибка без повторов"
+		}
+
+		text += fmt.Sprintf("%d. %s — %s\n   👥 %s\n   ❗ %s (%s, попыток: %d)\n\n",
+			i+1,
+			letter.Media.FileName,
+			letter.FailedAt.Format("02.01.2006 15:04"),
+			letter.GroupTitle,
+			truncateText(letter.LastError, 200),
+			reason,
+			letter.Attempts)
+
+		// Распакованный файл импорта удален вместе с переносом в dead letter
+		if letter.Media.LocalPath != "" {
+			imported = true
+			continue
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("🔁 %d. %s", i+1, truncateText(letter.Media.FileName, 40)),
+				fmt.Sprintf("dlq_retry:%d", letter.ID)),
+		))
+	}
+
+	if imported {
+		text += "📦 Файлы из импорта истории повторить нельзя: «Повторить все» уберет их из списка. " +
+			"Чтобы догрузить их, импортируйте архив снова через /import - уже загруженное будет пропущено.\n"
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔁 Повторить все", "dlq_retry_all"),
+	))
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	b.Api.Send(reply)
+}
+
+// handleDeadLetterRetry возвращает в очередь одну неудачную загрузку
+func (b *Bot) handleDeadLetterRetry(chatID int64, data string, messageID int) {
+	// Формат: dlq_retry:{deadLetterID}
+	parts := strings.Split(data, ":")
+	if len(parts) != 2 {
+		return
+	}
+
+	var deadLetterID int64
+	fmt.Sscanf(parts[1], "%d", &deadLetterID)
+
+	requeued, resolved, err := b.queueRepo.RequeueDeadLetter(deadLetterID, chatID)
+	if err != nil {
+		log.Printf("Error requeueing dead letter %d: %v", deadLetterID, err)
+		b.sendErrorMessage(chatID, "❌ Ошибка при повторной постановке в очередь")
+		return
+	}
+	if !resolved {
+		b.sendErrorMessage(chatID, "❌ Загрузка не найдена или уже в очереди")
+		return
+	}
+
+	// Запись удалена без повтора: файл уже ждет загрузки, уже загружен
+	// повтором того же файла или пришел из импорта
+	text := "🧹 Загрузка убрана из списка неудачных: этот файл уже в очереди или его нельзя повторить."
+	if requeued {
+		b.uploadPool.Notify()
+		text = "🔁 Загрузка снова поставлена в очередь."
+	}
+
+	reply := tgbotapi.NewMessage(chatID, text)
+	reply.ReplyToMessageID = messageID
+	b.Api.Send(reply)
+}
+
+// handleDeadLetterRetryAll возвращает в очередь все неудачные загрузки владельца
+func (b *Bot) handleDeadLetterRetryAll(chatID int64, messageID int) {
+	count, err := b.queueRepo.RequeueOwnerDeadLetters(chatID)
+	if err != nil {
+		log.Printf("Error requeueing dead letters of %d: %v", chatID, err)
+		b.sendErrorMessage(chatID, "❌ Ошибка при повторной постановке в очередь")
+		return
+	}
+	b.uploadPool.Notify()
+
+	editMsg := tgbotapi.NewEditMessageText(chatID, messageID,
+		fmt.Sprintf("🔁 Снова поставлено в очередь загрузок: %d", count))
+	b.Api.Send(editMsg)
+}
+
+// truncateText обрезает текст до limit символов
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "…"
+}
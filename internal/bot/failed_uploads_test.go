@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	queueRepository "mail_helper_bot/internal/pkg/upload_queue/repository"
+	"mail_helper_bot/internal/pkg/upload_queue/worker"
+)
+
+// fakeQueueRepo отвечает на повтор dead letter заданным результатом
+type fakeQueueRepo struct {
+	queueRepository.QueueRepository
+
+	requeued, resolved bool
+	err                error
+
+	deadLetterID, ownerChatID int64
+}
+
+func (q *fakeQueueRepo) RequeueDeadLetter(deadLetterID, ownerChatID int64) (bool, bool, error) {
+	q.deadLetterID, q.ownerChatID = deadLetterID, ownerChatID
+	return q.requeued, q.resolved, q.err
+}
+
+// fakeTelegram принимает sendMessage и запоминает тексты сообщений
+type fakeTelegram struct {
+	mu    sync.Mutex
+	texts []string
+}
+
+func (f *fakeTelegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/sendMessage") {
+		f.mu.Lock()
+		f.texts = append(f.texts, r.FormValue("text"))
+		f.mu.Unlock()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}}`))
+}
+
+func newTestTelegramAPI(t *testing.T) (*tgbotapi.BotAPI, *fakeTelegram) {
+	t.Helper()
+	telegram := &fakeTelegram{}
+	server := httptest.NewServer(telegram)
+	t.Cleanup(server.Close)
+
+	api := &tgbotapi.BotAPI{Token: "test", Client: server.Client(), Buffer: 100}
+	api.SetAPIEndpoint(server.URL + "/bot%s/%s")
+	return api, telegram
+}
+
+func TestHandleDeadLetterRetry(t *testing.T) {
+	tests := []struct {
+		name  string
+		queue *fakeQueueRepo
+		reply string
+	}{
+		{"requeued", &fakeQueueRepo{requeued: true, resolved: true}, "🔁 Загрузка снова поставлена в очередь."},
+		{"only cleared", &fakeQueueRepo{resolved: true}, "🧹 Загрузка убрана из списка неудачных"},
+		{"not found", &fakeQueueRepo{}, "❌ Загрузка не найдена или уже в очереди"},
+		{"database error", &fakeQueueRepo{err: errors.New("connection reset")}, "❌ Ошибка при повторной постановке в очередь"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, telegram := newTestTelegramAPI(t)
+			b := &Bot{
+				Api:        api,
+				queueRepo:  tt.queue,
+				uploadPool: worker.NewPool(tt.queue, nil, 1),
+			}
+
+			b.handleDeadLetterRetry(testOwnerID, "dlq_retry:15", 3)
+
+			if tt.queue.deadLetterID != 15 || tt.queue.ownerChatID != testOwnerID {
+				t.Errorf("RequeueDeadLetter(%d, %d), want (15, %d)", tt.queue.deadLetterID, tt.queue.ownerChatID, testOwnerID)
+			}
+			if len(telegram.texts) != 1 || !strings.HasPrefix(telegram.texts[0], tt.reply) {
+				t.Fatalf("replies = %q, want one starting with %q", telegram.texts, tt.reply)
+			}
+		})
+	}
+}
+
+func TestHandleDeadLetterRetryBadData(t *testing.T) {
+	api, telegram := newTestTelegramAPI(t)
+	queue := &fakeQueueRepo{}
+	b := &Bot{Api: api, queueRepo: queue}
+
+	b.handleDeadLetterRetry(testOwnerID, "dlq_retry", 3)
+
+	if queue.deadLetterID != 0 || len(telegram.texts) != 0 {
+		t.Fatalf("malformed callback was handled: id=%d, replies=%q", queue.deadLetterID, telegram.texts)
+	}
+}
@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mail_helper_bot/internal/pkg/filter"
+)
+
+// handleFiltersCommand показывает и меняет правила отбора медиа группы.
+// /filters - текущие правила, /filters <поле> <значения> - задать поле,
+// /filters <поле> - очистить поле, /filters clear - очистить все
+func (b *Bot) handleFiltersCommand(msg *tgbotapi.Message) {
+	group, err := b.groupRepo.GetGroupSession(msg.Chat.ID)
+	if err != nil || group == nil {
+		reply := tgbotapi.NewMessage(msg.Chat.ID,
+			"❌ Группа не настроена. Используйте /setup_group для настройки.")
+		b.Api.Send(reply)
+		return
+	}
+
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) == 0 {
+		b.sendFiltersInfo(msg.Chat.ID, &group.Filters)
+		return
+	}
+
+	if !b.checkGroupAdmin(msg) {
+		return
+	}
+
+	field := strings.ToLower(args[0])
+	if field == "clear" {
+		group.Filters = filter.Rules{}
+	} else {
+		// Регулярное выражение может содержать пробелы, поэтому берем его из текста целиком
+		values := args[1:]
+		if field == "caption_include" || field == "caption_exclude" {
+			values = nil
+			if rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(msg.CommandArguments()), args[0])); rest != "" {
+				values = []string{rest}
+			}
+		}
+
+		if err := group.Filters.Set(field, values); err != nil {
+			reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("❌ %v\n\nСписок полей: /filters", err))
+			b.Api.Send(reply)
+			return
+		}
+	}
+
+	if err := b.groupRepo.SaveGroupSession(group); err != nil {
+		log.Printf("Error saving filter rules: %v", err)
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Ошибка при сохранении правил.")
+		b.Api.Send(reply)
+		return
+	}
+
+	b.sendFiltersInfo(msg.Chat.ID, &group.Filters)
+}
+
+func (b *Bot) sendFiltersInfo(chatID int64, rules *filter.Rules) {
+	text := "🔎 Правила отбора медиа\n\n"
+	if rules.IsEmpty() {
+		text += "Правил нет - загружаются все медиа выбранных типов.\n"
+	} else {
+		text += strings.Join(rules.Describe(), "\n") + "\n"
+	}
+
+	text += "\nИзменить: /filters <поле> <значения>\nОчистить поле: /filters <поле>\nОчистить все: /filters clear\n\nПоля:\n"
+	for _, f := range filter.Fields {
+		text += fmt.Sprintf("• %s - %s\n", f.Name, f.Description)
+	}
+	text += "\nИсключения проверяются раньше разрешений. Причина каждого пропуска пишется в лог."
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	b.Api.Send(msg)
+}
@@ -5,12 +5,14 @@ import (
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"log"
 	"mail_helper_bot/internal/pkg/group/domain"
-	"strings"
 )
 
 func (b *Bot) handleBotAddedToGroup(msg *tgbotapi.Message) {
 	chat := msg.Chat
 	user := msg.From
+	if user == nil {
+		return
+	}
 
 	session, err := b.oauth.GetUserSession(user.ID)
 	if err != nil || session == nil || session.AccessToken == "" {
@@ -65,7 +67,7 @@ func (b *Bot) handleBotAddedToGroup(msg *tgbotapi.Message) {
 		GroupID:         chat.ID,
 		GroupTitle:      chat.Title,
 		OwnerChatID:     user.ID,
-		MediaType:       "photos", // по умолчанию
+		MediaType:       domain.DefaultMediaTypes,
 		CloudFolderPath: cloudFolderPath,
 	}
 
@@ -78,7 +80,7 @@ func (b *Bot) handleBotAddedToGroup(msg *tgbotapi.Message) {
 	}
 
 	// Отправляем сообщение с выбором типа медиа
-	b.sendMediaTypeSelection(chat.ID)
+	b.sendMediaTypeSelection(group.GroupID, group)
 }
 
 func (b *Bot) sendAuthRequiredMessage(userID int64, groupTitle string) {
@@ -103,94 +105,42 @@ func (b *Bot) isUserAdmin(member tgbotapi.ChatMember) bool {
 	return member.Status == "creator" || member.Status == "administrator"
 }
 
-func (b *Bot) sendMediaTypeSelection(chatID int64) {
-	text := `📁 Бот добавлен в группу!
-
-Выберите тип медиа для автоматической выгрузки в облако:`
-
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📷 Только фото", "media_type:photos"),
-			tgbotapi.NewInlineKeyboardButtonData("🎥 Только видео", "media_type:videos"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📷🎥 Все медиа", "media_type:all"),
-		),
-	)
-
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = keyboard
-	b.Api.Send(msg)
-}
-
-func (b *Bot) handleMediaTypeSelection(chatID int64, data string, messageID int) {
-	parts := strings.Split(data, ":")
-	if len(parts) != 2 {
-		return
-	}
-
-	mediaType := parts[1]
-	validTypes := map[string]string{
-		"photos": "📷 Только фото",
-		"videos": "🎥 Только видео",
-		"all":    "📷🎥 Все медиа",
-	}
-
-	if _, valid := validTypes[mediaType]; !valid {
-		return
-	}
-
-	// Обновляем настройки группы
-	group, err := b.groupRepo.GetGroupSession(chatID)
-	if err != nil || group == nil {
-		return
+// isChatAdmin проверяет, что пользователь - администратор чата
+func (b *Bot) isChatAdmin(chatID, userID int64) (bool, error) {
+	member, err := b.Api.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
+			ChatID: chatID,
+			UserID: userID,
+		},
+	})
+	if err != nil {
+		return false, err
 	}
+	return b.isUserAdmin(member), nil
+}
 
-	group.MediaType = mediaType
-	if err := b.groupRepo.SaveGroupSession(group); err != nil {
-		log.Printf("Error updating group media type: %v", err)
-		return
+// checkGroupAdmin проверяет, что автор команды - администратор группы,
+// и сообщает об отказе в группу
+func (b *Bot) checkGroupAdmin(msg *tgbotapi.Message) bool {
+	if msg.From == nil {
+		return false
 	}
 
-	// Проверяем авторизацию владельца и создаем папку с публичной ссылкой
-	session, err := b.oauth.GetUserSession(group.OwnerChatID)
-	if err == nil && session != nil && session.AccessToken != "" {
-		// Создаем папку в облаке
-		err := b.mediaProcessor.CreateCloudFolder(session.AccessToken, group.CloudFolderPath)
-		if err != nil {
-			log.Printf("Error creating cloud folder: %v", err)
-		} else {
-			// Создаем публичную ссылку
-			publicURL, err := b.mediaProcessor.CreatePublicLink(session.AccessToken, group.CloudFolderPath)
-			if err != nil {
-				log.Printf("Error creating public link: %v", err)
-			} else {
-				group.PublicURL = publicURL
-				b.groupRepo.SaveGroupSession(group)
-			}
-		}
+	isAdmin, err := b.isChatAdmin(msg.Chat.ID, msg.From.ID)
+	if err != nil {
+		log.Printf("Error getting chat member: %v", err)
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Ошибка при проверке прав.")
+		b.Api.Send(reply)
+		return false
 	}
 
-	// Обновляем сообщение
-	text := fmt.Sprintf("✅ Настройки сохранены!\n\nГруппа: %s\nТип медиа: %s\n\n☁️ Облачная папка: %s",
-		group.GroupTitle, validTypes[mediaType], group.CloudFolderPath)
-
-	if group.PublicURL != "" {
-		text += fmt.Sprintf("\n\n🔗 Публичная ссылка:\n%s", group.PublicURL)
+	if !isAdmin {
+		reply := tgbotapi.NewMessage(msg.Chat.ID,
+			"❌ Только администратор группы может менять эту настройку.")
+		b.Api.Send(reply)
+		return false
 	}
-
-	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, text)
-	b.Api.Send(editMsg)
-
-	// Отправляем инструкцию
-	instruction := `📖 Инструкция:
-
-Теперь бот будет автоматически загружать все новые медиафайлы указанного типа из этой группы прямо в ваше облако Mail.ru.
-
-Для просмотра статуса и ссылки используйте команду /group_status`
-
-	msg := tgbotapi.NewMessage(chatID, instruction)
-	b.Api.Send(msg)
+	return true
 }
 
 func (b *Bot) handleGroupStatus(msg *tgbotapi.Message) {
@@ -205,42 +155,49 @@ func (b *Bot) handleGroupStatus(msg *tgbotapi.Message) {
 	groupStats, err := b.groupRepo.GetGroupMediaStats(msg.Chat.ID)
 	if err != nil {
 		log.Printf("Error getting media stats: %v", err)
-		groupStats.PhotosCount, groupStats.VideosCount = 0, 0
-	}
-
-	mediaTypeText := map[string]string{
-		"photos": "📷 Только фото",
-		"videos": "🎥 Только видео",
-		"all":    "📷🎥 Все медиа",
+		groupStats = &domain.GroupStats{}
 	}
 
 	text := fmt.Sprintf(`📊 Статус группы: %s
 
-Тип медиа: %s
-Загружено фото: %d
-Загружено видео: %d
+Типы медиа: %s
+Загружено: %s
+Общий объем: %s
 ☁️ Облачная папка: %s`,
 		group.GroupTitle,
-		mediaTypeText[group.MediaType],
-		groupStats.PhotosCount,
-		groupStats.VideosCount,
+		domain.MediaTypesTitle(group.MediaType),
+		formatMediaCounts(groupStats),
+		formatBytes(groupStats.TotalSizeBytes),
 		group.CloudFolderPath)
 
+	if state := groupState(group); state != domain.StateActive {
+		text += "\nСостояние: " + stateTitle(state)
+		if state == domain.StateDryRun {
+			text += "\n" + dryRunSummary(group)
+		}
+	}
+
+	if group.HistoryProcessed {
+		text += "\n📦 История импортирована из экспорта"
+	}
+
 	// Добавляем публичную ссылку, если она есть
 	if group.PublicURL != "" {
 		text += fmt.Sprintf("\n\n🔗 Публичная ссылка:\n%s", group.PublicURL)
 		text += "\n\n📤 Поделитесь этой ссылкой с друзьями для просмотра медиа!"
 	} else {
 		// Пытаемся создать публичную ссылку, если её еще нет
-		session, err := b.oauth.GetUserSession(msg.Chat.ID)
-		if err == nil && session != nil && session.AccessToken != "" {
-			publicURL, err := b.mediaProcessor.CreatePublicLink(session.AccessToken, group.CloudFolderPath)
-			if err == nil && publicURL != "" {
-				group.PublicURL = publicURL
-				b.groupRepo.SaveGroupSession(group)
-				text += fmt.Sprintf("\n\n🔗 Публичная ссылка:\n%s", publicURL)
-				text += "\n\n📤 Поделитесь этой ссылкой с друзьями для просмотра медиа!"
-			}
+		var publicURL string
+		err := b.tokens.Do(msg.Chat.ID, func(accessToken string) error {
+			var err error
+			publicURL, err = b.mediaProcessor.CreatePublicLink(accessToken, group.CloudFolderPath)
+			return err
+		})
+		if err == nil && publicURL != "" {
+			group.PublicURL = publicURL
+			b.groupRepo.SaveGroupSession(group)
+			text += fmt.Sprintf("\n\n🔗 Публичная ссылка:\n%s", publicURL)
+			text += "\n\n📤 Поделитесь этой ссылкой с друзьями для просмотра медиа!"
 		}
 	}
 
@@ -265,16 +222,14 @@ func (b *Bot) handleMyGroups(msg *tgbotapi.Message) {
 
 	text := "📋 Ваши группы:\n\n"
 	for i, group := range groups {
-		mediaTypeText := map[string]string{
-			"photos": "📷",
-			"videos": "🎥",
-			"all":    "📷🎥",
+		groupStats, err := b.groupRepo.GetGroupMediaStats(group.GroupID)
+		if err != nil {
+			log.Printf("Error getting media stats: %v", err)
+			groupStats = &domain.GroupStats{}
 		}
-
-		groupStats, _ := b.groupRepo.GetGroupMediaStats(group.GroupID)
-		text += fmt.Sprintf("%d. %s %s\n   ☁️ В облаке: 📷%d 🎥%d",
-			i+1, mediaTypeText[group.MediaType], group.GroupTitle,
-			groupStats.PhotosCount, groupStats.VideosCount)
+		text += fmt.Sprintf("%d. %s %s\n   ☁️ В облаке: %s",
+			i+1, mediaTypesIcons(group.MediaType), group.GroupTitle,
+			formatMediaCounts(groupStats))
 
 		if group.PublicURL != "" {
 			text += fmt.Sprintf("\n   🔗 Ссылка: %s", group.PublicURL)
@@ -286,3 +241,17 @@ func (b *Bot) handleMyGroups(msg *tgbotapi.Message) {
 	reply.ParseMode = "HTML"
 	b.Api.Send(reply)
 }
+
+// formatBytes форматирует размер в человекочитаемом виде
+func formatBytes(size int64) string {
+	switch {
+	case size >= 1<<30:
+		return fmt.Sprintf("%.2f ГБ", float64(size)/(1<<30))
+	case size >= 1<<20:
+		return fmt.Sprintf("%.2f МБ", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f КБ", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d Б", size)
+	}
+}
@@ -1,10 +1,12 @@
 package bot
 
 import (
+	"errors"
 	"fmt"
 	"log"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mail_helper_bot/internal/pkg/oauth/oauth_service"
 )
 
 func handleStartCommand(bot *Bot, msg *tgbotapi.Message) {
@@ -21,11 +23,28 @@ func handleStartCommand(bot *Bot, msg *tgbotapi.Message) {
 /status - Проверить статус авторизации  
 /logout - Выйти из аккаунта
 /my_groups - Мои настроенные группы
+/failed - Неудачные загрузки и повтор
+/import - Импорт истории из экспорта Telegram Desktop
+/add_channel - Подключить канал
+/digest - Ежедневная или еженедельная сводка по группам
 
 📋 Команды в группах:
 /group_status - Статус выгрузки медиа
 /share - Публичная ссылка
 /bot_settings - Настройки типа медиа
+/naming - Шаблон имен файлов
+/layout - Раскладка по подпапкам
+/albums - Отдельные папки для альбомов
+/filters - Правила отбора медиа
+/routes - Папки по хештегам и подписям
+/sidecar - Файлы с подписями и index.json
+/privacy - Удаление геолокации из фото
+/edits - Версии отредактированных медиа
+/topics - Подпапки тем форума
+/save, /forget - Ответом на сообщение: загрузить или удалить его медиа
+/pause, /resume - Приостановить и возобновить выгрузку
+/dryrun - Пробный режим без загрузки в облако
+/optout, /optin - Участнику: не загружать или снова загружать свои медиа
 /setup_group - Принудительная настройка группы
 
 🚀 **Как начать:**
@@ -99,20 +118,25 @@ func handleStatusCommand(bot *Bot, msg *tgbotapi.Message) {
 		return
 	}
 
-	_, err = bot.oauth.GetUserInfo(session.AccessToken)
+	// Токен обновляется через общий провайдер: заранее перед истечением и
+	// без гонки с одновременными загрузками. Выход только при отозванной сессии.
+	accessToken, err := bot.tokens.GetToken(msg.Chat.ID)
+	if errors.Is(err, oauth_service.ErrSessionExpired) {
+		bot.storage.Logout(msg.Chat.ID)
+		reply := tgbotapi.NewMessage(msg.Chat.ID,
+			"Сессия устарела. Пожалуйста, авторизуйтесь снова с помощью /login")
+		bot.Api.Send(reply)
+		return
+	}
+	if err == nil {
+		_, err = bot.oauth.GetUserInfo(accessToken)
+	}
 	if err != nil {
-		newToken, err := bot.oauth.RefreshToken(session.RefreshToken)
-		if err != nil {
-			bot.storage.Logout(msg.Chat.ID)
-			reply := tgbotapi.NewMessage(msg.Chat.ID,
-				"Сессия устарела. Пожалуйста, авторизуйтесь снова с помощью /login")
-			bot.Api.Send(reply)
-			return
-		}
-
-		userInfo, _ := bot.oauth.GetUserInfo(newToken.AccessToken)
-		bot.oauth.SaveUserSession(msg.Chat.ID, newToken, userInfo)
-		session.AccessToken = newToken.AccessToken
+		log.Printf("Error checking session of %d: %v", msg.Chat.ID, err)
+		reply := tgbotapi.NewMessage(msg.Chat.ID,
+			"⚠️ Не удалось проверить авторизацию. Попробуйте позже.")
+		bot.Api.Send(reply)
+		return
 	}
 
 	text := fmt.Sprintf("✅ Вы авторизованы!\n\n👤 Имя: %s\n📧 Email: %s",
@@ -0,0 +1,443 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mail_helper_bot/internal/pkg/filter"
+	"mail_helper_bot/internal/pkg/group/domain"
+	"mail_helper_bot/internal/pkg/media"
+	"mail_helper_bot/internal/pkg/naming"
+	"mail_helper_bot/internal/pkg/tgexport"
+)
+
+// importProgressEvery - через сколько сообщений с медиа обновлять прогресс импорта
+const importProgressEvery = 25
+
+// importFileUniquePrefix - префикс file_unique_id файлов из экспорта: у них нет
+// file_id Telegram, и дубликаты определяются по содержимому
+const importFileUniquePrefix = "export:"
+
+// importMaxTotalSize - сколько всего можно распаковать из одного архива.
+// Защищает диск от архивов, которые распаковываются в гигантские файлы.
+const importMaxTotalSize int64 = 20 << 30
+
+// pendingImport - архив экспорта, присланный владельцем и ожидающий выбора группы
+type pendingImport struct {
+	FileID   string
+	FileName string
+	FileSize int64
+	running  bool
+}
+
+// importStats - итоги импорта архива
+type importStats struct {
+	total      int // Сообщений с медиа в экспорте
+	done       int
+	queued     int
+	duplicates int
+	skipped    int
+	failed     int
+	tooLarge   bool // Импорт остановлен по общему лимиту размера
+}
+
+// SetImportDir задает папку, в которую распаковываются архивы экспорта
+func (b *Bot) SetImportDir(dir string) {
+	b.importDir = dir
+}
+
+// handleImportCommand объясняет, как импортировать историю группы
+func (b *Bot) handleImportCommand(msg *tgbotapi.Message) {
+	text := fmt.Sprintf(`📦 Импорт истории группы
+
+Бот не может прочитать старые сообщения группы, но может загрузить медиа из экспорта Telegram Desktop:
+
+1. В Telegram Desktop откройте группу → ⋮ → Экспорт истории чата
+2. Отметьте нужные типы медиа и выберите формат JSON
+3. Упакуйте папку экспорта (с result.json) в zip-архив
+4. Пришлите архив сюда файлом и выберите группу
+
+Файлы пройдут через обычную очередь загрузки с настройками группы, уже загруженные пропускаются. Размер архива - до %s.`,
+		formatBytes(b.mediaProcessor.APIConfig().MaxFileSize()))
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	b.Api.Send(reply)
+}
+
+// isExportArchive сообщает, похож ли документ из личного чата на zip-архив
+// экспорта. Остальные файлы, присланные боту, к импорту не относятся.
+func isExportArchive(doc *tgbotapi.Document) bool {
+	if doc == nil {
+		return false
+	}
+	return strings.EqualFold(path.Ext(doc.FileName), ".zip") || doc.MimeType == "application/zip"
+}
+
+// handleImportArchive принимает zip-архив экспорта в личном чате и предлагает
+// выбрать группу, в облако которой загрузить его медиа
+func (b *Bot) handleImportArchive(msg *tgbotapi.Message) {
+	doc := msg.Document
+	if limit := b.mediaProcessor.APIConfig().MaxFileSize(); int64(doc.FileSize) > limit {
+		reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf(
+			"❌ Архив весит %s, бот может скачать не больше %s (%s).\n\nРазбейте экспорт на части по датам.",
+			formatBytes(int64(doc.FileSize)), formatBytes(limit), b.mediaProcessor.APIConfig().ModeName()))
+		b.Api.Send(reply)
+		return
+	}
+
+	groups, err := b.groupRepo.GetUserGroups(msg.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting user groups: %v", err)
+		return
+	}
+	if len(groups) == 0 {
+		reply := tgbotapi.NewMessage(msg.Chat.ID,
+			"🤷‍♂️ Вы не управляете ни одной группой с этим ботом. Сначала добавьте бота в группу.")
+		b.Api.Send(reply)
+		return
+	}
+
+	b.importsMu.Lock()
+	if current := b.imports[msg.Chat.ID]; current != nil && current.running {
+		b.importsMu.Unlock()
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "⏳ Предыдущий импорт еще идет. Дождитесь его окончания.")
+		b.Api.Send(reply)
+		return
+	}
+	b.imports[msg.Chat.ID] = &pendingImport{
+		FileID:   doc.FileID,
+		FileName: doc.FileName,
+		FileSize: int64(doc.FileSize),
+	}
+	b.importsMu.Unlock()
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, group := range groups {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(group.GroupTitle, fmt.Sprintf("import:%d", group.GroupID)),
+		))
+	}
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID,
+		fmt.Sprintf("📦 Архив %s получен. В облако какой группы загрузить медиа?", doc.FileName))
+	reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	b.Api.Send(reply)
+}
+
+// handleImportSelection запускает импорт присланного архива в выбранную группу.
+// Формат: import:{groupID}
+func (b *Bot) handleImportSelection(query *tgbotapi.CallbackQuery, data string) {
+	chatID := query.Message.Chat.ID
+	messageID := query.Message.MessageID
+
+	groupID, err := strconv.ParseInt(strings.TrimPrefix(data, "import:"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	group, err := b.groupRepo.GetGroupSession(groupID)
+	if err != nil || group == nil || group.OwnerChatID != query.From.ID {
+		edit := tgbotapi.NewEditMessageText(chatID, messageID, "❌ Группа не найдена или вы не ее владелец.")
+		b.Api.Send(edit)
+		return
+	}
+
+	if group.PublicURL == "" {
+		edit := tgbotapi.NewEditMessageText(chatID, messageID,
+			fmt.Sprintf("❌ Группа \"%s\" еще не настроена: выберите типы медиа в группе и повторите.", group.GroupTitle))
+		b.Api.Send(edit)
+		return
+	}
+
+	if groupState(group) == domain.StatePaused {
+		edit := tgbotapi.NewEditMessageText(chatID, messageID,
+			fmt.Sprintf("❌ Выгрузка в группе \"%s\" приостановлена. Возобновите ее командой /resume в группе.", group.GroupTitle))
+		b.Api.Send(edit)
+		return
+	}
+
+	session, err := b.oauth.GetUserSession(group.OwnerChatID)
+	if err != nil || session == nil || session.AccessToken == "" {
+		edit := tgbotapi.NewEditMessageText(chatID, messageID, "❌ Сначала авторизуйтесь через /login.")
+		b.Api.Send(edit)
+		return
+	}
+
+	b.importsMu.Lock()
+	pending := b.imports[query.From.ID]
+	if pending == nil || pending.running {
+		b.importsMu.Unlock()
+		if pending == nil {
+			edit := tgbotapi.NewEditMessageText(chatID, messageID, "❌ Архив не найден. Пришлите его еще раз.")
+			b.Api.Send(edit)
+		}
+		return
+	}
+	pending.running = true
+	b.importsMu.Unlock()
+
+	go func() {
+		defer func() {
+			b.importsMu.Lock()
+			delete(b.imports, query.From.ID)
+			b.importsMu.Unlock()
+		}()
+		b.runImport(chatID, messageID, group, pending)
+	}()
+}
+
+// runImport скачивает архив, ставит медиа экспорта в очередь загрузки и
+// обновляет сообщение с прогрессом
+func (b *Bot) runImport(chatID int64, messageID int, group *domain.GroupSession, pending *pendingImport) {
+	progress := func(text string) {
+		edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+		if _, err := b.Api.Send(edit); err != nil {
+			log.Printf("Error updating import progress: %v", err)
+		}
+	}
+
+	progress(fmt.Sprintf("⏳ Скачиваю архив %s...", pending.FileName))
+
+	dir := filepath.Join(b.importDir, fmt.Sprintf("%d_%d", group.GroupID, time.Now().UnixNano()))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Printf("Error creating import dir %s: %v", dir, err)
+		progress("❌ Не удалось подготовить импорт. Попробуйте позже.")
+		return
+	}
+	// Папка удаляется, если в ней не осталось файлов. Иначе ее удалит
+	// последнее задание загрузки через removeImportedFile.
+	defer os.Remove(dir)
+
+	zipPath := filepath.Join(dir, "export.zip")
+	if _, err := b.mediaProcessor.SaveTelegramFile(pending.FileID, pending.FileSize, zipPath); err != nil {
+		log.Printf("Error downloading export archive for group %d: %v", group.GroupID, err)
+		progress("❌ Не удалось скачать архив. Попробуйте прислать его еще раз.")
+		os.Remove(zipPath)
+		return
+	}
+	defer os.Remove(zipPath)
+
+	archive, err := tgexport.OpenArchive(zipPath)
+	if errors.Is(err, tgexport.ErrResultTooLarge) {
+		log.Printf("Error opening export archive for group %d: %v", group.GroupID, err)
+		progress("❌ Файл result.json в архиве слишком большой. Экспортируйте чат по частям, за меньшие периоды.")
+		return
+	}
+	if err != nil {
+		log.Printf("Error opening export archive for group %d: %v", group.GroupID, err)
+		progress("❌ Это не экспорт Telegram Desktop: в архиве нет result.json. Экспортируйте чат в формате JSON.")
+		return
+	}
+	defer archive.Close()
+	archive.SetLimits(b.mediaProcessor.APIConfig().MaxFileSize(), importMaxTotalSize)
+
+	stats := &importStats{}
+	for i := range archive.Export.Messages {
+		if archive.Export.Messages[i].MediaPath() != "" {
+			stats.total++
+		}
+	}
+
+	log.Printf("Importing %d media of export %q into group %d", stats.total, archive.Export.Name, group.GroupID)
+	progress(importProgressText(group, archive.Export.Name, stats))
+
+	for i := range archive.Export.Messages {
+		m := &archive.Export.Messages[i]
+		if m.MediaPath() == "" {
+			continue
+		}
+
+		b.importMessage(group, archive, m, dir, stats)
+		if stats.tooLarge {
+			log.Printf("Import into group %d stopped: extracted size limit reached", group.GroupID)
+			break
+		}
+		stats.done++
+		if stats.done%importProgressEvery == 0 {
+			progress(importProgressText(group, archive.Export.Name, stats))
+		}
+	}
+
+	// История считается импортированной, только если медиа действительно
+	// загружаются и в очередь попало все: без остановки на лимите и без сбоев.
+	// Иначе повторный импорт того же экспорта должен остаться возможным.
+	if groupState(group) == domain.StateActive && !stats.tooLarge && stats.failed == 0 {
+		if err := b.groupRepo.MarkHistoryProcessed(group.GroupID); err != nil {
+			log.Printf("Error marking history processed for group %d: %v", group.GroupID, err)
+		}
+	}
+
+	log.Printf("Import into group %d finished: %+v", group.GroupID, *stats)
+	progress(importProgressText(group, archive.Export.Name, stats))
+}
+
+// importMessage распаковывает медиа сообщения экспорта и ставит его в очередь
+// загрузки так же, как медиа из группы: с типами, фильтрами, маршрутами и дедупликацией
+func (b *Bot) importMessage(group *domain.GroupSession, archive *tgexport.Archive, m *tgexport.Message,
+	dir string, stats *importStats) {
+	relPath := m.MediaPath()
+
+	mediaType := exportMediaType(m)
+	if mediaType == "" || !group.AcceptsMediaType(mediaType) || b.isMemberOptedOut(group, m.SenderID()) {
+		stats.skipped++
+		return
+	}
+
+	fileName := m.FileName
+	if fileName == "" {
+		fileName = path.Base(relPath)
+	}
+
+	localPath := filepath.Join(dir, fmt.Sprintf("%d_%s", m.ID, filepath.Base(relPath)))
+	size, contentHash, err := archive.Extract(relPath, localPath)
+	if err != nil {
+		log.Printf("Error extracting %s from export: %v", relPath, err)
+		if errors.Is(err, tgexport.ErrTotalTooLarge) {
+			stats.tooLarge = true
+			return
+		}
+		stats.failed++
+		return
+	}
+
+	mediaInfo := &media.MediaInfo{
+		FileUniqueID:    importFileUniquePrefix + contentHash,
+		Type:            mediaType,
+		FileName:        fileName,
+		MimeType:        m.MimeType,
+		FileSize:        size,
+		Width:           m.Width,
+		Height:          m.Height,
+		Duration:        m.Duration,
+		MessageID:       m.ID,
+		MessageDate:     m.Unix(),
+		SenderID:        m.SenderID(),
+		SenderName:      m.From,
+		Caption:         m.Caption(),
+		CloudFolderPath: group.CloudFolderPath,
+		LocalPath:       localPath,
+	}
+	if mediaInfo.MimeType == "" && mediaType == domain.MediaPhoto {
+		mediaInfo.MimeType = "image/jpeg"
+	}
+
+	decision := group.Filters.Evaluate(filter.Input{
+		MimeType: mediaInfo.MimeType,
+		Ext:      naming.ExtFor(mediaInfo.FileName, mediaInfo.MimeType, mediaInfo.Type),
+		Size:     mediaInfo.FileSize,
+		SenderID: mediaInfo.SenderID,
+		Caption:  mediaInfo.Caption,
+	})
+	if !decision.Allowed {
+		log.Printf("Skipping imported %s in group %d by filter: %s", relPath, group.GroupID, decision.Reason)
+		os.Remove(localPath)
+		stats.skipped++
+		return
+	}
+
+	// Файл мог уже прийти в группу через бота или с прошлым импортом
+	processed, err := b.groupRepo.IsContentProcessed(contentHash, group.GroupID)
+	if err != nil {
+		log.Printf("Error checking imported content %s: %v", contentHash, err)
+		os.Remove(localPath)
+		stats.failed++
+		return
+	}
+	if processed {
+		os.Remove(localPath)
+		stats.duplicates++
+		return
+	}
+
+	// Хеш известен заранее: копия из другой группы владельца добавится без загрузки
+	if err := b.groupRepo.SaveMediaContentHash(mediaInfo.FileUniqueID, contentHash, size); err != nil {
+		log.Printf("Error saving media content hash: %v", err)
+	}
+
+	fields := namingFields(mediaInfo)
+	routeMedia(group, mediaInfo, mediaInfo.Caption, fields)
+
+	if !b.enqueueMedia(group, mediaInfo, fields) {
+		// Тот же файл уже ждет загрузки - например, он дважды встречается в экспорте
+		os.Remove(localPath)
+		stats.duplicates++
+		return
+	}
+	stats.queued++
+}
+
+// exportMediaType сопоставляет медиа экспорта с категориями медиа группы.
+// Стикеры не импортируются.
+func exportMediaType(m *tgexport.Message) string {
+	if m.Photo != "" {
+		return domain.MediaPhoto
+	}
+
+	switch m.MediaType {
+	case tgexport.MediaVideoFile:
+		return domain.MediaVideo
+	case tgexport.MediaAnimation:
+		return domain.MediaAnimation
+	case tgexport.MediaVideoMessage:
+		return domain.MediaVideoNote
+	case tgexport.MediaVoiceMessage:
+		return domain.MediaVoice
+	case tgexport.MediaAudioFile:
+		return domain.MediaAudio
+	case tgexport.MediaSticker:
+		return ""
+	}
+
+	// Фото и видео, отправленные файлом, относятся к фото и видео
+	if strings.HasPrefix(m.MimeType, "image/") {
+		return domain.MediaPhoto
+	} else if strings.HasPrefix(m.MimeType, "video/") {
+		return domain.MediaVideo
+	}
+	return domain.MediaDocument
+}
+
+func importProgressText(group *domain.GroupSession, exportName string, stats *importStats) string {
+	title := "⏳ Импорт истории"
+	if stats.tooLarge {
+		title = fmt.Sprintf("⚠️ Импорт истории остановлен: распаковано больше %s", formatBytes(importMaxTotalSize))
+	} else if stats.done == stats.total {
+		title = "✅ Импорт истории завершен"
+	}
+
+	return fmt.Sprintf(`%s
+
+• Экспорт: %s
+• Группа: %s
+• Обработано: %d из %d
+• Поставлено в очередь: %d
+• Уже в облаке: %d
+• Пропущено по настройкам: %d
+• Ошибки: %d
+
+Файлы загружаются в фоне, статус - /group_status в группе.`,
+		title, exportName, group.GroupTitle, stats.done, stats.total,
+		stats.queued, stats.duplicates, stats.skipped, stats.failed)
+}
+
+// removeImportedFile удаляет распакованный файл экспорта, когда задание
+// загрузки завершено
+func removeImportedFile(mediaInfo *media.MediaInfo) {
+	if mediaInfo.LocalPath == "" {
+		return
+	}
+	if err := os.Remove(mediaInfo.LocalPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("Error removing imported file %s: %v", mediaInfo.LocalPath, err)
+	}
+	// Папка импорта удаляется вместе с последним файлом; пока в ней есть
+	// файлы или архив, os.Remove ничего не делает
+	os.Remove(filepath.Dir(mediaInfo.LocalPath))
+}
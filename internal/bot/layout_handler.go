@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mail_helper_bot/internal/pkg/naming"
+)
+
+// handleLayoutCommand показывает текущую раскладку по подпапкам и клавиатуру выбора
+func (b *Bot) handleLayoutCommand(msg *tgbotapi.Message) {
+	group, err := b.groupRepo.GetGroupSession(msg.Chat.ID)
+	if err != nil || group == nil {
+		reply := tgbotapi.NewMessage(msg.Chat.ID,
+			"❌ Группа не настроена. Используйте /setup_group для настройки.")
+		b.Api.Send(reply)
+		return
+	}
+
+	if !b.checkGroupAdmin(msg) {
+		return
+	}
+
+	text := fmt.Sprintf(`📂 Раскладка файлов по подпапкам
+
+Сейчас: %s
+Папка группы: %s
+
+Выберите, как раскладывать новые файлы:`,
+		naming.LayoutTitle(group.FolderLayout), group.CloudFolderPath)
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, layout := range naming.Layouts {
+		title := layout.Title
+		if layout.Name == group.FolderLayout || (group.FolderLayout == "" && layout.Name == naming.LayoutFlat) {
+			title = "✅ " + title
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(title, fmt.Sprintf("layout:%d:%s", group.GroupID, layout.Name)),
+		))
+	}
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	b.Api.Send(reply)
+}
+
+// handleLayoutSelection сохраняет выбранную раскладку. Формат: layout:{groupID}:{layout}
+func (b *Bot) handleLayoutSelection(query *tgbotapi.CallbackQuery, data string) {
+	parts := strings.Split(data, ":")
+	if len(parts) != 3 || !naming.IsValidLayout(parts[2]) {
+		return
+	}
+
+	group := b.groupFromCallback(query, data, 3)
+	if group == nil {
+		return
+	}
+
+	group.FolderLayout = parts[2]
+	if err := b.groupRepo.SaveGroupSession(group); err != nil {
+		log.Printf("Error saving folder layout: %v", err)
+		return
+	}
+
+	text := fmt.Sprintf("✅ Раскладка сохранена: %s\n\nНовые файлы будут попадать в подпапки внутри %s. Уже загруженные файлы не перемещаются.",
+		naming.LayoutTitle(group.FolderLayout), group.CloudFolderPath)
+
+	editMsg := tgbotapi.NewEditMessageText(query.Message.Chat.ID, query.Message.MessageID, text)
+	b.Api.Send(editMsg)
+}
@@ -0,0 +1,196 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"path"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mail_helper_bot/internal/pkg/group/domain"
+	"mail_helper_bot/internal/pkg/sidecar"
+)
+
+// handleSaveCommand загружает медиа сообщения, на которое отвечает команда,
+// в обход выбранных типов медиа, фильтров и выключенных тем. Папка и имя
+// выбираются как обычно.
+func (b *Bot) handleSaveCommand(msg *tgbotapi.Message, thread threadInfo) {
+	group, target, ok := b.manualCommandTarget(msg, "/save")
+	if !ok {
+		return
+	}
+
+	session, err := b.oauth.GetUserSession(group.OwnerChatID)
+	if err != nil || session == nil || session.AccessToken == "" {
+		b.sendErrorMessage(msg.Chat.ID, "❌ Владелец облака группы не авторизован. Ему нужно выполнить /login в личном чате с ботом.")
+		return
+	}
+
+	mediaInfo := extractMediaInfo(target)
+	if mediaInfo == nil {
+		b.sendErrorMessage(msg.Chat.ID, "❌ В этом сообщении нет медиа.")
+		return
+	}
+
+	if limit := b.mediaProcessor.APIConfig().MaxFileSize(); mediaInfo.FileSize > limit {
+		b.sendErrorMessage(msg.Chat.ID, fmt.Sprintf("❌ Файл весит %s, бот может скачать не больше %s (%s).",
+			formatBytes(mediaInfo.FileSize), formatBytes(limit), b.mediaProcessor.APIConfig().ModeName()))
+		return
+	}
+
+	processed, err := b.groupRepo.IsMediaProcessed(mediaInfo.FileUniqueID, group.GroupID)
+	if err != nil {
+		log.Printf("Error checking media processing: %v", err)
+		b.sendErrorMessage(msg.Chat.ID, "❌ Ошибка при проверке файла.")
+		return
+	}
+	if processed {
+		b.sendErrorMessage(msg.Chat.ID, "ℹ️ Этот файл уже загружен в облако.")
+		return
+	}
+
+	fillMessageMetadata(mediaInfo, target)
+	// Отказ участника сильнее решения администратора
+	if b.isMemberOptedOut(group, mediaInfo.SenderID) {
+		b.sendErrorMessage(msg.Chat.ID, "❌ Автор сообщения отказывается от загрузки своих медиа (/optout).")
+		return
+	}
+	// Часть альбома сохраняется отдельно: альбом уже учтен
+	mediaInfo.AlbumID = ""
+
+	topic, err := b.messageTopic(group, thread)
+	if err != nil {
+		log.Printf("Error getting topic %d of group %d: %v", thread.ThreadID, group.GroupID, err)
+	}
+	if topic != nil {
+		mediaInfo.TopicFolder = topic.Folder()
+	}
+
+	fields := namingFields(mediaInfo)
+	routeMedia(group, mediaInfo, mediaInfo.Caption, fields)
+
+	if !b.enqueueMedia(group, mediaInfo, fields) {
+		b.sendErrorMessage(msg.Chat.ID, "ℹ️ Этот файл уже ждет загрузки.")
+		return
+	}
+
+	log.Printf("Media of message %d in group %d saved manually by user %d", target.MessageID, group.GroupID, msg.From.ID)
+	text := fmt.Sprintf("📥 Файл поставлен в очередь загрузки:\n%s", mediaInfo.CloudFilePath())
+	if groupState(group) == domain.StateDryRun {
+		// В пробном режиме воркер только учтет файл в сводке /dryrun
+		text = fmt.Sprintf("🧪 Пробный режим: файл не загружается, а только учитывается в сводке. "+
+			"Он был бы сохранен как:\n%s", mediaInfo.CloudFilePath())
+	}
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	reply.ReplyToMessageID = target.MessageID
+	b.Api.Send(reply)
+}
+
+// handleForgetCommand удаляет из облака все версии медиа сообщения, на
+// которое отвечает команда, вместе со спутниками и забывает их, так что
+// файл можно будет загрузить снова
+func (b *Bot) handleForgetCommand(msg *tgbotapi.Message) {
+	group, target, ok := b.manualCommandTarget(msg, "/forget")
+	if !ok {
+		return
+	}
+
+	records, err := b.groupRepo.GetMessageMedia(group.GroupID, target.MessageID)
+	if err != nil {
+		log.Printf("Error getting media of message %d: %v", target.MessageID, err)
+		b.sendErrorMessage(msg.Chat.ID, "❌ Ошибка при поиске файла.")
+		return
+	}
+	if len(records) == 0 {
+		b.sendErrorMessage(msg.Chat.ID, "ℹ️ Медиа этого сообщения не загружалось в облако.")
+		return
+	}
+
+	if err := b.removeUploadedMedia(group, records); err != nil {
+		log.Printf("Error forgetting media of message %d in group %d: %v", target.MessageID, group.GroupID, err)
+		b.sendErrorMessage(msg.Chat.ID, "❌ Не удалось удалить файл из облака. Попробуйте позже.")
+		return
+	}
+
+	log.Printf("Media of message %d in group %d forgotten by user %d", target.MessageID, group.GroupID, msg.From.ID)
+	text := "🗑 Файл удален из облака и из истории загрузок."
+	if len(records) > 1 {
+		text = fmt.Sprintf("🗑 Удалено версий файла: %d. Они убраны из облака и из истории загрузок.", len(records))
+	}
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	reply.ReplyToMessageID = target.MessageID
+	b.Api.Send(reply)
+}
+
+// removeUploadedMedia удаляет файлы из облака вместе со спутниками, забывает
+// их в processed_media и обновляет index.json затронутых папок
+func (b *Bot) removeUploadedMedia(group *domain.GroupSession, records []*domain.ProcessedMedia) error {
+	// В режиме замены версии лежат по одному пути
+	var paths, ids []string
+	folders := make(map[string]bool)
+	seen := make(map[string]bool)
+	for _, record := range records {
+		ids = append(ids, record.ID)
+		if record.CloudPath == "" || seen[record.CloudPath] {
+			continue
+		}
+		seen[record.CloudPath] = true
+		paths = append(paths, record.CloudPath)
+		// Формат спутников мог смениться после загрузки, поэтому удаляются
+		// оба возможных спутника: отсутствующий файл удаление не ломает
+		paths = append(paths,
+			sidecar.FileName(record.CloudPath, sidecar.FormatJSON),
+			sidecar.FileName(record.CloudPath, sidecar.FormatText))
+		folders[path.Dir(record.CloudPath)] = true
+
+		// Оригинал с метаданными лежит вне папки группы и удаляется отдельно
+		if record.OriginalPath != "" && !seen[record.OriginalPath] {
+			seen[record.OriginalPath] = true
+			paths = append(paths, record.OriginalPath)
+		}
+	}
+
+	err := b.tokens.Do(group.OwnerChatID, func(accessToken string) error {
+		for _, cloudPath := range paths {
+			if err := b.mediaProcessor.DeleteCloudFile(accessToken, cloudPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := b.groupRepo.DeleteProcessedMedia(group.GroupID, ids); err != nil {
+		return fmt.Errorf("files deleted from cloud, but not from history: %w", err)
+	}
+
+	if group.FolderIndex {
+		for folder := range folders {
+			b.scheduleFolderIndex(group, folder)
+		}
+	}
+	return nil
+}
+
+// manualCommandTarget проверяет, что команда отправлена ответом на сообщение
+// администратором настроенной группы, и возвращает группу и это сообщение
+func (b *Bot) manualCommandTarget(msg *tgbotapi.Message, command string) (*domain.GroupSession, *tgbotapi.Message, bool) {
+	group, err := b.groupRepo.GetGroupSession(msg.Chat.ID)
+	if err != nil || group == nil {
+		reply := tgbotapi.NewMessage(msg.Chat.ID,
+			"❌ Группа не настроена. Используйте /setup_group для настройки.")
+		b.Api.Send(reply)
+		return nil, nil, false
+	}
+
+	if msg.ReplyToMessage == nil {
+		b.sendErrorMessage(msg.Chat.ID, fmt.Sprintf("❌ Отправьте %s ответом на сообщение с медиа.", command))
+		return nil, nil, false
+	}
+
+	if !b.checkGroupAdmin(msg) {
+		return nil, nil, false
+	}
+	return group, msg.ReplyToMessage, true
+}
@@ -3,123 +3,379 @@ package bot
 import (
 	"fmt"
 	"log"
+	"path"
 	"strings"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mail_helper_bot/internal/pkg/filter"
 	"mail_helper_bot/internal/pkg/group/domain"
 	"mail_helper_bot/internal/pkg/media"
+	"mail_helper_bot/internal/pkg/naming"
+	"mail_helper_bot/internal/pkg/sidecar"
+	queueDomain "mail_helper_bot/internal/pkg/upload_queue/domain"
 )
 
-func (b *Bot) handleMediaMessage(msg *tgbotapi.Message) {
+// maxNameCollisions - сколько суффиксов перебирать при совпадении имен
+const maxNameCollisions = 1000
+
+func (b *Bot) handleMediaMessage(msg *tgbotapi.Message, thread threadInfo) {
+	group, mediaInfo, fields, ok := b.acceptMedia(msg, thread)
+	if !ok {
+		return
+	}
+
+	// Части альбома собираются вместе и ставятся в очередь по истечении окна
+	if mediaInfo.AlbumID != "" {
+		b.collectAlbumItem(group, mediaInfo)
+		return
+	}
+
+	b.enqueueMedia(group, mediaInfo, fields)
+}
+
+// acceptMedia проверяет, нужно ли загружать медиа сообщения: группа настроена,
+// владелец авторизован, медиа проходит по типу, размеру и фильтрам и еще не
+// загружалось. Медиа из темы форума проверяется по настройкам темы и кладется
+// в ее подпапку. Возвращает группу, данные медиа с папкой назначения и поля для имени файла.
+func (b *Bot) acceptMedia(msg *tgbotapi.Message, thread threadInfo) (*domain.GroupSession, *media.MediaInfo, naming.Fields, bool) {
 	// Проверяем, есть ли настройки для этой группы
 	group, err := b.groupRepo.GetGroupSession(msg.Chat.ID)
 	if err != nil || group == nil {
-		return
+		return nil, nil, naming.Fields{}, false
 	}
 
-	log.Println("handle media")
-
-	log.Println("Group: ", group)
+	if groupState(group) == domain.StatePaused {
+		log.Printf("Skipping media in group %d: archiving is paused", group.GroupID)
+		return nil, nil, naming.Fields{}, false
+	}
 
 	// Проверяем авторизацию владельца группы
 	session, err := b.oauth.GetUserSession(group.OwnerChatID)
 	if err != nil || session == nil || session.AccessToken == "" {
 		log.Printf("Owner not authorized for group %d. msg.Chat.ID = %d", group.GroupID, msg.Chat.ID)
-		return
+		b.notifyOwnerAuth(group)
+		return nil, nil, naming.Fields{}, false
 	}
 
 	// Определяем тип медиа и собираем информацию
-	var mediaInfo *media.MediaInfo
+	mediaInfo := extractMediaInfo(msg)
+	if mediaInfo == nil {
+		return nil, nil, naming.Fields{}, false
+	}
+
+	topic, err := b.messageTopic(group, thread)
+	if err != nil {
+		log.Printf("Error getting topic %d of group %d: %v", thread.ThreadID, group.GroupID, err)
+		return nil, nil, naming.Fields{}, false
+	}
+	if topic != nil && !topic.Enabled {
+		log.Printf("Skipping %s in group %d: topic %d is disabled", mediaInfo.Type, group.GroupID, topic.ThreadID)
+		return nil, nil, naming.Fields{}, false
+	}
 
-	log.Printf("msg.Photo", msg.Photo)
-	log.Printf("msg.Video", msg.Video)
-	log.Printf("msg.Document", msg.Document)
+	if topic != nil && !topic.AcceptsMediaType(group, mediaInfo.Type) {
+		log.Printf("Skipping %s in group %d: media types of topic %d are %s", mediaInfo.Type, group.GroupID, topic.ThreadID, topic.MediaType)
+		return nil, nil, naming.Fields{}, false
+	}
+	if topic == nil && !group.AcceptsMediaType(mediaInfo.Type) {
+		log.Printf("Skipping %s in group %d: media types are %s", mediaInfo.Type, group.GroupID, group.MediaType)
+		return nil, nil, naming.Fields{}, false
+	}
+	mediaInfo.CloudFolderPath = group.CloudFolderPath
 
+	fillMessageMetadata(mediaInfo, msg)
+	if b.isMemberOptedOut(group, mediaInfo.SenderID) {
+		log.Printf("Skipping %s in group %d: user %d opted out", mediaInfo.Type, group.GroupID, mediaInfo.SenderID)
+		return nil, nil, naming.Fields{}, false
+	}
+	if topic != nil {
+		mediaInfo.TopicFolder = topic.Folder()
+		// В теме каждое сообщение без ответа ссылается на сообщение о ее создании
+		if ctx := mediaInfo.Context; ctx != nil && ctx.Reply != nil && ctx.Reply.MessageID == topic.ThreadID {
+			ctx.Reply = nil
+			if len(ctx.CaptionEntities) == 0 && ctx.Forward == nil {
+				mediaInfo.Context = nil
+			}
+		}
+	}
+
+	if decision := group.Filters.Evaluate(filterInput(mediaInfo, msg)); !decision.Allowed {
+		log.Printf("Skipping %s %s in group %d by filter: %s", mediaInfo.Type, mediaInfo.FileUniqueID, group.GroupID, decision.Reason)
+		return nil, nil, naming.Fields{}, false
+	}
+
+	// Файл больше лимита текущего режима Bot API скачать не получится
+	if mediaInfo.FileSize > b.mediaProcessor.APIConfig().MaxFileSize() {
+		log.Printf("Skipping %s %s in group %d: size %d is over the Bot API limit",
+			mediaInfo.Type, mediaInfo.FileUniqueID, group.GroupID, mediaInfo.FileSize)
+		b.notifyFileTooBig(group, mediaInfo)
+		return nil, nil, naming.Fields{}, false
+	}
+
+	fields := namingFields(mediaInfo)
+	routeMedia(group, mediaInfo, mediaInfo.Caption, fields)
+
+	// Проверяем, не обрабатывали ли мы уже это медиа
+	processed, err := b.groupRepo.IsMediaProcessed(mediaInfo.FileUniqueID, group.GroupID)
+	if err != nil {
+		log.Printf("Error checking media processing: %v", err)
+		return nil, nil, naming.Fields{}, false
+	}
+	if processed {
+		log.Printf("Media already processed: %s", mediaInfo.FileUniqueID)
+		return nil, nil, naming.Fields{}, false
+	}
+
+	if group.PublicURL == "" {
+		log.Printf("Skipping media in group %d: no public link", group.GroupID)
+		return nil, nil, naming.Fields{}, false
+	}
+
+	return group, mediaInfo, fields, true
+}
+
+// extractMediaInfo определяет категорию медиа в сообщении и собирает данные файла.
+// Возвращает nil, если в сообщении нет поддерживаемого медиа.
+func extractMediaInfo(msg *tgbotapi.Message) *media.MediaInfo {
 	switch {
-	case msg.Photo != nil && len(msg.Photo) > 0 && (group.MediaType == "photos" || group.MediaType == "all"):
-		log.Println("Its photo")
+	case len(msg.Photo) > 0:
 		photo := msg.Photo[len(msg.Photo)-1] // Берем самое качественное фото
-		mediaInfo = &media.MediaInfo{
-			FileID:          photo.FileID,
-			Type:            "photo",
-			FileName:        fmt.Sprintf("photo_%d.jpg", time.Now().Unix()),
-			CloudFolderPath: group.CloudFolderPath,
+		return &media.MediaInfo{
+			FileID:       photo.FileID,
+			FileUniqueID: photo.FileUniqueID,
+			Type:         domain.MediaPhoto,
+			MimeType:     "image/jpeg",
+			FileSize:     int64(photo.FileSize),
+			Width:        photo.Width,
+			Height:       photo.Height,
+		}
+
+	case msg.Video != nil:
+		return &media.MediaInfo{
+			FileID:       msg.Video.FileID,
+			FileUniqueID: msg.Video.FileUniqueID,
+			Type:         domain.MediaVideo,
+			FileName:     msg.Video.FileName,
+			MimeType:     msg.Video.MimeType,
+			FileSize:     int64(msg.Video.FileSize),
+			Width:        msg.Video.Width,
+			Height:       msg.Video.Height,
+			Duration:     msg.Video.Duration,
 		}
 
-	case msg.Video != nil && (group.MediaType == "videos" || group.MediaType == "all"):
-		log.Println("Its video")
-		fileName := fmt.Sprintf("video_%d.mp4", time.Now().Unix())
-		if msg.Video.FileName != "" {
-			fileName = msg.Video.FileName
+	// GIF приходит и как Animation, и как Document, поэтому проверяется раньше документа
+	case msg.Animation != nil:
+		return &media.MediaInfo{
+			FileID:       msg.Animation.FileID,
+			FileUniqueID: msg.Animation.FileUniqueID,
+			Type:         domain.MediaAnimation,
+			FileName:     msg.Animation.FileName,
+			MimeType:     msg.Animation.MimeType,
+			FileSize:     int64(msg.Animation.FileSize),
+			Width:        msg.Animation.Width,
+			Height:       msg.Animation.Height,
+			Duration:     msg.Animation.Duration,
 		}
-		mediaInfo = &media.MediaInfo{
-			FileID:          msg.Video.FileID,
-			Type:            "video",
-			FileName:        fileName,
-			CloudFolderPath: group.CloudFolderPath,
+
+	case msg.VideoNote != nil:
+		return &media.MediaInfo{
+			FileID:       msg.VideoNote.FileID,
+			FileUniqueID: msg.VideoNote.FileUniqueID,
+			Type:         domain.MediaVideoNote,
+			MimeType:     "video/mp4",
+			FileSize:     int64(msg.VideoNote.FileSize),
+			Width:        msg.VideoNote.Length,
+			Height:       msg.VideoNote.Length,
+			Duration:     msg.VideoNote.Duration,
 		}
 
-	case msg.Document != nil && group.MediaType == "all":
-		log.Println("Its document")
-		mimeType := msg.Document.MimeType
-		var mediaType string
+	case msg.Voice != nil:
+		return &media.MediaInfo{
+			FileID:       msg.Voice.FileID,
+			FileUniqueID: msg.Voice.FileUniqueID,
+			Type:         domain.MediaVoice,
+			MimeType:     msg.Voice.MimeType,
+			FileSize:     int64(msg.Voice.FileSize),
+			Duration:     msg.Voice.Duration,
+		}
 
-		if strings.HasPrefix(mimeType, "image/") {
-			mediaType = "photo"
-		} else if strings.HasPrefix(mimeType, "video/") {
-			mediaType = "video"
-		} else {
-			return
+	case msg.Audio != nil:
+		fileName := msg.Audio.FileName
+		if fileName == "" && msg.Audio.Title != "" {
+			fileName = strings.TrimPrefix(msg.Audio.Performer+" - "+msg.Audio.Title, " - ")
+		}
+		return &media.MediaInfo{
+			FileID:       msg.Audio.FileID,
+			FileUniqueID: msg.Audio.FileUniqueID,
+			Type:         domain.MediaAudio,
+			FileName:     fileName,
+			MimeType:     msg.Audio.MimeType,
+			FileSize:     int64(msg.Audio.FileSize),
+			Duration:     msg.Audio.Duration,
 		}
 
-		mediaInfo = &media.MediaInfo{
-			FileID:          msg.Document.FileID,
-			Type:            mediaType,
-			FileName:        msg.Document.FileName,
-			CloudFolderPath: group.CloudFolderPath,
+	case msg.Document != nil:
+		// Фото и видео, отправленные файлом, относятся к фото и видео
+		mediaType := domain.MediaDocument
+		if strings.HasPrefix(msg.Document.MimeType, "image/") {
+			mediaType = domain.MediaPhoto
+		} else if strings.HasPrefix(msg.Document.MimeType, "video/") {
+			mediaType = domain.MediaVideo
 		}
 
-	default:
-		log.Println("Its nothing")
-		return
+		return &media.MediaInfo{
+			FileID:       msg.Document.FileID,
+			FileUniqueID: msg.Document.FileUniqueID,
+			Type:         mediaType,
+			FileName:     msg.Document.FileName,
+			MimeType:     msg.Document.MimeType,
+			FileSize:     int64(msg.Document.FileSize),
+		}
 	}
+	return nil
+}
 
-	// Проверяем, не обрабатывали ли мы уже это медиа
-	log.Println("Media info:", mediaInfo)
-	processed, err := b.groupRepo.IsMediaProcessed(mediaInfo.FileID, group.GroupID)
+// enqueueMedia подбирает свободное имя файла и ставит медиа в очередь загрузки.
+// Сама загрузка выполняется фоновыми воркерами. Возвращает true, если задание
+// поставлено в очередь.
+func (b *Bot) enqueueMedia(group *domain.GroupSession, mediaInfo *media.MediaInfo, fields naming.Fields) bool {
+	// Имя подбирается и резервируется заданием атомарно относительно других постановок
+	b.enqueueMu.Lock()
+	defer b.enqueueMu.Unlock()
+
+	// Имя новой версии отредактированного медиа выбрано по прошлой версии, но
+	// тоже может быть занято. Как есть используется только путь заменяемого файла.
+	name := mediaInfo.FileName
+	if mediaInfo.Version <= 1 {
+		name = naming.Render(group.NamingTemplate, fields)
+	}
+	if !mediaInfo.Overwrite {
+		if err := b.assignFileName(group, mediaInfo, name); err != nil {
+			log.Printf("Error assigning file name: %v", err)
+			return false
+		}
+	}
+
+	job := &queueDomain.UploadJob{
+		GroupID: group.GroupID,
+		Media:   mediaInfo,
+	}
+
+	queued, err := b.queueRepo.Enqueue(job)
 	if err != nil {
-		log.Printf("Error checking media processing: %v", err)
-		return
+		log.Printf("Error enqueueing upload job: %v", err)
+		return false
 	}
-	log.Println("Processed status: ", processed)
-	if processed {
-		log.Printf("Media already processed: %s", mediaInfo.FileID)
-		return
+	if !queued {
+		log.Printf("Media already waiting for upload: %s", mediaInfo.FileUniqueID)
+		return false
 	}
+	b.uploadPool.Notify()
+
+	log.Printf("Queued upload job %d for media: %s", job.ID, mediaInfo.FileName)
+	return true
+}
 
-	if group.PublicURL != "" {
-		err = b.mediaProcessor.ProcessSingleMedia(session.AccessToken, mediaInfo)
+// routeMedia выбирает папку медиа: подпапка темы форума, подпапка правила
+// маршрутизации по подписи, затем подпапка раскладки группы
+func routeMedia(group *domain.GroupSession, mediaInfo *media.MediaInfo, caption string, fields naming.Fields) {
+	route := group.Routes.Match(caption)
+	if route.Name != "" {
+		log.Printf("Media %s in group %d routed by %s to %q", mediaInfo.FileUniqueID, group.GroupID, route.Name, route.Folder)
+	}
+
+	mediaInfo.Route = route.Name
+	mediaInfo.CloudFolderPath = path.Join(group.CloudFolderPath, mediaInfo.TopicFolder, route.Folder, naming.Subfolder(group.FolderLayout, fields))
+}
+
+// namingFields собирает значения для шаблона имени и раскладки по подпапкам.
+// Исходное имя файла берется из mediaInfo.FileName.
+func namingFields(mediaInfo *media.MediaInfo) naming.Fields {
+	return naming.Fields{
+		Type:         mediaInfo.Type,
+		Date:         time.Unix(mediaInfo.MessageDate, 0),
+		Sender:       mediaInfo.SenderName,
+		MessageID:    mediaInfo.MessageID,
+		Album:        mediaInfo.AlbumID,
+		OriginalName: mediaInfo.FileName,
+		Ext:          naming.ExtFor(mediaInfo.FileName, mediaInfo.MimeType, mediaInfo.Type),
+	}
+}
+
+// assignFileName дает файлу имя name. Если такой путь уже занят загруженным
+// или ожидающим загрузки файлом, к имени добавляется суффикс _2, _3 и т.д.
+func (b *Bot) assignFileName(group *domain.GroupSession, mediaInfo *media.MediaInfo, name string) error {
+	for n := 1; n <= maxNameCollisions; n++ {
+		mediaInfo.FileName = name
+		if n > 1 {
+			mediaInfo.FileName = naming.WithSuffix(name, n)
+		}
+		// Имя манифеста папки занято под index.json
+		if mediaInfo.FileName == sidecar.IndexFileName {
+			continue
+		}
+
+		used, err := b.groupRepo.IsCloudPathUsed(mediaInfo.CloudFilePath(), group.GroupID)
 		if err != nil {
-			log.Printf("Error uploading media to cloud: %v", err)
-			return
+			return err
+		}
+		if !used {
+			used, err = b.queueRepo.IsCloudPathPending(mediaInfo.CloudFilePath(), group.GroupID)
+			if err != nil {
+				return err
+			}
+		}
+		if !used {
+			return nil
 		}
-	} else {
-		log.Println("No public url")
-		return
 	}
+	return fmt.Errorf("no free name for %s after %d attempts", name, maxNameCollisions)
+}
+
+// fillMessageMetadata переносит в mediaInfo данные сообщения: ID, дату, отправителя, подпись и альбом
+func fillMessageMetadata(mediaInfo *media.MediaInfo, msg *tgbotapi.Message) {
+	mediaInfo.MessageID = msg.MessageID
+	mediaInfo.MessageDate = int64(msg.Date)
+	mediaInfo.Caption = msg.Caption
+	mediaInfo.AlbumID = msg.MediaGroupID
+	mediaInfo.Context = messageContext(msg)
 
-	// Помечаем как обработанное
-	processedMedia := &domain.ProcessedMedia{
-		GroupID:      group.GroupID,
-		FileUniqueID: mediaInfo.FileID,
-		FileName:     mediaInfo.FileName,
-		MediaType:    mediaInfo.Type,
+	if msg.From != nil {
+		mediaInfo.SenderID = msg.From.ID
+		mediaInfo.SenderName = senderDisplayName(msg.From)
+	} else if msg.SenderChat != nil {
+		// Пост канала: автор - канал, подпись автора есть, если она включена
+		mediaInfo.SenderID = msg.SenderChat.ID
+		mediaInfo.SenderName = msg.SenderChat.Title
+		if msg.AuthorSignature != "" {
+			mediaInfo.SenderName = msg.AuthorSignature
+		}
 	}
+}
 
-	if err := b.groupRepo.SaveProcessedMedia(processedMedia); err != nil {
-		log.Printf("Error saving processed media: %v", err)
+// filterInput собирает данные медиа для правил фильтрации группы
+func filterInput(mediaInfo *media.MediaInfo, msg *tgbotapi.Message) filter.Input {
+	input := filter.Input{
+		MimeType: mediaInfo.MimeType,
+		Ext:      naming.ExtFor(mediaInfo.FileName, mediaInfo.MimeType, mediaInfo.Type),
+		Size:     mediaInfo.FileSize,
+		SenderID: mediaInfo.SenderID,
+		Caption:  mediaInfo.Caption,
+	}
+	if msg.From != nil {
+		input.SenderUsername = msg.From.UserName
+	} else if msg.SenderChat != nil {
+		input.SenderUsername = msg.SenderChat.UserName
 	}
+	return input
+}
 
-	log.Printf("Successfully uploaded media: %s to cloud folder: %s", mediaInfo.FileName, group.CloudFolderPath)
+// senderDisplayName возвращает имя пользователя для отображения
+func senderDisplayName(user *tgbotapi.User) string {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" && user.UserName != "" {
+		name = "@" + user.UserName
+	}
+	return name
 }
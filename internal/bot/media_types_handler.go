@@ -0,0 +1,229 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mail_helper_bot/internal/pkg/group/domain"
+)
+
+// mediaTypesKeyboard строит клавиатуру выбора категорий: каждая кнопка
+// включает или выключает категорию, отмеченные помечены галочкой
+func mediaTypesKeyboard(group *domain.GroupSession) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+
+	for _, c := range domain.MediaCategories {
+		mark := "▫️"
+		if group.AcceptsMediaType(c.Type) {
+			mark = "✅"
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("%s %s %s", mark, c.Icon, c.Title),
+			fmt.Sprintf("media_toggle:%d:%s", group.GroupID, c.Type)))
+
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📦 Выбрать все", fmt.Sprintf("media_toggle:%d:all", group.GroupID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("💾 Готово", fmt.Sprintf("media_done:%d", group.GroupID)),
+		),
+	)
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// sendMediaTypeSelection отправляет в чат выбор категорий медиа для загрузки:
+// в саму группу или, для канала, в личный чат с администратором
+func (b *Bot) sendMediaTypeSelection(chatID int64, group *domain.GroupSession) {
+	text := fmt.Sprintf(`📁 Настройка бота для группы "%s"
+
+Отметьте категории медиа для автоматической выгрузки в облако и нажмите «Готово»:`, group.GroupTitle)
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = mediaTypesKeyboard(group)
+	b.Api.Send(msg)
+}
+
+// handleMediaTypesOpen показывает выбор категорий. Формат: media_types:{groupID}
+func (b *Bot) handleMediaTypesOpen(query *tgbotapi.CallbackQuery, data string) {
+	group := b.groupFromCallback(query, data, 2)
+	if group == nil {
+		return
+	}
+	b.sendMediaTypeSelection(query.Message.Chat.ID, group)
+}
+
+// handleMediaTypeToggle включает или выключает категорию. Формат: media_toggle:{groupID}:{type|all}
+func (b *Bot) handleMediaTypeToggle(query *tgbotapi.CallbackQuery, data string) {
+	parts := strings.Split(data, ":")
+	if len(parts) != 3 {
+		return
+	}
+	mediaType := parts[2]
+	if mediaType != "all" && !domain.IsMediaType(mediaType) {
+		return
+	}
+
+	group := b.groupFromCallback(query, data, 3)
+	if group == nil {
+		return
+	}
+
+	types := domain.ParseMediaTypes(group.MediaType)
+	switch {
+	case mediaType == "all":
+		types = nil
+		for _, c := range domain.MediaCategories {
+			types = append(types, c.Type)
+		}
+	case group.AcceptsMediaType(mediaType):
+		// Последнюю категорию не выключаем: группа без категорий ничего не загружает
+		if len(types) == 1 {
+			return
+		}
+		for i, t := range types {
+			if t == mediaType {
+				types = append(types[:i], types[i+1:]...)
+				break
+			}
+		}
+	default:
+		types = append(types, mediaType)
+	}
+
+	group.MediaType = domain.FormatMediaTypes(types)
+	if err := b.groupRepo.SaveGroupSession(group); err != nil {
+		log.Printf("Error updating group media type: %v", err)
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageReplyMarkup(query.Message.Chat.ID, query.Message.MessageID, mediaTypesKeyboard(group))
+	b.Api.Send(edit)
+}
+
+// handleMediaTypesDone завершает выбор категорий. Если у группы еще нет
+// публичной ссылки, создает папку в облаке и ссылку. Формат: media_done:{groupID}
+func (b *Bot) handleMediaTypesDone(query *tgbotapi.CallbackQuery, data string) {
+	group := b.groupFromCallback(query, data, 2)
+	if group == nil {
+		return
+	}
+	chatID := query.Message.Chat.ID
+
+	if group.PublicURL == "" {
+		// Проверяем авторизацию владельца и создаем папку с публичной ссылкой
+		err := b.tokens.Do(group.OwnerChatID, func(accessToken string) error {
+			// Создаем папку в облаке
+			if err := b.mediaProcessor.CreateCloudFolder(accessToken, group.CloudFolderPath); err != nil {
+				return fmt.Errorf("error creating cloud folder: %w", err)
+			}
+
+			// Создаем публичную ссылку
+			publicURL, err := b.mediaProcessor.CreatePublicLink(accessToken, group.CloudFolderPath)
+			if err != nil {
+				return fmt.Errorf("error creating public link: %w", err)
+			}
+
+			group.PublicURL = publicURL
+			return nil
+		})
+		if err != nil {
+			log.Printf("Error preparing cloud folder: %v", err)
+		} else {
+			b.groupRepo.SaveGroupSession(group)
+		}
+	}
+
+	// Обновляем сообщение
+	text := fmt.Sprintf("✅ Настройки сохранены!\n\nГруппа: %s\nТипы медиа: %s\n\n☁️ Облачная папка: %s",
+		group.GroupTitle, domain.MediaTypesTitle(group.MediaType), group.CloudFolderPath)
+
+	if group.PublicURL != "" {
+		text += fmt.Sprintf("\n\n🔗 Публичная ссылка:\n%s", group.PublicURL)
+	}
+
+	editMsg := tgbotapi.NewEditMessageText(chatID, query.Message.MessageID, text)
+	b.Api.Send(editMsg)
+
+	// Отправляем инструкцию
+	instruction := `📖 Инструкция:
+
+Теперь бот будет автоматически загружать все новые медиафайлы выбранных типов из этой группы прямо в ваше облако Mail.ru.
+
+Для просмотра статуса и ссылки используйте команду /group_status`
+	if group.IsChannel {
+		// Команды групп в канале не работают: у постов нет автора, а ответы бота видны подписчикам
+		instruction = fmt.Sprintf(`📖 Инструкция:
+
+Теперь бот будет автоматически загружать все новые медиафайлы выбранных типов из постов канала прямо в ваше облако Mail.ru.
+
+Статус и ссылка - /my_groups здесь, в личном чате. Изменить категории: /add_channel %d
+
+Остальные настройки групп (фильтры, имена файлов, маршруты, пауза и другие) для каналов недоступны: канал работает с настройками по умолчанию.`,
+			group.GroupID)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, instruction)
+	b.Api.Send(msg)
+}
+
+// groupFromCallback достает группу из callback-данных вида prefix:{groupID}[:...]
+// и проверяет, что кнопку нажал администратор группы
+func (b *Bot) groupFromCallback(query *tgbotapi.CallbackQuery, data string, partsCount int) *domain.GroupSession {
+	parts := strings.Split(data, ":")
+	if len(parts) != partsCount {
+		return nil
+	}
+
+	groupID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	isAdmin, err := b.isChatAdmin(groupID, query.From.ID)
+	if err != nil || !isAdmin {
+		return nil
+	}
+
+	group, err := b.groupRepo.GetGroupSession(groupID)
+	if err != nil || group == nil {
+		return nil
+	}
+	return group
+}
+
+// formatMediaCounts показывает число загруженных файлов по категориям: "📷12 🎥3"
+func formatMediaCounts(stats *domain.GroupStats) string {
+	var parts []string
+	for _, c := range domain.MediaCategories {
+		if count := stats.Counts[c.Type]; count > 0 {
+			parts = append(parts, fmt.Sprintf("%s%d", c.Icon, count))
+		}
+	}
+	if len(parts) == 0 {
+		return "0"
+	}
+	return strings.Join(parts, " ")
+}
+
+// mediaTypesIcons возвращает значки категорий группы: "📷🎥"
+func mediaTypesIcons(value string) string {
+	var icons strings.Builder
+	for _, t := range domain.ParseMediaTypes(value) {
+		icons.WriteString(domain.MediaTypeIcon(t))
+	}
+	return icons.String()
+}
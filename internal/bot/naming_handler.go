@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mail_helper_bot/internal/pkg/naming"
+)
+
+// handleNamingCommand показывает или меняет шаблон имен файлов группы.
+// /naming - текущий шаблон, /naming reset - шаблон по умолчанию,
+// /naming <шаблон> - новый шаблон
+func (b *Bot) handleNamingCommand(msg *tgbotapi.Message) {
+	group, err := b.groupRepo.GetGroupSession(msg.Chat.ID)
+	if err != nil || group == nil {
+		reply := tgbotapi.NewMessage(msg.Chat.ID,
+			"❌ Группа не настроена. Используйте /setup_group для настройки.")
+		b.Api.Send(reply)
+		return
+	}
+
+	args := strings.TrimSpace(msg.CommandArguments())
+	if args == "" {
+		b.sendNamingInfo(msg.Chat.ID, group.NamingTemplate)
+		return
+	}
+
+	if !b.checkGroupAdmin(msg) {
+		return
+	}
+
+	template := args
+	if args == "reset" {
+		template = ""
+	} else if err := naming.Validate(template); err != nil {
+		reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("❌ Некорректный шаблон: %v", err))
+		b.Api.Send(reply)
+		return
+	}
+
+	group.NamingTemplate = template
+	if err := b.groupRepo.SaveGroupSession(group); err != nil {
+		log.Printf("Error saving naming template: %v", err)
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Ошибка при сохранении шаблона.")
+		b.Api.Send(reply)
+		return
+	}
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf(
+		"✅ Шаблон имен сохранен: %s\n\nПример: %s",
+		displayTemplate(template), namingExample(template)))
+	b.Api.Send(reply)
+}
+
+func (b *Bot) sendNamingInfo(chatID int64, template string) {
+	var placeholders strings.Builder
+	for _, p := range naming.Placeholders {
+		placeholders.WriteString(fmt.Sprintf("• %s - %s\n", p.Name, p.Description))
+	}
+
+	text := fmt.Sprintf(`📝 Шаблон имен файлов: %s
+Пример: %s
+
+Подстановки:
+%s
+Изменить: /naming {date}_{sender}_{original_name}
+Сбросить: /naming reset
+
+Если {ext} не указан, расширение добавляется в конец. При совпадении имен добавляется суффикс _2, _3 и т.д.`,
+		displayTemplate(template), namingExample(template), placeholders.String())
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	b.Api.Send(msg)
+}
+
+func displayTemplate(template string) string {
+	if template == "" {
+		return naming.DefaultTemplate + " (по умолчанию)"
+	}
+	return template
+}
+
+// namingExample показывает, как шаблон назовет фото, отправленное сейчас
+func namingExample(template string) string {
+	return naming.Render(template, naming.Fields{
+		Type:         "photo",
+		Date:         time.Now(),
+		Sender:       "Иван Петров",
+		MessageID:    1234,
+		Album:        "13579",
+		OriginalName: "IMG_0001.jpg",
+		Ext:          "jpg",
+	})
+}
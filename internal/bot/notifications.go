@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"mail_helper_bot/internal/pkg/cloud/cloud_service"
+	"mail_helper_bot/internal/pkg/group/domain"
+	"mail_helper_bot/internal/pkg/media"
+	notifyDomain "mail_helper_bot/internal/pkg/notify/domain"
+	"mail_helper_bot/internal/pkg/notify/notify_service"
+	"mail_helper_bot/internal/pkg/oauth/oauth_service"
+	queueDomain "mail_helper_bot/internal/pkg/upload_queue/domain"
+)
+
+// SetNotifier задает сервис уведомлений владельцам
+func (b *Bot) SetNotifier(notifier *notify_service.Notifier) {
+	b.notifier = notifier
+}
+
+// HandleOwnerLogin вызывается после успешной авторизации: если сессия снова
+// истечет, владелец узнает об этом сразу, без ожидания интервала повтора
+func (b *Bot) HandleOwnerLogin(chatID int64) {
+	b.notifier.Resolve(chatID, notifyDomain.KindAuthExpired)
+}
+
+// notifyOwnerAuth сообщает владельцу, что медиа группы не загружаются из-за
+// недействительной сессии, и предлагает авторизоваться снова
+func (b *Bot) notifyOwnerAuth(group *domain.GroupSession) {
+	b.notifier.Notify(notifyDomain.Notification{
+		OwnerChatID: group.OwnerChatID,
+		Kind:        notifyDomain.KindAuthExpired,
+		Text: fmt.Sprintf("🔐 Сессия Облака Mail.ru истекла или доступ отозван.\n\n"+
+			"Медиа из \"%s\" и других ваших групп не загружаются, пока вы не авторизуетесь снова.", group.GroupTitle),
+		ReloginButton: true,
+	})
+}
+
+// notifyUploadError сообщает владельцу о сбоях загрузки, которые может
+// исправить только он: закончившееся место и удаленная папка группы
+func (b *Bot) notifyUploadError(group *domain.GroupSession, err error) {
+	var apiErr *cloud_service.APIError
+	if !errors.As(err, &apiErr) {
+		return
+	}
+
+	switch {
+	case apiErr.QuotaExceeded():
+		b.notifier.Notify(notifyDomain.Notification{
+			OwnerChatID: group.OwnerChatID,
+			Kind:        notifyDomain.KindQuotaExceeded,
+			Text: fmt.Sprintf("💾 В Облаке Mail.ru закончилось место.\n\n"+
+				"Медиа из \"%s\" не загружаются. Освободите место или расширьте тариф, "+
+				"затем повторите неудачные загрузки: /failed", group.GroupTitle),
+		})
+	case apiErr.NotFound():
+		b.notifier.Notify(notifyDomain.Notification{
+			OwnerChatID: group.OwnerChatID,
+			Kind:        notifyDomain.KindFolderMissing,
+			Key:         strconv.FormatInt(group.GroupID, 10),
+			Text: fmt.Sprintf("📁 Папка группы \"%s\" не найдена в облаке:\n%s\n\n"+
+				"Бот создаст ее заново при следующей загрузке. Прежняя публичная ссылка "+
+				"больше не работает, новую можно получить командой /share в группе.",
+				group.GroupTitle, group.CloudFolderPath),
+		})
+	}
+}
+
+// notifyUploadFailures сообщает владельцу, что файлы группы не загрузились
+// после всех повторов. Сбои, о которых владелец уже узнал отдельно, пропускаются.
+func (b *Bot) notifyUploadFailures(job *queueDomain.UploadJob, err error) {
+	if errors.Is(err, oauth_service.ErrNotAuthorized) || errors.Is(err, oauth_service.ErrSessionExpired) ||
+		errors.Is(err, media.ErrFileTooBig) {
+		return
+	}
+
+	group, groupErr := b.groupRepo.GetGroupSession(job.GroupID)
+	if groupErr != nil || group == nil {
+		return
+	}
+
+	fileName := ""
+	if job.Media != nil {
+		fileName = job.Media.FileName
+	}
+
+	b.notifier.Notify(notifyDomain.Notification{
+		OwnerChatID: group.OwnerChatID,
+		Kind:        notifyDomain.KindUploadFailures,
+		Key:         strconv.FormatInt(group.GroupID, 10),
+		Text: fmt.Sprintf("⚠️ Файлы не загружаются в облако группы \"%s\"\n\n"+
+			"• Файл: %s\n"+
+			"• Попыток: %d\n"+
+			"• Ошибка: %s\n\n"+
+			"Посмотреть и повторить неудачные загрузки: /failed",
+			group.GroupTitle, fileName, job.Attempts, truncateText(err.Error(), 200)),
+	})
+}
@@ -0,0 +1,223 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mail_helper_bot/internal/pkg/group/domain"
+)
+
+// handleOptOutCommand отключает загрузку медиа участника, отправившего
+// команду. Права администратора не нужны: каждый решает за себя. Если
+// участник уже что-то отправлял, владельцу облака предлагается удалить эти файлы.
+func (b *Bot) handleOptOutCommand(msg *tgbotapi.Message) {
+	group, err := b.groupRepo.GetGroupSession(msg.Chat.ID)
+	if err != nil || group == nil {
+		reply := tgbotapi.NewMessage(msg.Chat.ID,
+			"❌ Группа не настроена. Используйте /setup_group для настройки.")
+		b.Api.Send(reply)
+		return
+	}
+
+	optedOut, err := b.groupRepo.IsMemberOptedOut(group.GroupID, msg.From.ID)
+	if err != nil {
+		log.Printf("Error checking opt-out of user %d in group %d: %v", msg.From.ID, group.GroupID, err)
+		b.sendErrorMessage(msg.Chat.ID, "❌ Ошибка при загрузке настроек.")
+		return
+	}
+	if optedOut {
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "ℹ️ Ваши медиа уже не загружаются в облако. Снова разрешить: /optin")
+		reply.ReplyToMessageID = msg.MessageID
+		b.Api.Send(reply)
+		return
+	}
+
+	if err := b.groupRepo.AddMemberOptOut(group.GroupID, msg.From.ID); err != nil {
+		log.Printf("Error saving opt-out of user %d in group %d: %v", msg.From.ID, group.GroupID, err)
+		b.sendErrorMessage(msg.Chat.ID, "❌ Ошибка при сохранении настроек.")
+		return
+	}
+	log.Printf("User %d opted out of archiving in group %d", msg.From.ID, group.GroupID)
+
+	text := "🙈 Ваши новые медиа больше не будут загружаться в облако группы. Снова разрешить: /optin"
+
+	records, err := b.groupRepo.GetSenderMedia(group.GroupID, msg.From.ID)
+	if err != nil {
+		log.Printf("Error getting media of user %d in group %d: %v", msg.From.ID, group.GroupID, err)
+	}
+	if len(records) > 0 {
+		if b.askOwnerAboutPastMedia(group, msg.From, len(records)) {
+			text += fmt.Sprintf("\n\nУже загруженных файлов: %d. Владелец облака решит, удалить ли их.", len(records))
+		} else {
+			text += fmt.Sprintf("\n\nУже загруженных файлов: %d. Они останутся в облаке, пока их не удалит владелец.", len(records))
+		}
+	}
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	reply.ReplyToMessageID = msg.MessageID
+	b.Api.Send(reply)
+}
+
+// handleOptInCommand снова разрешает загрузку медиа участника. Медиа,
+// отправленные во время отказа, не догружаются.
+func (b *Bot) handleOptInCommand(msg *tgbotapi.Message) {
+	group, err := b.groupRepo.GetGroupSession(msg.Chat.ID)
+	if err != nil || group == nil {
+		reply := tgbotapi.NewMessage(msg.Chat.ID,
+			"❌ Группа не настроена. Используйте /setup_group для настройки.")
+		b.Api.Send(reply)
+		return
+	}
+
+	if err := b.groupRepo.RemoveMemberOptOut(group.GroupID, msg.From.ID); err != nil {
+		log.Printf("Error removing opt-out of user %d in group %d: %v", msg.From.ID, group.GroupID, err)
+		b.sendErrorMessage(msg.Chat.ID, "❌ Ошибка при сохранении настроек.")
+		return
+	}
+	log.Printf("User %d opted in to archiving in group %d", msg.From.ID, group.GroupID)
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID,
+		"✅ Ваши новые медиа снова загружаются в облако группы. Отправленные раньше не догружаются, их можно сохранить через /save.")
+	reply.ReplyToMessageID = msg.MessageID
+	b.Api.Send(reply)
+}
+
+// askOwnerAboutPastMedia спрашивает владельца облака в личном чате, удалить
+// ли уже загруженные медиа отказавшегося участника. Возвращает false, если
+// сообщение не дошло.
+func (b *Bot) askOwnerAboutPastMedia(group *domain.GroupSession, member *tgbotapi.User, count int) bool {
+	text := fmt.Sprintf("🙈 Участник %s отказывается от загрузки своих медиа в группе \"%s\".\n\n"+
+		"Файлов участника в облаке: %d. Удалить их вместе с подписями?",
+		member.FirstName, group.GroupTitle, count)
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🗑 Удалить",
+				fmt.Sprintf("optout_purge:%d:%d", group.GroupID, member.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("📁 Оставить",
+				fmt.Sprintf("optout_keep:%d:%d", group.GroupID, member.ID)),
+		),
+	)
+
+	reply := tgbotapi.NewMessage(group.OwnerChatID, text)
+	reply.ReplyMarkup = keyboard
+	if _, err := b.Api.Send(reply); err != nil {
+		log.Printf("Error asking owner %d about media of user %d: %v", group.OwnerChatID, member.ID, err)
+		return false
+	}
+	return true
+}
+
+// handleOptOutPurge удаляет в фоне из облака уже загруженные медиа участника по
+// решению владельца. Формат: optout_purge:{groupID}:{userID}
+func (b *Bot) handleOptOutPurge(query *tgbotapi.CallbackQuery, data string) {
+	chatID := query.Message.Chat.ID
+	messageID := query.Message.MessageID
+
+	group, userID, ok := b.optOutCallbackTarget(query, strings.TrimPrefix(data, "optout_purge:"))
+	if !ok {
+		return
+	}
+
+	// Кнопка не устаревает: участник мог уже вернуться через /optin.
+	// При ошибке базы ничего не удаляется.
+	optedOut, err := b.groupRepo.IsMemberOptedOut(group.GroupID, userID)
+	if err != nil {
+		log.Printf("Error checking opt-out of user %d in group %d: %v", userID, group.GroupID, err)
+		b.Api.Send(tgbotapi.NewEditMessageText(chatID, messageID, "❌ Ошибка при проверке участника."))
+		return
+	}
+	if !optedOut {
+		b.Api.Send(tgbotapi.NewEditMessageText(chatID, messageID,
+			"ℹ️ Участник снова разрешил загрузку своих медиа, файлы не удалены."))
+		return
+	}
+
+	records, err := b.groupRepo.GetSenderMedia(group.GroupID, userID)
+	if err != nil {
+		log.Printf("Error getting media of user %d in group %d: %v", userID, group.GroupID, err)
+		b.Api.Send(tgbotapi.NewEditMessageText(chatID, messageID, "❌ Ошибка при поиске файлов."))
+		return
+	}
+	if len(records) == 0 {
+		b.Api.Send(tgbotapi.NewEditMessageText(chatID, messageID, "ℹ️ Файлов участника в облаке уже нет."))
+		return
+	}
+
+	// Удаление может занять долго, поэтому идет в фоне. Кнопки убираются,
+	// чтобы повторное нажатие не запустило второе удаление.
+	b.Api.Send(tgbotapi.NewEditMessageText(chatID, messageID, fmt.Sprintf(
+		"⏳ Удаляю файлы участника из облака группы \"%s\": %d...", group.GroupTitle, len(records))))
+
+	go func() {
+		if err := b.removeUploadedMedia(group, records); err != nil {
+			log.Printf("Error purging media of user %d in group %d: %v", userID, group.GroupID, err)
+			// Кнопки возвращаются, чтобы повторить
+			edit := tgbotapi.NewEditMessageText(chatID, messageID,
+				query.Message.Text+"\n\n❌ Не удалось удалить файлы из облака. Попробуйте позже.")
+			edit.ReplyMarkup = query.Message.ReplyMarkup
+			b.Api.Send(edit)
+			return
+		}
+
+		log.Printf("Media of opted out user %d in group %d purged by owner", userID, group.GroupID)
+		b.Api.Send(tgbotapi.NewEditMessageText(chatID, messageID, fmt.Sprintf(
+			"🗑 Файлы участника удалены из облака группы \"%s\": %d.", group.GroupTitle, len(records))))
+	}()
+}
+
+// handleOptOutKeep оставляет уже загруженные медиа участника в облаке.
+// Формат: optout_keep:{groupID}:{userID}
+func (b *Bot) handleOptOutKeep(query *tgbotapi.CallbackQuery, data string) {
+	group, _, ok := b.optOutCallbackTarget(query, strings.TrimPrefix(data, "optout_keep:"))
+	if !ok {
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageText(query.Message.Chat.ID, query.Message.MessageID, fmt.Sprintf(
+		"📁 Уже загруженные файлы участника остаются в облаке группы \"%s\". Новые загружаться не будут.", group.GroupTitle))
+	b.Api.Send(edit)
+}
+
+// optOutCallbackTarget разбирает {groupID}:{userID} и проверяет, что кнопку
+// нажал владелец облака группы
+func (b *Bot) optOutCallbackTarget(query *tgbotapi.CallbackQuery, ids string) (*domain.GroupSession, int64, bool) {
+	parts := strings.Split(ids, ":")
+	if len(parts) != 2 {
+		return nil, 0, false
+	}
+	groupID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, 0, false
+	}
+	userID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, 0, false
+	}
+
+	group, err := b.groupRepo.GetGroupSession(groupID)
+	if err != nil || group == nil || group.OwnerChatID != query.From.ID {
+		edit := tgbotapi.NewEditMessageText(query.Message.Chat.ID, query.Message.MessageID,
+			"❌ Группа не найдена или вы не ее владелец.")
+		b.Api.Send(edit)
+		return nil, 0, false
+	}
+	return group, userID, true
+}
+
+// isMemberOptedOut проверяет отказ отправителя от загрузки. При ошибке базы
+// медиа не загружается: лучше пропустить файл, чем выгрузить его против воли.
+func (b *Bot) isMemberOptedOut(group *domain.GroupSession, senderID int64) bool {
+	if senderID == 0 {
+		return false
+	}
+	optedOut, err := b.groupRepo.IsMemberOptedOut(group.GroupID, senderID)
+	if err != nil {
+		log.Printf("Error checking opt-out of user %d in group %d: %v", senderID, group.GroupID, err)
+		return true
+	}
+	return optedOut
+}
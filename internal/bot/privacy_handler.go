@@ -0,0 +1,105 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mail_helper_bot/internal/pkg/group/domain"
+	"mail_helper_bot/internal/pkg/media"
+)
+
+// originalsSuffix - суффикс папки с оригиналами рядом с папкой группы
+const originalsSuffix = "_originals"
+
+// handlePrivacyCommand настраивает удаление метаданных из изображений.
+// /privacy - текущие настройки, /privacy on|off - убирать метаданные,
+// /privacy originals on|off - хранить оригиналы отдельно
+func (b *Bot) handlePrivacyCommand(msg *tgbotapi.Message) {
+	group, err := b.groupRepo.GetGroupSession(msg.Chat.ID)
+	if err != nil || group == nil {
+		reply := tgbotapi.NewMessage(msg.Chat.ID,
+			"❌ Группа не настроена. Используйте /setup_group для настройки.")
+		b.Api.Send(reply)
+		return
+	}
+
+	keptOriginals := group.KeepOriginals
+	args := strings.Fields(strings.ToLower(msg.CommandArguments()))
+	switch {
+	case len(args) == 0:
+		b.sendPrivacyInfo(msg.Chat.ID, group)
+		return
+	case len(args) == 1 && args[0] == "on":
+		group.StripMetadata = true
+	case len(args) == 1 && args[0] == "off":
+		group.StripMetadata = false
+	case len(args) == 2 && args[0] == "originals" && args[1] == "on":
+		group.KeepOriginals = true
+	case len(args) == 2 && args[0] == "originals" && args[1] == "off":
+		group.KeepOriginals = false
+	default:
+		reply := tgbotapi.NewMessage(msg.Chat.ID,
+			"❌ Используйте /privacy on, /privacy off или /privacy originals on|off")
+		b.Api.Send(reply)
+		return
+	}
+
+	if !b.checkGroupAdmin(msg) {
+		return
+	}
+
+	// Оригиналы с геолокацией хранятся только по явному решению владельца
+	if group.KeepOriginals && !keptOriginals && msg.From.ID != group.OwnerChatID {
+		reply := tgbotapi.NewMessage(msg.Chat.ID,
+			"❌ Хранить оригиналы с метаданными может включить только владелец облака группы.")
+		b.Api.Send(reply)
+		return
+	}
+
+	if err := b.groupRepo.SaveGroupSession(group); err != nil {
+		log.Printf("Error saving privacy settings: %v", err)
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Ошибка при сохранении настроек.")
+		b.Api.Send(reply)
+		return
+	}
+
+	b.sendPrivacyInfo(msg.Chat.ID, group)
+}
+
+func (b *Bot) sendPrivacyInfo(chatID int64, group *domain.GroupSession) {
+	strip := "выключено"
+	if group.StripMetadata {
+		strip = "включено"
+	}
+	originals := "не хранятся"
+	if group.KeepOriginals {
+		originals = fmt.Sprintf("хранятся в папке %s (без публичной ссылки)", group.CloudFolderPath+originalsSuffix)
+	}
+
+	text := fmt.Sprintf(`🔒 Приватность изображений
+
+• Удаление метаданных: %s
+• Оригиналы: %s
+
+Когда удаление включено, из JPEG и PNG перед загрузкой вырезаются геолокация, модель камеры, автор, время съемки и другие метаданные. Само изображение не пережимается.
+
+Включить: /privacy on
+Выключить: /privacy off
+Хранить оригиналы (только владелец): /privacy originals on|off`, strip, originals)
+
+	if group.KeepOriginals && !group.StripMetadata {
+		text += "\n\nОригиналы сохраняются, только когда удаление метаданных включено."
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	b.Api.Send(msg)
+}
+
+// originalsFolder возвращает папку для оригинала: рядом с папкой группы, а не
+// внутри нее, чтобы оригиналы не попали под публичную ссылку
+func originalsFolder(group *domain.GroupSession, mediaInfo *media.MediaInfo) string {
+	relative := strings.TrimPrefix(mediaInfo.CloudFolderPath, group.CloudFolderPath)
+	return group.CloudFolderPath + originalsSuffix + relative
+}
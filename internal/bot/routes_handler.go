@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mail_helper_bot/internal/pkg/routing"
+)
+
+// routesLogLimit - сколько последних загрузок показывает /routes log
+const routesLogLimit = 10
+
+// handleRoutesCommand показывает и меняет правила маршрутизации по подписям.
+// /routes - текущие правила, /routes #тег <папка>, /routes prefix <префикс> <папка>,
+// /routes default [папка], /routes del <номер>, /routes clear, /routes log
+func (b *Bot) handleRoutesCommand(msg *tgbotapi.Message) {
+	group, err := b.groupRepo.GetGroupSession(msg.Chat.ID)
+	if err != nil || group == nil {
+		reply := tgbotapi.NewMessage(msg.Chat.ID,
+			"❌ Группа не настроена. Используйте /setup_group для настройки.")
+		b.Api.Send(reply)
+		return
+	}
+
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) == 0 {
+		b.sendRoutesInfo(msg.Chat.ID, &group.Routes)
+		return
+	}
+
+	action := strings.ToLower(args[0])
+	if action == "log" {
+		b.sendRoutesLog(msg.Chat.ID, group.GroupID)
+		return
+	}
+
+	if !b.checkGroupAdmin(msg) {
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(action, "#"):
+		err = group.Routes.Add(routing.KindHashtag, action, strings.Join(args[1:], " "))
+	case action == routing.KindPrefix:
+		if len(args) < 3 {
+			err = fmt.Errorf("использование: /routes prefix <префикс> <папка>")
+			break
+		}
+		err = group.Routes.Add(routing.KindPrefix, args[1], strings.Join(args[2:], " "))
+	case action == routing.DefaultRouteName:
+		err = group.Routes.SetDefault(strings.Join(args[1:], " "))
+	case action == "del":
+		n := 0
+		if len(args) > 1 {
+			n, _ = strconv.Atoi(args[1])
+		}
+		err = group.Routes.Remove(n)
+	case action == "clear":
+		group.Routes = routing.Rules{}
+	default:
+		err = fmt.Errorf("неизвестное действие %q", args[0])
+	}
+	if err != nil {
+		reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("❌ %v\n\nСправка: /routes", err))
+		b.Api.Send(reply)
+		return
+	}
+
+	if err := b.groupRepo.SaveGroupSession(group); err != nil {
+		log.Printf("Error saving routing rules: %v", err)
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Ошибка при сохранении правил.")
+		b.Api.Send(reply)
+		return
+	}
+
+	b.sendRoutesInfo(msg.Chat.ID, &group.Routes)
+}
+
+func (b *Bot) sendRoutesInfo(chatID int64, rules *routing.Rules) {
+	text := "🧭 Маршрутизация по подписям\n\n"
+	if rules.IsEmpty() {
+		text += "Правил нет - медиа сохраняются в папку группы.\n"
+	} else {
+		text += strings.Join(rules.Describe(), "\n") + "\n"
+	}
+
+	text += "\nДобавить по хештегу: /routes #тег <папка>\n" +
+		"Добавить по началу подписи: /routes prefix <префикс> <папка>\n" +
+		"Папка для медиа без тега: /routes default <папка>\n" +
+		"Сбросить папку без тега: /routes default\n" +
+		"Удалить правило: /routes del <номер>\n" +
+		"Удалить все: /routes clear\n" +
+		"Какие правила сработали: /routes log\n" +
+		"\nПравила проверяются по порядку, папки создаются внутри папки группы. " +
+		"Альбом целиком попадает в папку по своей подписи."
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	b.Api.Send(msg)
+}
+
+// sendRoutesLog показывает, по какому правилу сохранены последние загрузки
+func (b *Bot) sendRoutesLog(chatID, groupID int64) {
+	recent, err := b.groupRepo.GetRecentProcessedMedia(groupID, routesLogLimit)
+	if err != nil {
+		log.Printf("Error getting recent media of group %d: %v", groupID, err)
+		reply := tgbotapi.NewMessage(chatID, "❌ Ошибка при получении загрузок.")
+		b.Api.Send(reply)
+		return
+	}
+	if len(recent) == 0 {
+		reply := tgbotapi.NewMessage(chatID, "📭 В группе еще нет загрузок.")
+		b.Api.Send(reply)
+		return
+	}
+
+	text := fmt.Sprintf("🧭 Последние %d загрузок:\n", len(recent))
+	for _, m := range recent {
+		route := m.MatchedRoute
+		if route == "" {
+			route = "без правил"
+		}
+		text += fmt.Sprintf("\n• %s\n  %s → %s", m.FileName, route, m.CloudPath)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	b.Api.Send(msg)
+}
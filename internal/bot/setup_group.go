@@ -6,7 +6,6 @@ import (
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"mail_helper_bot/internal/pkg/group/domain"
-	user_session_domain "mail_helper_bot/internal/pkg/session/domain"
 )
 
 // handleSetupGroup обрабатывает команду принудительной настройки группы
@@ -53,11 +52,11 @@ func (b *Bot) handleSetupGroup(msg *tgbotapi.Message) {
 	}
 
 	// Создаем новую запись
-	b.createGroupSession(msg.Chat, msg.From.ID, session)
+	b.createGroupSession(msg.Chat, msg.From.ID)
 }
 
 // createGroupSession создает запись о группе
-func (b *Bot) createGroupSession(chat *tgbotapi.Chat, userID int64, session *user_session_domain.UserSession) {
+func (b *Bot) createGroupSession(chat *tgbotapi.Chat, userID int64) {
 	// Генерируем путь к папке в облаке
 	cloudFolderPath := b.mediaProcessor.GenerateCloudFolderPath(chat.ID, chat.Title)
 
@@ -66,7 +65,7 @@ func (b *Bot) createGroupSession(chat *tgbotapi.Chat, userID int64, session *use
 		GroupID:         chat.ID,
 		GroupTitle:      chat.Title,
 		OwnerChatID:     userID,
-		MediaType:       "photos", // по умолчанию
+		MediaType:       domain.DefaultMediaTypes,
 		CloudFolderPath: cloudFolderPath,
 	}
 
@@ -78,7 +77,9 @@ func (b *Bot) createGroupSession(chat *tgbotapi.Chat, userID int64, session *use
 	}
 
 	// Пытаемся создать папку в облаке
-	err := b.mediaProcessor.CreateCloudFolder(session.AccessToken, cloudFolderPath)
+	err := b.tokens.Do(userID, func(accessToken string) error {
+		return b.mediaProcessor.CreateCloudFolder(accessToken, cloudFolderPath)
+	})
 	if err != nil {
 		log.Printf("Error creating cloud folder: %v", err)
 		// Не прерываем выполнение, т.к. папка может быть создана позже
@@ -94,26 +95,23 @@ func (b *Bot) sendSetupSuccessMessage(chatID int64, group *domain.GroupSession)
 
 📋 Информация о группе:
 • Название: %s
-• Тип медиа: 📷 Фото (по умолчанию)
+• Типы медиа: %s (по умолчанию)
 • Облачная папка: %s
 • Владелец: настроен
 
 🎯 Что дальше:
-• Используйте /bot_settings для изменения типа медиа
+• Используйте /bot_settings для изменения типов медиа
 • Используйте /share для получения публичной ссылки
 • Бот начнет загружать новые медиафайлы автоматически
 
 ⚙️ Изменить настройки:`,
 		group.GroupTitle,
+		domain.MediaTypesTitle(group.MediaType),
 		group.CloudFolderPath)
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📷 Только фото", fmt.Sprintf("media_type_settings:%d:photos", group.GroupID)),
-			tgbotapi.NewInlineKeyboardButtonData("🎥 Только видео", fmt.Sprintf("media_type_settings:%d:videos", group.GroupID)),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📷🎥 Все медиа", fmt.Sprintf("media_type_settings:%d:all", group.GroupID)),
+			tgbotapi.NewInlineKeyboardButtonData("🗂 Типы медиа", fmt.Sprintf("media_types:%d", group.GroupID)),
 		),
 	)
 
@@ -131,34 +129,23 @@ func (b *Bot) sendGroupAlreadySetupMessage(chatID int64, group *domain.GroupSess
 		stats = &domain.GroupStats{}
 	}
 
-	mediaTypeText := map[string]string{
-		"photos": "📷 Фото",
-		"videos": "🎥 Видео",
-		"all":    "📷🎥 Все медиа",
-	}
-
 	text := fmt.Sprintf(`ℹ️ Группа уже настроена
 
 📊 Текущие настройки:
 • Название: %s
-• Тип медиа: %s
-• Загружено: 📷%d 🎥%d
+• Типы медиа: %s
+• Загружено: %s
 • Облачная папка: %s
 
 🔄 Изменить настройки:`,
 		group.GroupTitle,
-		mediaTypeText[group.MediaType],
-		stats.PhotosCount,
-		stats.VideosCount,
+		domain.MediaTypesTitle(group.MediaType),
+		formatMediaCounts(stats),
 		group.CloudFolderPath)
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📷 Только фото", fmt.Sprintf("media_type_settings:%d:photos", group.GroupID)),
-			tgbotapi.NewInlineKeyboardButtonData("🎥 Только видео", fmt.Sprintf("media_type_settings:%d:videos", group.GroupID)),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📷🎥 Все медиа", fmt.Sprintf("media_type_settings:%d:all", group.GroupID)),
+			tgbotapi.NewInlineKeyboardButtonData("🗂 Типы медиа", fmt.Sprintf("media_types:%d", group.GroupID)),
 		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("📊 Обновить статистику", fmt.Sprintf("refresh_stats:%d", group.GroupID)),
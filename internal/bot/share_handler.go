@@ -57,7 +57,12 @@ func (b *Bot) handleShareCommand(msg *tgbotapi.Message) {
 	if group.PublicURL == "" {
 		b.sendCreatingLinkMessage(msg.Chat.ID)
 
-		publicURL, err := b.mediaProcessor.CreatePublicLink(session.AccessToken, group.CloudFolderPath)
+		var publicURL string
+		err := b.tokens.Do(group.OwnerChatID, func(accessToken string) error {
+			var err error
+			publicURL, err = b.mediaProcessor.CreatePublicLink(accessToken, group.CloudFolderPath)
+			return err
+		})
 		if err != nil {
 			log.Printf("Error creating public link: %v", err)
 			b.sendErrorMessage(msg.Chat.ID,
@@ -93,16 +98,10 @@ func (b *Bot) sendShareLink(chatID int64, group *domain.GroupSession) {
 		stats = &domain.GroupStats{}
 	}
 
-	mediaTypeText := map[string]string{
-		"photos": "📷 фото",
-		"videos": "🎥 видео",
-		"all":    "📷🎥 медиа",
-	}
-
 	text := fmt.Sprintf(`🔗 Публичная ссылка для группы "%s"
 
 📊 **Статистика:**
-• Загружено: 📷%d 🎥%d
+• Загружено: %s
 • Тип контента: %s
 • Облачная папка: %s
 
@@ -111,9 +110,8 @@ func (b *Bot) sendShareLink(chatID int64, group *domain.GroupSession) {
 
 📤 Поделитесь этой ссылкой с друзьями!`,
 		group.GroupTitle,
-		stats.PhotosCount,
-		stats.VideosCount,
-		mediaTypeText[group.MediaType],
+		formatMediaCounts(stats),
+		domain.MediaTypesTitle(group.MediaType),
 		group.CloudFolderPath,
 		group.PublicURL)
 
@@ -0,0 +1,282 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mail_helper_bot/internal/pkg/group/domain"
+	"mail_helper_bot/internal/pkg/media"
+	"mail_helper_bot/internal/pkg/sidecar"
+)
+
+// maxReplyText - сколько символов текста исходного сообщения сохранять в контексте ответа
+const maxReplyText = 200
+
+// indexRebuildDelay - как долго копятся загрузки в папку перед пересборкой ее index.json
+const indexRebuildDelay = 10 * time.Second
+
+// pendingIndex - пересборка index.json папки, ожидающая своей очереди
+type pendingIndex struct {
+	group   *domain.GroupSession
+	timer   *time.Timer
+	running bool
+	// Папка изменилась во время пересборки, нужна еще одна
+	dirty bool
+}
+
+// handleSidecarCommand настраивает файлы-спутники и манифест папок.
+// /sidecar - текущие настройки, /sidecar json|txt|off - формат спутника,
+// /sidecar index on|off - вести index.json в папках
+func (b *Bot) handleSidecarCommand(msg *tgbotapi.Message) {
+	group, err := b.groupRepo.GetGroupSession(msg.Chat.ID)
+	if err != nil || group == nil {
+		reply := tgbotapi.NewMessage(msg.Chat.ID,
+			"❌ Группа не настроена. Используйте /setup_group для настройки.")
+		b.Api.Send(reply)
+		return
+	}
+
+	args := strings.Fields(strings.ToLower(msg.CommandArguments()))
+	switch {
+	case len(args) == 0:
+		b.sendSidecarInfo(msg.Chat.ID, group)
+		return
+	case len(args) == 1 && args[0] == "off":
+		group.SidecarFormat = sidecar.FormatOff
+	case len(args) == 1 && sidecar.IsValidFormat(args[0]):
+		group.SidecarFormat = args[0]
+	case len(args) == 2 && args[0] == "index" && args[1] == "on":
+		group.FolderIndex = true
+	case len(args) == 2 && args[0] == "index" && args[1] == "off":
+		group.FolderIndex = false
+	default:
+		reply := tgbotapi.NewMessage(msg.Chat.ID,
+			"❌ Используйте /sidecar json, /sidecar txt, /sidecar off или /sidecar index on|off")
+		b.Api.Send(reply)
+		return
+	}
+
+	if !b.checkGroupAdmin(msg) {
+		return
+	}
+
+	if err := b.groupRepo.SaveGroupSession(group); err != nil {
+		log.Printf("Error saving sidecar settings: %v", err)
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Ошибка при сохранении настроек.")
+		b.Api.Send(reply)
+		return
+	}
+
+	b.sendSidecarInfo(msg.Chat.ID, group)
+}
+
+func (b *Bot) sendSidecarInfo(chatID int64, group *domain.GroupSession) {
+	format := "выключены"
+	if group.SidecarFormat != sidecar.FormatOff {
+		format = sidecar.FileName("<имя файла>", group.SidecarFormat)
+	}
+	index := "выключен"
+	if group.FolderIndex {
+		index = "включен"
+	}
+
+	text := fmt.Sprintf(`📝 Контекст сообщений в облаке
+
+• Файлы-спутники: %s
+• %s в каждой папке: %s
+
+Спутник лежит рядом с файлом и хранит подпись с форматированием, автора, дату, пересылку, ответ и альбом. %s - список всех файлов папки с тем же контекстом, он дополняется при каждой загрузке.
+
+Формат спутника: /sidecar json, /sidecar txt
+Выключить спутники: /sidecar off
+Манифест папки: /sidecar index on|off`,
+		format, sidecar.IndexFileName, index, sidecar.IndexFileName)
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	b.Api.Send(msg)
+}
+
+// writeSidecars кладет рядом с загруженным файлом спутник и обновляет index.json
+// папки. Ошибки только логируются: сам файл уже загружен, и повтор задания не нужен.
+func (b *Bot) writeSidecars(group *domain.GroupSession, mediaInfo *media.MediaInfo, size int64) {
+	if group.SidecarFormat == sidecar.FormatOff && !group.FolderIndex {
+		return
+	}
+
+	if group.SidecarFormat != sidecar.FormatOff {
+		data, err := sidecarRecord(mediaInfo, size).Render(group.SidecarFormat)
+		if err != nil {
+			log.Printf("Error rendering sidecar of %s: %v", mediaInfo.FileName, err)
+		} else {
+			cloudPath := path.Join(mediaInfo.CloudFolderPath, sidecar.FileName(mediaInfo.FileName, group.SidecarFormat))
+			err = b.tokens.Do(group.OwnerChatID, func(accessToken string) error {
+				return b.mediaProcessor.UploadMetaFile(accessToken, cloudPath, data)
+			})
+			if err != nil {
+				log.Printf("Error uploading sidecar %s: %v", cloudPath, err)
+			}
+		}
+	}
+
+	if group.FolderIndex {
+		b.scheduleFolderIndex(group, mediaInfo.CloudFolderPath)
+	}
+}
+
+// scheduleFolderIndex откладывает пересборку index.json папки: манифест
+// собирается из всех файлов папки, поэтому при потоке загрузок он
+// пересобирается не чаще раза в indexRebuildDelay, а не после каждого файла.
+// Пересборка, не дождавшаяся остановки бота, пройдет при следующей загрузке в папку.
+func (b *Bot) scheduleFolderIndex(group *domain.GroupSession, folder string) {
+	key := fmt.Sprintf("%d:%s", group.GroupID, folder)
+
+	b.indexMu.Lock()
+	defer b.indexMu.Unlock()
+
+	pending, ok := b.indexes[key]
+	if !ok {
+		pending = &pendingIndex{}
+		b.indexes[key] = pending
+	}
+	pending.group = group
+
+	if pending.running {
+		pending.dirty = true
+		return
+	}
+	if pending.timer == nil {
+		pending.timer = time.AfterFunc(indexRebuildDelay, func() { b.flushFolderIndex(key, folder) })
+	}
+}
+
+// flushFolderIndex пересобирает отложенный index.json. Пересборки одной папки
+// не идут одновременно, разные папки пересобираются параллельно.
+func (b *Bot) flushFolderIndex(key, folder string) {
+	b.indexMu.Lock()
+	pending := b.indexes[key]
+	pending.timer = nil
+	pending.running = true
+	group := pending.group
+	b.indexMu.Unlock()
+
+	b.updateFolderIndex(group, folder)
+
+	b.indexMu.Lock()
+	defer b.indexMu.Unlock()
+	pending.running = false
+	if pending.dirty {
+		pending.dirty = false
+		pending.timer = time.AfterFunc(indexRebuildDelay, func() { b.flushFolderIndex(key, folder) })
+		return
+	}
+	delete(b.indexes, key)
+}
+
+// updateFolderIndex пересобирает index.json папки из processed_media, поэтому
+// потерянный или испорченный манифест восстанавливается при следующей загрузке
+func (b *Bot) updateFolderIndex(group *domain.GroupSession, folder string) {
+	records, err := b.groupRepo.GetFolderSidecars(group.GroupID, folder)
+	if err != nil {
+		log.Printf("Error getting sidecars of folder %s: %v", folder, err)
+		return
+	}
+
+	data, err := sidecar.RenderIndex(folder, records)
+	if err != nil {
+		log.Printf("Error rendering index of folder %s: %v", folder, err)
+		return
+	}
+
+	cloudPath := path.Join(folder, sidecar.IndexFileName)
+	err = b.tokens.Do(group.OwnerChatID, func(accessToken string) error {
+		return b.mediaProcessor.UploadMetaFile(accessToken, cloudPath, data)
+	})
+	if err != nil {
+		log.Printf("Error uploading folder index %s: %v", cloudPath, err)
+	}
+}
+
+// sidecarRecord собирает описание загруженного файла для спутника и манифеста
+func sidecarRecord(mediaInfo *media.MediaInfo, size int64) *sidecar.Record {
+	record := &sidecar.Record{
+		FileName:   mediaInfo.FileName,
+		Type:       mediaInfo.Type,
+		MimeType:   mediaInfo.MimeType,
+		Size:       size,
+		MessageID:  mediaInfo.MessageID,
+		Date:       sidecar.FormatDate(mediaInfo.MessageDate),
+		SenderID:   mediaInfo.SenderID,
+		SenderName: mediaInfo.SenderName,
+		Caption:    mediaInfo.Caption,
+		AlbumID:    mediaInfo.AlbumID,
+		Route:      mediaInfo.Route,
+		Version:    mediaInfo.Version,
+	}
+	if ctx := mediaInfo.Context; ctx != nil {
+		record.CaptionEntities = ctx.CaptionEntities
+		record.Forward = ctx.Forward
+		record.Reply = ctx.Reply
+	}
+	return record
+}
+
+// messageContext собирает форматирование подписи, источник пересылки и
+// сообщение, на которое отвечает медиа. Возвращает nil, если контекста нет.
+func messageContext(msg *tgbotapi.Message) *sidecar.Context {
+	ctx := &sidecar.Context{}
+
+	for _, e := range msg.CaptionEntities {
+		entity := sidecar.Entity{
+			Type:     e.Type,
+			Offset:   e.Offset,
+			Length:   e.Length,
+			URL:      e.URL,
+			Language: e.Language,
+		}
+		if e.User != nil {
+			entity.UserID = e.User.ID
+		}
+		ctx.CaptionEntities = append(ctx.CaptionEntities, entity)
+	}
+
+	if msg.ForwardDate != 0 {
+		forward := &sidecar.Forward{
+			From:      msg.ForwardSenderName,
+			MessageID: msg.ForwardFromMessageID,
+			Date:      int64(msg.ForwardDate),
+		}
+		if msg.ForwardFrom != nil {
+			forward.From = senderDisplayName(msg.ForwardFrom)
+			forward.FromID = msg.ForwardFrom.ID
+		}
+		if msg.ForwardFromChat != nil {
+			forward.From = msg.ForwardFromChat.Title
+			forward.ChatID = msg.ForwardFromChat.ID
+		}
+		ctx.Forward = forward
+	}
+
+	if reply := msg.ReplyToMessage; reply != nil {
+		text := reply.Text
+		if text == "" {
+			text = reply.Caption
+		}
+		ctx.Reply = &sidecar.Reply{
+			MessageID: reply.MessageID,
+			Text:      truncateText(text, maxReplyText),
+		}
+		if reply.From != nil {
+			ctx.Reply.SenderID = reply.From.ID
+			ctx.Reply.SenderName = senderDisplayName(reply.From)
+		}
+	}
+
+	if len(ctx.CaptionEntities) == 0 && ctx.Forward == nil && ctx.Reply == nil {
+		return nil
+	}
+	return ctx
+}
@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mail_helper_bot/internal/pkg/group/domain"
+	"mail_helper_bot/internal/pkg/media"
+)
+
+// handlePauseCommand приостанавливает выгрузку. В отличие от удаления бота,
+// настройки группы и история загрузок сохраняются.
+func (b *Bot) handlePauseCommand(msg *tgbotapi.Message) {
+	b.setGroupState(msg, domain.StatePaused)
+}
+
+// handleResumeCommand возобновляет загрузку после паузы или пробного режима
+func (b *Bot) handleResumeCommand(msg *tgbotapi.Message) {
+	b.setGroupState(msg, domain.StateActive)
+}
+
+// handleDryRunCommand включает пробный режим: бот отбирает и называет файлы
+// как обычно, но только учитывает их. Повторная команда показывает итоги.
+func (b *Bot) handleDryRunCommand(msg *tgbotapi.Message) {
+	b.setGroupState(msg, domain.StateDryRun)
+}
+
+func (b *Bot) setGroupState(msg *tgbotapi.Message, state string) {
+	group, err := b.groupRepo.GetGroupSession(msg.Chat.ID)
+	if err != nil || group == nil {
+		reply := tgbotapi.NewMessage(msg.Chat.ID,
+			"❌ Группа не настроена. Используйте /setup_group для настройки.")
+		b.Api.Send(reply)
+		return
+	}
+
+	previous := groupState(group)
+	if previous == state {
+		b.sendStateInfo(msg.Chat.ID, group)
+		return
+	}
+
+	if !b.checkGroupAdmin(msg) {
+		return
+	}
+
+	group.State = state
+	if err := b.groupRepo.SaveGroupSession(group); err != nil {
+		log.Printf("Error saving group state: %v", err)
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Ошибка при сохранении настроек.")
+		b.Api.Send(reply)
+		return
+	}
+	log.Printf("Group %d state changed from %s to %s", group.GroupID, previous, state)
+
+	text := ""
+	switch state {
+	case domain.StatePaused:
+		text = "⏸ Выгрузка приостановлена. Новые медиа не загружаются, настройки и история сохранены. Файлы, уже стоящие в очереди, будут загружены.\n\nВозобновить: /resume"
+	case domain.StateDryRun:
+		if err := b.groupRepo.ResetDryRunStats(group.GroupID); err != nil {
+			log.Printf("Error resetting dry run stats: %v", err)
+		}
+		text = "🧪 Пробный режим включен. Бот отбирает медиа по всем настройкам группы, но ничего не загружает в облако, а только считает.\n\nИтоги: /dryrun, вернуться к загрузке: /resume"
+	default:
+		text = "▶️ Выгрузка возобновлена."
+		if previous == domain.StateDryRun {
+			text += "\n\n" + dryRunSummary(group)
+		}
+		if previous == domain.StatePaused {
+			text += " Медиа, отправленные во время паузы, не загружаются."
+		}
+	}
+
+	b.Api.Send(tgbotapi.NewMessage(msg.Chat.ID, text))
+}
+
+func (b *Bot) sendStateInfo(chatID int64, group *domain.GroupSession) {
+	text := "Состояние выгрузки: " + stateTitle(groupState(group))
+	switch groupState(group) {
+	case domain.StatePaused:
+		text += "\n\nВозобновить: /resume"
+	case domain.StateDryRun:
+		text += "\n\n" + dryRunSummary(group) + "\n\nВернуться к загрузке: /resume"
+	default:
+		text += "\n\nПриостановить: /pause, пробный режим: /dryrun"
+	}
+	b.Api.Send(tgbotapi.NewMessage(chatID, text))
+}
+
+// recordDryRun учитывает медиа, которое было бы загружено, вместо загрузки
+func (b *Bot) recordDryRun(group *domain.GroupSession, mediaInfo *media.MediaInfo) {
+	log.Printf("Dry run in group %d: would upload %s (%s, %d bytes)",
+		group.GroupID, mediaInfo.CloudFilePath(), mediaInfo.Type, mediaInfo.FileSize)
+
+	if err := b.groupRepo.AddDryRunMedia(group.GroupID, mediaInfo.FileUniqueID, mediaInfo.FileSize); err != nil {
+		log.Printf("Error counting dry run media: %v", err)
+	}
+}
+
+// groupState возвращает состояние группы; у групп до появления состояний оно пустое
+func groupState(group *domain.GroupSession) string {
+	if group.State == "" {
+		return domain.StateActive
+	}
+	return group.State
+}
+
+func stateTitle(state string) string {
+	switch state {
+	case domain.StatePaused:
+		return "⏸ на паузе"
+	case domain.StateDryRun:
+		return "🧪 пробный режим"
+	default:
+		return "▶️ загрузка"
+	}
+}
+
+func dryRunSummary(group *domain.GroupSession) string {
+	return fmt.Sprintf("🧪 В пробном режиме было бы загружено: %d файлов, %s",
+		group.DryRunCount, formatBytes(group.DryRunBytes))
+}
@@ -0,0 +1,191 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mail_helper_bot/internal/pkg/group/domain"
+)
+
+// messageTopic возвращает тему форума сообщения или nil, если сообщение вне
+// темы. Тема, которую бот видит впервые, сохраняется включенной; название
+// дописывается, как только оно становится известно.
+func (b *Bot) messageTopic(group *domain.GroupSession, thread threadInfo) (*domain.GroupTopic, error) {
+	if thread.ThreadID == 0 {
+		return nil, nil
+	}
+
+	topic, err := b.groupRepo.GetGroupTopic(group.GroupID, thread.ThreadID)
+	if err != nil {
+		return nil, err
+	}
+	if topic != nil && (topic.Name != "" || thread.Name == "") {
+		return topic, nil
+	}
+
+	if topic == nil {
+		topic = &domain.GroupTopic{GroupID: group.GroupID, ThreadID: thread.ThreadID, Enabled: true}
+	}
+	topic.Name = thread.Name
+	if err := b.groupRepo.SaveGroupTopic(topic); err != nil {
+		return nil, err
+	}
+	return topic, nil
+}
+
+// handleTopicServiceMessage запоминает название темы при ее создании и
+// переименовании. Новые файлы темы пойдут в подпапку с новым названием,
+// уже загруженные остаются на месте.
+func (b *Bot) handleTopicServiceMessage(msg *tgbotapi.Message, thread threadInfo) {
+	if thread.Name == "" {
+		return
+	}
+
+	group, err := b.groupRepo.GetGroupSession(msg.Chat.ID)
+	if err != nil || group == nil {
+		return
+	}
+
+	topic, err := b.groupRepo.GetGroupTopic(group.GroupID, thread.ThreadID)
+	if err != nil {
+		log.Printf("Error getting topic %d of group %d: %v", thread.ThreadID, group.GroupID, err)
+		return
+	}
+	if topic == nil {
+		topic = &domain.GroupTopic{GroupID: group.GroupID, ThreadID: thread.ThreadID, Enabled: true}
+	}
+	if topic.Name == thread.Name {
+		return
+	}
+
+	log.Printf("Topic %d of group %d renamed from %q to %q", thread.ThreadID, group.GroupID, topic.Name, thread.Name)
+	topic.Name = thread.Name
+	if err := b.groupRepo.SaveGroupTopic(topic); err != nil {
+		log.Printf("Error saving topic %d of group %d: %v", thread.ThreadID, group.GroupID, err)
+	}
+}
+
+// handleTopicsCommand показывает и настраивает темы форума.
+// /topics - список тем, /topics on|off N - включить или выключить тему,
+// /topics types N photo,video|group - категории медиа темы,
+// /topics name N Название - название темы, созданной до добавления бота
+func (b *Bot) handleTopicsCommand(msg *tgbotapi.Message) {
+	group, err := b.groupRepo.GetGroupSession(msg.Chat.ID)
+	if err != nil || group == nil {
+		reply := tgbotapi.NewMessage(msg.Chat.ID,
+			"❌ Группа не настроена. Используйте /setup_group для настройки.")
+		b.Api.Send(reply)
+		return
+	}
+
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) == 0 {
+		b.sendTopicsInfo(msg.Chat.ID, group)
+		return
+	}
+
+	usage := "❌ Используйте /topics on|off N, /topics types N photo,video|group или /topics name N Название"
+	if len(args) < 2 {
+		b.Api.Send(tgbotapi.NewMessage(msg.Chat.ID, usage))
+		return
+	}
+
+	threadID, err := strconv.Atoi(strings.TrimPrefix(args[1], "#"))
+	if err != nil || threadID <= 0 {
+		b.Api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Номер темы - число из списка /topics"))
+		return
+	}
+
+	topic, err := b.groupRepo.GetGroupTopic(group.GroupID, threadID)
+	if err != nil {
+		log.Printf("Error getting topic %d of group %d: %v", threadID, group.GroupID, err)
+		b.Api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Ошибка при загрузке настроек."))
+		return
+	}
+	if topic == nil {
+		b.Api.Send(tgbotapi.NewMessage(msg.Chat.ID,
+			fmt.Sprintf("❌ Тема #%d еще не встречалась. Она появится в /topics после первого сообщения в ней.", threadID)))
+		return
+	}
+
+	switch command := strings.ToLower(args[0]); {
+	case command == "on" && len(args) == 2:
+		topic.Enabled = true
+	case command == "off" && len(args) == 2:
+		topic.Enabled = false
+	case command == "types" && len(args) == 3 && strings.ToLower(args[2]) == "group":
+		topic.MediaType = ""
+	case command == "types" && len(args) == 3:
+		types := domain.FormatMediaTypes(strings.Split(strings.ToLower(args[2]), ","))
+		if types == "" {
+			b.Api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Неизвестные типы медиа. Доступны: "+mediaTypeNames()))
+			return
+		}
+		topic.MediaType = types
+	case command == "name" && len(args) >= 3:
+		topic.Name = strings.Join(args[2:], " ")
+	default:
+		b.Api.Send(tgbotapi.NewMessage(msg.Chat.ID, usage))
+		return
+	}
+
+	if !b.checkGroupAdmin(msg) {
+		return
+	}
+
+	if err := b.groupRepo.SaveGroupTopic(topic); err != nil {
+		log.Printf("Error saving topic %d of group %d: %v", threadID, group.GroupID, err)
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Ошибка при сохранении настроек.")
+		b.Api.Send(reply)
+		return
+	}
+
+	b.sendTopicsInfo(msg.Chat.ID, group)
+}
+
+func (b *Bot) sendTopicsInfo(chatID int64, group *domain.GroupSession) {
+	topics, err := b.groupRepo.GetGroupTopics(group.GroupID)
+	if err != nil {
+		log.Printf("Error getting topics of group %d: %v", group.GroupID, err)
+		b.Api.Send(tgbotapi.NewMessage(chatID, "❌ Ошибка при загрузке настроек."))
+		return
+	}
+
+	text := "🗂 Темы форума\n\n"
+	if len(topics) == 0 {
+		text += "Бот еще не видел сообщений в темах. Медиа из тем будут складываться в подпапки с их названиями.\n"
+	}
+	for _, topic := range topics {
+		status := "✅"
+		if !topic.Enabled {
+			status = "⏸"
+		}
+		types := "как в группе"
+		if topic.MediaType != "" {
+			types = domain.MediaTypesTitle(topic.MediaType)
+		}
+		text += fmt.Sprintf("%s #%d %s\n   📁 %s/%s\n   Типы медиа: %s\n",
+			status, topic.ThreadID, topic.Title(), group.CloudFolderPath, topic.Folder(), types)
+	}
+
+	text += `
+Медиа из General загружаются в папку группы. После переименования темы новые файлы идут в папку с новым названием.
+
+Выключить или включить тему: /topics off N, /topics on N
+Типы медиа темы: /topics types N photo,video или /topics types N group
+Название темы, созданной до бота: /topics name N Название`
+
+	b.Api.Send(tgbotapi.NewMessage(chatID, text))
+}
+
+// mediaTypeNames перечисляет коды категорий медиа для подсказок
+func mediaTypeNames() string {
+	var names []string
+	for _, c := range domain.MediaCategories {
+		names = append(names, c.Type)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,136 @@
+package bot
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// incomingUpdate - апдейт Telegram вместе с темой форума сообщения.
+// tgbotapi v5.5.1 не знает о темах, поэтому они разбираются из того же ответа getUpdates.
+type incomingUpdate struct {
+	tgbotapi.Update
+	thread threadInfo
+}
+
+// threadInfo - тема форума, в которой отправлено сообщение
+type threadInfo struct {
+	ThreadID int    // 0 - сообщение вне темы: General или обычная группа
+	Name     string // Название темы, если оно известно из сообщения
+	Service  bool   // Служебное сообщение о создании или переименовании темы
+}
+
+type rawForumTopic struct {
+	Name string `json:"name"`
+}
+
+// rawTopicMessage - поля сообщения о темах форума из Bot API 6.3
+type rawTopicMessage struct {
+	MessageID         int            `json:"message_id"`
+	MessageThreadID   int            `json:"message_thread_id"`
+	IsTopicMessage    bool           `json:"is_topic_message"`
+	ForumTopicCreated *rawForumTopic `json:"forum_topic_created"`
+	ForumTopicEdited  *rawForumTopic `json:"forum_topic_edited"`
+	ReplyToMessage    *struct {
+		MessageID         int            `json:"message_id"`
+		ForumTopicCreated *rawForumTopic `json:"forum_topic_created"`
+	} `json:"reply_to_message"`
+}
+
+type rawUpdate struct {
+	Message       *rawTopicMessage `json:"message"`
+	EditedMessage *rawTopicMessage `json:"edited_message"`
+}
+
+// thread определяет тему сообщения. message_thread_id бывает и у ответов в
+// обычных группах, поэтому тема учитывается только при is_topic_message.
+func (m *rawTopicMessage) thread() threadInfo {
+	if m == nil {
+		return threadInfo{}
+	}
+
+	// Сообщение о создании темы открывает ее: его ID и есть ID темы
+	if m.ForumTopicCreated != nil {
+		threadID := m.MessageThreadID
+		if threadID == 0 {
+			threadID = m.MessageID
+		}
+		return threadInfo{ThreadID: threadID, Name: m.ForumTopicCreated.Name, Service: true}
+	}
+
+	if !m.IsTopicMessage || m.MessageThreadID == 0 {
+		return threadInfo{}
+	}
+
+	thread := threadInfo{ThreadID: m.MessageThreadID}
+	switch {
+	case m.ForumTopicEdited != nil:
+		// Без name тему не переименовали, а сменили значок
+		thread.Name = m.ForumTopicEdited.Name
+		thread.Service = true
+	case m.ReplyToMessage != nil && m.ReplyToMessage.MessageID == m.MessageThreadID &&
+		m.ReplyToMessage.ForumTopicCreated != nil:
+		// Сообщение не в ответ на другое ссылается на создание темы
+		thread.Name = m.ReplyToMessage.ForumTopicCreated.Name
+	}
+	return thread
+}
+
+// receiveUpdates опрашивает getUpdates так же, как tgbotapi.GetUpdatesChan,
+// но сохраняет темы форумов сообщений
+func (b *Bot) receiveUpdates(config tgbotapi.UpdateConfig) <-chan incomingUpdate {
+	ch := make(chan incomingUpdate, b.Api.Buffer)
+
+	go func() {
+		for {
+			updates, err := b.getUpdates(config)
+			if err != nil {
+				log.Println(err)
+				log.Println("Failed to get updates, retrying in 3 seconds...")
+				time.Sleep(time.Second * 3)
+				continue
+			}
+
+			for _, update := range updates {
+				if update.UpdateID >= config.Offset {
+					config.Offset = update.UpdateID + 1
+					ch <- update
+				}
+			}
+		}
+	}()
+
+	return ch
+}
+
+func (b *Bot) getUpdates(config tgbotapi.UpdateConfig) ([]incomingUpdate, error) {
+	resp, err := b.Api.Request(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var updates []tgbotapi.Update
+	if err := json.Unmarshal(resp.Result, &updates); err != nil {
+		return nil, err
+	}
+	var raw []rawUpdate
+	if err := json.Unmarshal(resp.Result, &raw); err != nil {
+		return nil, err
+	}
+
+	result := make([]incomingUpdate, len(updates))
+	for i, update := range updates {
+		result[i].Update = update
+		if i < len(raw) {
+			switch {
+			case raw[i].Message != nil:
+				result[i].thread = raw[i].Message.thread()
+			case raw[i].EditedMessage != nil:
+				result[i].thread = raw[i].EditedMessage.thread()
+			}
+		}
+	}
+	return result, nil
+}
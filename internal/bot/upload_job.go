@@ -0,0 +1,253 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"mail_helper_bot/internal/pkg/cloud/cloud_service"
+	"mail_helper_bot/internal/pkg/group/domain"
+	"mail_helper_bot/internal/pkg/media"
+	notifyDomain "mail_helper_bot/internal/pkg/notify/domain"
+	"mail_helper_bot/internal/pkg/oauth/oauth_service"
+	queueDomain "mail_helper_bot/internal/pkg/upload_queue/domain"
+)
+
+// strippedContentPrefix - префикс ключа индекса содержимого для хеша файла,
+// загруженного без метаданных
+const strippedContentPrefix = "stripped:"
+
+// ProcessUploadJob выполняет задание из очереди загрузок: скачивает медиа
+// из Telegram, загружает его в облако владельца группы и помечает как обработанное.
+// Ошибки, которые повтор не исправит, помечаются как постоянные.
+func (b *Bot) ProcessUploadJob(job *queueDomain.UploadJob) error {
+	mediaInfo := job.Media
+	if mediaInfo == nil {
+		return queueDomain.Permanent(fmt.Errorf("job %d has no media payload", job.ID))
+	}
+
+	group, err := b.groupRepo.GetGroupSession(job.GroupID)
+	if err != nil {
+		return fmt.Errorf("failed to get group %d: %v", job.GroupID, err)
+	}
+	if group == nil {
+		return queueDomain.Permanent(fmt.Errorf("group %d is not configured", job.GroupID))
+	}
+
+	// Участник мог отказаться от загрузки, пока задание ждало в очереди. Ошибка
+	// базы возвращается, чтобы задание повторилось, а не завершилось без загрузки.
+	optedOut := false
+	if mediaInfo.SenderID != 0 {
+		optedOut, err = b.groupRepo.IsMemberOptedOut(group.GroupID, mediaInfo.SenderID)
+		if err != nil {
+			return fmt.Errorf("failed to check opt-out of user %d: %w", mediaInfo.SenderID, err)
+		}
+	}
+	if optedOut {
+		log.Printf("Dropping job %d in group %d: sender %d opted out", job.ID, group.GroupID, mediaInfo.SenderID)
+		b.finishAlbumItem(group.GroupID, mediaInfo, false)
+		removeImportedFile(mediaInfo)
+		return nil
+	}
+
+	// В пробном режиме облако не трогается: файл только учитывается
+	if groupState(group) == domain.StateDryRun {
+		b.recordDryRun(group, mediaInfo)
+		b.finishAlbumItem(group.GroupID, mediaInfo, true)
+		removeImportedFile(mediaInfo)
+		return nil
+	}
+
+	mediaInfo.StripMetadata = group.StripMetadata
+	if group.StripMetadata && group.KeepOriginals {
+		mediaInfo.OriginalsFolder = originalsFolder(group, mediaInfo)
+	}
+
+	// Тот же файл мог уже загружаться - в эту или в другую группу владельца
+	reused, err := b.reuseUploadedContent(group, mediaInfo)
+	if err != nil {
+		log.Printf("Error reusing uploaded content, uploading again: %v", err)
+	}
+	if reused {
+		b.finishAlbumItem(group.GroupID, mediaInfo, true)
+		removeImportedFile(mediaInfo)
+		return nil
+	}
+
+	// Токен владельца обновляется заранее и повторно при ответе 401
+	var result *cloud_service.UploadResult
+	err = b.tokens.Do(group.OwnerChatID, func(accessToken string) error {
+		var err error
+		result, err = b.mediaProcessor.ProcessSingleMedia(accessToken, mediaInfo)
+		return err
+	})
+	if errors.Is(err, oauth_service.ErrNotAuthorized) || errors.Is(err, oauth_service.ErrSessionExpired) {
+		b.notifyOwnerAuth(group)
+		return queueDomain.Permanent(fmt.Errorf("owner %d of group %d is not authorized: %w", group.OwnerChatID, group.GroupID, err))
+	}
+	if errors.Is(err, media.ErrFileTooBig) {
+		b.notifyFileTooBig(group, mediaInfo)
+		return queueDomain.Permanent(fmt.Errorf("failed to upload media to cloud: %w", err))
+	}
+	if err != nil {
+		b.notifyUploadError(group, err)
+		err = fmt.Errorf("failed to upload media to cloud: %w", err)
+		if !media.IsTransient(err) {
+			return queueDomain.Permanent(err)
+		}
+		return err
+	}
+
+	if err := b.groupRepo.SaveMediaContentHash(contentIndexKey(mediaInfo.FileUniqueID, mediaInfo.StripMetadata), result.Hash, result.Size); err != nil {
+		log.Printf("Error saving media content hash: %v", err)
+	}
+
+	b.markMediaProcessed(group, mediaInfo, result.Hash, result.Size)
+	b.writeSidecars(group, mediaInfo, result.Size)
+	b.finishAlbumItem(group.GroupID, mediaInfo, true)
+	removeImportedFile(mediaInfo)
+
+	log.Printf("Successfully uploaded media: %s to cloud folder: %s", mediaInfo.FileName, mediaInfo.CloudFolderPath)
+	return nil
+}
+
+// handleUploadDeadLetter вызывается пулом, когда задание перенесено в dead letter
+func (b *Bot) handleUploadDeadLetter(job *queueDomain.UploadJob, err error) {
+	b.notifyUploadFailures(job, err)
+	if job.Media != nil {
+		b.finishAlbumItem(job.GroupID, job.Media, false)
+		removeImportedFile(job.Media)
+	}
+}
+
+// notifyFileTooBig сообщает владельцу группы, что файл больше лимита текущего
+// режима Bot API. Пачка больших файлов дает одно уведомление со счетчиком похожих.
+func (b *Bot) notifyFileTooBig(group *domain.GroupSession, mediaInfo *media.MediaInfo) {
+	apiConfig := b.mediaProcessor.APIConfig()
+
+	size := "неизвестен"
+	if mediaInfo.FileSize > 0 {
+		size = formatBytes(mediaInfo.FileSize)
+	}
+
+	text := fmt.Sprintf("⚠️ Файл не загружен в облако группы \"%s\"\n\n"+
+		"• Файл: %s\n"+
+		"• Размер: %s\n"+
+		"• Лимит: %s (%s)",
+		group.GroupTitle, mediaInfo.FileName, size, formatBytes(apiConfig.MaxFileSize()), apiConfig.ModeName())
+
+	if !apiConfig.Local {
+		text += "\n\nЧтобы загружать файлы до 2 ГБ, запустите собственный сервер telegram-bot-api " +
+			"с флагом --local и укажите его в TELEGRAM_API_URL и TELEGRAM_API_LOCAL=true."
+	}
+
+	b.notifier.Notify(notifyDomain.Notification{
+		OwnerChatID: group.OwnerChatID,
+		Kind:        notifyDomain.KindFileTooBig,
+		Key:         strconv.FormatInt(group.GroupID, 10),
+		Text:        text,
+	})
+}
+
+// reuseUploadedContent проверяет индекс содержимого: если файл с тем же
+// содержимым уже есть в группе, загрузка пропускается, а если он есть в облаке
+// владельца в другой группе - файл добавляется по хешу без скачивания.
+// Возвращает true, если загружать файл больше не нужно.
+func (b *Bot) reuseUploadedContent(group *domain.GroupSession, mediaInfo *media.MediaInfo) (bool, error) {
+	if mediaInfo.FileUniqueID == "" {
+		return false, nil
+	}
+
+	contentHash, size, err := b.groupRepo.GetMediaContentHash(contentIndexKey(mediaInfo.FileUniqueID, group.StripMetadata))
+	if err != nil || contentHash == "" {
+		return false, err
+	}
+
+	inGroup, err := b.groupRepo.IsContentProcessed(contentHash, group.GroupID)
+	if err != nil {
+		return false, err
+	}
+	if inGroup {
+		// Запись не создается: по новому имени файла в облаке нет, и запись
+		// попала бы в index.json, статистику и /forget как второй файл
+		log.Printf("Content %s is already in group %d, skipping %s", contentHash, group.GroupID, mediaInfo.FileName)
+		return true, nil
+	}
+
+	// Копия из другой группы могла сохраниться вместе с метаданными
+	if group.StripMetadata {
+		return false, nil
+	}
+
+	owned, err := b.groupRepo.IsContentOwnedBy(contentHash, group.OwnerChatID)
+	if err != nil || !owned {
+		return false, err
+	}
+
+	err = b.tokens.Do(group.OwnerChatID, func(accessToken string) error {
+		return b.mediaProcessor.AddExistingMedia(accessToken, mediaInfo, contentHash, size)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	log.Printf("Reused cloud copy %s for media %s in group %d", contentHash, mediaInfo.FileName, group.GroupID)
+	b.markMediaProcessed(group, mediaInfo, contentHash, size)
+	b.writeSidecars(group, mediaInfo, size)
+	return true, nil
+}
+
+// contentIndexKey возвращает ключ файла в индексе содержимого. Хеш файла без
+// метаданных хранится под своим ключом: иначе группа, где метаданные не
+// удаляются, получила бы вместо оригинала очищенную копию, и наоборот.
+func contentIndexKey(fileUniqueID string, stripped bool) string {
+	if stripped {
+		return strippedContentPrefix + fileUniqueID
+	}
+	return fileUniqueID
+}
+
+// markMediaProcessed записывает медиа со всеми метаданными в processed_media группы
+func (b *Bot) markMediaProcessed(group *domain.GroupSession, mediaInfo *media.MediaInfo, contentHash string, size int64) {
+	processedMedia := &domain.ProcessedMedia{
+		GroupID:       group.GroupID,
+		FileUniqueID:  mediaInfo.FileUniqueID,
+		FileName:      mediaInfo.FileName,
+		MediaType:     mediaInfo.Type,
+		FileSizeBytes: size,
+		ContentHash:   contentHash,
+		CloudPath:     mediaInfo.CloudFilePath(),
+		OriginalPath:  mediaInfo.OriginalPath,
+		MimeType:      mediaInfo.MimeType,
+		Width:         mediaInfo.Width,
+		Height:        mediaInfo.Height,
+		Duration:      mediaInfo.Duration,
+		MessageID:     mediaInfo.MessageID,
+		SenderID:      mediaInfo.SenderID,
+		SenderName:    mediaInfo.SenderName,
+		Caption:       mediaInfo.Caption,
+		AlbumID:       mediaInfo.AlbumID,
+		MatchedRoute:  mediaInfo.Route,
+		Version:       mediaInfo.Version,
+		PreviousID:    mediaInfo.PreviousID,
+		Replaces:      mediaInfo.Overwrite,
+	}
+
+	if record, err := json.Marshal(sidecarRecord(mediaInfo, size)); err == nil {
+		processedMedia.Sidecar = record
+	} else {
+		log.Printf("Error marshaling sidecar record: %v", err)
+	}
+
+	if mediaInfo.MessageDate > 0 {
+		messageDate := time.Unix(mediaInfo.MessageDate, 0)
+		processedMedia.MessageDate = &messageDate
+	}
+
+	if err := b.groupRepo.SaveProcessedMedia(processedMedia); err != nil {
+		log.Printf("Error saving processed media: %v", err)
+	}
+}
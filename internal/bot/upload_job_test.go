@@ -0,0 +1,225 @@
+package bot
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mail_helper_bot/internal/pkg/group/domain"
+	groupRepository "mail_helper_bot/internal/pkg/group/repository"
+	"mail_helper_bot/internal/pkg/media"
+	notifyDomain "mail_helper_bot/internal/pkg/notify/domain"
+	"mail_helper_bot/internal/pkg/notify/notify_service"
+	notifyRepository "mail_helper_bot/internal/pkg/notify/repository"
+	"mail_helper_bot/internal/pkg/oauth/oauth_service"
+	sessionDomain "mail_helper_bot/internal/pkg/session/domain"
+	queueDomain "mail_helper_bot/internal/pkg/upload_queue/domain"
+)
+
+const (
+	testGroupID = -100
+	testOwnerID = 42
+)
+
+// fakeGroupRepo реализует только методы, которые вызывает ProcessUploadJob
+// до загрузки; вызов остальных завершит тест паникой
+type fakeGroupRepo struct {
+	groupRepository.GroupRepository
+
+	group       *domain.GroupSession
+	groupErr    error
+	optedOut    bool
+	optOutErr   error
+	dryRunFiles []string
+}
+
+func (r *fakeGroupRepo) GetGroupSession(groupID int64) (*domain.GroupSession, error) {
+	return r.group, r.groupErr
+}
+
+func (r *fakeGroupRepo) IsMemberOptedOut(groupID, userID int64) (bool, error) {
+	return r.optedOut, r.optOutErr
+}
+
+func (r *fakeGroupRepo) AddDryRunMedia(groupID int64, fileUniqueID string, size int64) error {
+	r.dryRunFiles = append(r.dryRunFiles, fileUniqueID)
+	return nil
+}
+
+func (r *fakeGroupRepo) GetMediaContentHash(fileUniqueID string) (string, int64, error) {
+	return "", 0, nil
+}
+
+// fakeSessions хранит сессии владельцев в памяти
+type fakeSessions struct {
+	oauth_service.Storage
+
+	sessions map[int64]*sessionDomain.UserSession
+}
+
+func (s *fakeSessions) GetSession(chatID int64) (*sessionDomain.UserSession, error) {
+	return s.sessions[chatID], nil
+}
+
+// fakeNotifications запоминает виды уведомлений и не дает их отправить,
+// поэтому тестам не нужен Telegram
+type fakeNotifications struct {
+	notifyRepository.NotificationRepository
+
+	kinds []string
+}
+
+func (n *fakeNotifications) Acquire(ownerChatID int64, kind, key string, cooldown time.Duration, maxPerHour int) (bool, int, error) {
+	n.kinds = append(n.kinds, kind)
+	return false, 0, nil
+}
+
+func newTestUploadBot(groups *fakeGroupRepo, authorized bool) (*Bot, *fakeNotifications) {
+	sessions := &fakeSessions{sessions: make(map[int64]*sessionDomain.UserSession)}
+	if authorized {
+		sessions.sessions[testOwnerID] = &sessionDomain.UserSession{ChatID: testOwnerID, AccessToken: "token"}
+	}
+	oauth := oauth_service.NewOAuthService("client", "secret", "", sessions)
+
+	notifications := &fakeNotifications{}
+	b := &Bot{
+		oauth:          oauth,
+		tokens:         oauth_service.NewTokenProvider(oauth),
+		storage:        sessions,
+		groupRepo:      groups,
+		mediaProcessor: media.NewMediaProcessor(nil, media.BotAPIConfig{}),
+		notifier:       notify_service.NewNotifier(nil, notifications, oauth),
+	}
+	return b, notifications
+}
+
+func testGroup() *domain.GroupSession {
+	return &domain.GroupSession{
+		GroupID:         testGroupID,
+		GroupTitle:      "Test",
+		OwnerChatID:     testOwnerID,
+		CloudFolderPath: "telegram_group_test",
+	}
+}
+
+func testJob(mediaInfo *media.MediaInfo) *queueDomain.UploadJob {
+	if mediaInfo.FileUniqueID == "" {
+		mediaInfo.FileUniqueID = "unique"
+	}
+	if mediaInfo.FileName == "" {
+		mediaInfo.FileName = "photo.jpg"
+	}
+	mediaInfo.Type = domain.MediaPhoto
+	mediaInfo.CloudFolderPath = "telegram_group_test"
+	return &queueDomain.UploadJob{ID: 1, GroupID: testGroupID, Media: mediaInfo}
+}
+
+func TestProcessUploadJobErrors(t *testing.T) {
+	dbErr := errors.New("connection reset")
+
+	tests := []struct {
+		name       string
+		groups     *fakeGroupRepo
+		authorized bool
+		media      *media.MediaInfo
+		permanent  bool
+		notified   string
+	}{
+		{
+			name:      "group removed",
+			groups:    &fakeGroupRepo{},
+			media:     &media.MediaInfo{FileID: "file"},
+			permanent: true,
+		},
+		{
+			name:   "group lookup fails",
+			groups: &fakeGroupRepo{groupErr: dbErr},
+			media:  &media.MediaInfo{FileID: "file"},
+		},
+		{
+			name:   "opt-out check fails",
+			groups: &fakeGroupRepo{group: testGroup(), optOutErr: dbErr},
+			media:  &media.MediaInfo{FileID: "file", SenderID: 7},
+		},
+		{
+			name:      "owner not authorized",
+			groups:    &fakeGroupRepo{group: testGroup()},
+			media:     &media.MediaInfo{FileID: "file"},
+			permanent: true,
+			notified:  notifyDomain.KindAuthExpired,
+		},
+		{
+			name:       "file over the Bot API limit",
+			groups:     &fakeGroupRepo{group: testGroup()},
+			authorized: true,
+			media:      &media.MediaInfo{FileID: "file", FileSize: media.BotAPIConfig{}.MaxFileSize() + 1},
+			permanent:  true,
+			notified:   notifyDomain.KindFileTooBig,
+		},
+		{
+			name:       "imported file is gone",
+			groups:     &fakeGroupRepo{group: testGroup()},
+			authorized: true,
+			media:      &media.MediaInfo{LocalPath: filepath.Join(t.TempDir(), "missing.jpg")},
+			permanent:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, notifications := newTestUploadBot(tt.groups, tt.authorized)
+
+			err := b.ProcessUploadJob(testJob(tt.media))
+			if err == nil {
+				t.Fatal("err = nil, want an error")
+			}
+			if got := queueDomain.IsPermanent(err); got != tt.permanent {
+				t.Errorf("IsPermanent(%v) = %v, want %v", err, got, tt.permanent)
+			}
+
+			if tt.notified == "" && len(notifications.kinds) > 0 {
+				t.Errorf("notifications = %v, want none", notifications.kinds)
+			}
+			if tt.notified != "" && (len(notifications.kinds) != 1 || notifications.kinds[0] != tt.notified) {
+				t.Errorf("notifications = %v, want [%s]", notifications.kinds, tt.notified)
+			}
+		})
+	}
+}
+
+func TestProcessUploadJobSkips(t *testing.T) {
+	t.Run("sender opted out", func(t *testing.T) {
+		groups := &fakeGroupRepo{group: testGroup(), optedOut: true}
+		b, _ := newTestUploadBot(groups, true)
+
+		if err := b.ProcessUploadJob(testJob(&media.MediaInfo{FileID: "file", SenderID: 7})); err != nil {
+			t.Fatalf("err = %v, want nil", err)
+		}
+		if len(groups.dryRunFiles) > 0 {
+			t.Errorf("dry run files = %v, want none", groups.dryRunFiles)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		group := testGroup()
+		group.State = domain.StateDryRun
+		groups := &fakeGroupRepo{group: group}
+		b, _ := newTestUploadBot(groups, false)
+
+		if err := b.ProcessUploadJob(testJob(&media.MediaInfo{FileID: "file", FileUniqueID: "dry"})); err != nil {
+			t.Fatalf("err = %v, want nil", err)
+		}
+		if len(groups.dryRunFiles) != 1 || groups.dryRunFiles[0] != "dry" {
+			t.Errorf("dry run files = %v, want [dry]", groups.dryRunFiles)
+		}
+	})
+
+	t.Run("no media payload", func(t *testing.T) {
+		b, _ := newTestUploadBot(&fakeGroupRepo{}, true)
+
+		err := b.ProcessUploadJob(&queueDomain.UploadJob{ID: 1, GroupID: testGroupID})
+		if !queueDomain.IsPermanent(err) {
+			t.Fatalf("err = %v, want a permanent error", err)
+		}
+	})
+}
@@ -5,12 +5,14 @@ import (
 	"crypto/sha1"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mail_helper_bot/internal/pkg/http_client"
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -20,9 +22,17 @@ const (
 )
 
 type CloudService struct {
-	client *http_client.LoggedClient
+	client       *http_client.LoggedClient
+	uploadClient *http_client.LoggedClient
+
+	// Кеш папок, которые уже созданы или точно существуют, ключ - токен и путь
+	foldersMu sync.Mutex
+	folders   map[string]struct{}
 }
 
+// maxCachedFolders ограничивает размер кеша папок, при переполнении он сбрасывается
+const maxCachedFolders = 10000
+
 type CloudFolder struct {
 	Name      string
 	Path      string
@@ -61,7 +71,9 @@ type ShareResponse struct {
 func NewCloudService() *CloudService {
 	logServerURL := os.Getenv("LOG_SERVER_URL")
 	return &CloudService{
-		client: http_client.NewLoggedClient(logServerURL),
+		client:       http_client.NewLoggedClient(logServerURL),
+		uploadClient: http_client.NewLoggedStreamClient(logServerURL),
+		folders:      make(map[string]struct{}),
 	}
 }
 
@@ -79,31 +91,220 @@ func (cs *CloudService) CreateFolder(accessToken, folderPath string) error {
 
 	resp, err := cs.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to make request: %v", err)
+		return fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to create folder: status=%d, body=%s", resp.StatusCode, string(body))
+		return &APIError{Op: "create folder", StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return nil
 }
 
+// EnsureFolder создает папку folderPath вместе со всеми родительскими, как mkdir -p.
+// Уже существующие папки запоминаются и повторно не создаются.
+func (cs *CloudService) EnsureFolder(accessToken, folderPath string) error {
+	parts := strings.Split(strings.Trim(folderPath, "/"), "/")
+
+	current := ""
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		if current == "" {
+			current = part
+		} else {
+			current += "/" + part
+		}
+
+		if cs.isFolderCached(accessToken, current) {
+			continue
+		}
+
+		err := cs.CreateFolder(accessToken, current)
+		var apiErr *APIError
+		if err != nil && !(errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict) {
+			return err
+		}
+		cs.cacheFolder(accessToken, current)
+	}
+	return nil
+}
+
+// ForgetFolder убирает папку и вложенные в нее из кеша, например, если загрузка
+// в нее не удалась и папку могли удалить
+func (cs *CloudService) ForgetFolder(accessToken, folderPath string) {
+	prefix := folderKey(accessToken, strings.Trim(folderPath, "/"))
+
+	cs.foldersMu.Lock()
+	defer cs.foldersMu.Unlock()
+	for key := range cs.folders {
+		if key == prefix || strings.HasPrefix(key, prefix+"/") {
+			delete(cs.folders, key)
+		}
+	}
+}
+
+func (cs *CloudService) isFolderCached(accessToken, folderPath string) bool {
+	cs.foldersMu.Lock()
+	defer cs.foldersMu.Unlock()
+	_, ok := cs.folders[folderKey(accessToken, folderPath)]
+	return ok
+}
+
+func (cs *CloudService) cacheFolder(accessToken, folderPath string) {
+	cs.foldersMu.Lock()
+	defer cs.foldersMu.Unlock()
+	if len(cs.folders) >= maxCachedFolders {
+		cs.folders = make(map[string]struct{})
+	}
+	cs.folders[folderKey(accessToken, folderPath)] = struct{}{}
+}
+
+// folderKey - ключ кеша папок: папки разных аккаунтов не смешиваются
+func folderKey(accessToken, folderPath string) string {
+	return accessToken + "\x00" + folderPath
+}
+
+// UploadResult - хеш и размер загруженного содержимого
+type UploadResult struct {
+	Hash string
+	Size int64
+}
+
 // UploadFileFromBytes загружает файл в облако из байтового массива
 func (cs *CloudService) UploadFileFromBytes(accessToken string, fileData []byte, cloudPath string) error {
-	// Вычисляем хеш файла
+	_, err := cs.UploadFile(accessToken, bytes.NewReader(fileData), int64(len(fileData)), cloudPath)
+	return err
+}
+
+// UploadFile загружает файл в облако в два этапа: сначала содержимое потоком
+// отправляется на upload-эндпоинт, который возвращает хеш, затем файл с этим
+// хешем регистрируется по пути cloudPath через add. Файл целиком в памяти
+// не держится. size - ожидаемый размер файла или -1, если он неизвестен.
+func (cs *CloudService) UploadFile(accessToken string, reader io.Reader, size int64, cloudPath string) (*UploadResult, error) {
+	return cs.uploadFile(accessToken, reader, size, cloudPath, false)
+}
+
+// ReplaceFile загружает файл как UploadFile, но перезаписывает существующий файл по пути cloudPath
+func (cs *CloudService) ReplaceFile(accessToken string, reader io.Reader, size int64, cloudPath string) (*UploadResult, error) {
+	return cs.uploadFile(accessToken, reader, size, cloudPath, true)
+}
+
+func (cs *CloudService) uploadFile(accessToken string, reader io.Reader, size int64, cloudPath string, overwrite bool) (*UploadResult, error) {
+	fileHash, written, err := cs.uploadBlob(accessToken, reader, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cs.addFile(accessToken, fileHash, written, cloudPath, overwrite); err != nil {
+		return nil, err
+	}
+
+	return &UploadResult{Hash: fileHash, Size: written}, nil
+}
+
+// UploadContent передает содержимое в облако, не регистрируя его по пути.
+// Дальше файл добавляется через AddFileByHash.
+func (cs *CloudService) UploadContent(accessToken string, reader io.Reader, size int64) (*UploadResult, error) {
+	fileHash, written, err := cs.uploadBlob(accessToken, reader, size)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadResult{Hash: fileHash, Size: written}, nil
+}
+
+// ReplaceFileFromBytes загружает небольшой служебный файл, перезаписывая
+// существующий файл по пути cloudPath
+func (cs *CloudService) ReplaceFileFromBytes(accessToken string, fileData []byte, cloudPath string) error {
+	fileHash, written, err := cs.uploadBlob(accessToken, bytes.NewReader(fileData), int64(len(fileData)))
+	if err != nil {
+		return err
+	}
+	return cs.addFile(accessToken, fileHash, written, cloudPath, true)
+}
+
+// AddFileByHash регистрирует по пути cloudPath содержимое, которое уже есть
+// в облаке пользователя, без повторной передачи байт
+func (cs *CloudService) AddFileByHash(accessToken, fileHash string, size int64, cloudPath string) error {
+	return cs.addFile(accessToken, fileHash, size, cloudPath, false)
+}
+
+// ReplaceFileByHash регистрирует содержимое по хешу, перезаписывая существующий файл
+func (cs *CloudService) ReplaceFileByHash(accessToken, fileHash string, size int64, cloudPath string) error {
+	return cs.addFile(accessToken, fileHash, size, cloudPath, true)
+}
+
+// uploadBlob передает содержимое файла на upload-эндпоинт и возвращает хеш и размер.
+// Хеш считается локально во время передачи и сверяется с ответом сервера.
+func (cs *CloudService) uploadBlob(accessToken string, reader io.Reader, size int64) (string, int64, error) {
 	hasher := sha1.New()
-	hasher.Write(fileData)
+	body := &countingReader{reader: io.TeeReader(reader, hasher)}
+
+	url := fmt.Sprintf("%s/upload/", baseAPIURL)
+	req, err := http.NewRequest("PUT", url, body)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	// При неизвестной длине тело уходит chunked-кодированием
+	req.ContentLength = size
+	if size < 0 {
+		req.ContentLength = -1
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := cs.uploadClient.DoStream(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to upload file content: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", 0, &APIError{Op: "upload file content", StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	if size >= 0 && body.read != size {
+		return "", 0, fmt.Errorf("file size mismatch: expected=%d, got=%d", size, body.read)
+	}
+
 	fileHash := strings.ToUpper(hex.EncodeToString(hasher.Sum(nil)))
+	serverHash := strings.ToUpper(strings.TrimSpace(string(respBody)))
+	if serverHash != fileHash {
+		return "", 0, fmt.Errorf("file hash mismatch: local=%s, server=%s", fileHash, serverHash)
+	}
+
+	return fileHash, body.read, nil
+}
+
+// countingReader считает количество прочитанных байт
+type countingReader struct {
+	reader io.Reader
+	read   int64
+}
 
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	return n, err
+}
+
+// addFile регистрирует файл с известным хешем и размером по пути cloudPath.
+// Новые медиа не перезаписываются: коллизии имен разрешаются суффиксами до
+// загрузки, а если путь все же занят файлом, о котором бот не знает, - при ответе 409.
+func (cs *CloudService) addFile(accessToken, fileHash string, size int64, cloudPath string, overwrite bool) error {
 	// Подготавливаем данные для загрузки
 	uploadData := map[string]interface{}{
 		"hash":          fileHash,
-		"size":          len(fileData),
+		"size":          size,
 		"path":          cloudPath,
-		"overwrite":     true,
+		"overwrite":     overwrite,
 		"last_modified": time.Now().Unix(),
 	}
 
@@ -125,14 +326,40 @@ func (cs *CloudService) UploadFileFromBytes(accessToken string, fileData []byte,
 	// Выполняем запрос
 	resp, err := cs.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to upload file: %v", err)
+		return fmt.Errorf("failed to upload file: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("failed to upload file: status=%d, body=%s", resp.StatusCode, string(body))
+		return &APIError{Op: "upload file", StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return nil
+}
+
+// DeleteFile удаляет файл из облака. Отсутствующий файл ошибкой не считается:
+// результат тот же - файла по этому пути нет.
+func (cs *CloudService) DeleteFile(accessToken, cloudPath string) error {
+	url := fmt.Sprintf("%s/api/v1/private/remove/%s", baseAPIURL, strings.TrimPrefix(cloudPath, "/"))
+
+	req, err := http.NewRequest("POST", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := cs.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(resp.Body)
+		return &APIError{Op: "delete file", StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return nil
@@ -152,7 +379,7 @@ func (cs *CloudService) CreatePublicLink(accessToken, folderPath string) (string
 
 	resp, err := cs.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to make request: %v", err)
+		return "", fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -162,7 +389,7 @@ func (cs *CloudService) CreatePublicLink(accessToken, folderPath string) (string
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to create public link: status=%d, body=%s", resp.StatusCode, string(body))
+		return "", &APIError{Op: "create public link", StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var shareResp ShareResponse
@@ -170,8 +397,6 @@ func (cs *CloudService) CreatePublicLink(accessToken, folderPath string) (string
 		return "", fmt.Errorf("failed to parse response: %v", err)
 	}
 
-	fmt.Printf("Responce: %s", shareResp)
-
 	return shareResp.URL, nil // Используем URL напрямую, а не shareResp.Body.Url
 }
 
@@ -188,13 +413,13 @@ func (cs *CloudService) RemovePublicLink(accessToken, folderPath string) error {
 
 	resp, err := cs.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to make request: %v", err)
+		return fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to remove public link: status=%d, body=%s", resp.StatusCode, string(body))
+		return &APIError{Op: "remove public link", StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return nil
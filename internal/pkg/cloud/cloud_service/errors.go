@@ -0,0 +1,46 @@
+package cloud_service
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// APIError - ответ облака с неуспешным HTTP-статусом
+type APIError struct {
+	Op         string
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("failed to %s: status=%d, body=%s", e.Op, e.StatusCode, e.Body)
+}
+
+// Temporary сообщает, имеет ли смысл повторить запрос позже:
+// перегрузка, лимиты и ошибки на стороне сервера проходят сами,
+// остальные 4xx без изменения запроса не исправить
+func (e *APIError) Temporary() bool {
+	return e.StatusCode >= http.StatusInternalServerError ||
+		e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode == http.StatusRequestTimeout
+}
+
+// Conflict сообщает, что по пути уже лежит другой файл
+func (e *APIError) Conflict() bool {
+	return e.StatusCode == http.StatusConflict
+}
+
+// Unauthorized сообщает, что облако отклонило access token
+func (e *APIError) Unauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized
+}
+
+// QuotaExceeded сообщает, что в облаке закончилось место
+func (e *APIError) QuotaExceeded() bool {
+	return e.StatusCode == http.StatusInsufficientStorage
+}
+
+// NotFound сообщает, что путь не найден: например, папку удалили из облака
+func (e *APIError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
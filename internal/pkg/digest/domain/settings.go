@@ -0,0 +1,168 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Частота сводки
+const (
+	FrequencyOff    = "off"
+	FrequencyDaily  = "daily"
+	FrequencyWeekly = "weekly"
+)
+
+// DefaultTimezone - часовой пояс, пока владелец не выбрал свой
+const DefaultTimezone = "Europe/Moscow"
+
+// DefaultHour - час отправки сводки по умолчанию
+const DefaultHour = 9
+
+// Settings - расписание сводки владельца по его группам
+type Settings struct {
+	ChatID     int64
+	Frequency  string
+	Hour       int
+	Weekday    time.Weekday
+	Timezone   string
+	LastSentAt *time.Time
+}
+
+// NewSettings возвращает выключенную сводку с расписанием по умолчанию
+func NewSettings(chatID int64) *Settings {
+	return &Settings{
+		ChatID:    chatID,
+		Frequency: FrequencyOff,
+		Hour:      DefaultHour,
+		Weekday:   time.Monday,
+		Timezone:  DefaultTimezone,
+	}
+}
+
+// Enabled сообщает, включена ли сводка
+func (s *Settings) Enabled() bool {
+	return s.Frequency == FrequencyDaily || s.Frequency == FrequencyWeekly
+}
+
+// Period возвращает, за какой срок собирается сводка
+func (s *Settings) Period() time.Duration {
+	if s.Frequency == FrequencyWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// Location возвращает часовой пояс владельца. Сохраненный пояс проверен при
+// настройке, UTC - на случай, если база поясов пропала.
+func (s *Settings) Location() *time.Location {
+	loc, err := ParseTimezone(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// LastScheduled возвращает последнее по расписанию время отправки не позже now
+func (s *Settings) LastScheduled(now time.Time) time.Time {
+	local := now.In(s.Location())
+	scheduled := time.Date(local.Year(), local.Month(), local.Day(), s.Hour, 0, 0, 0, local.Location())
+
+	if s.Frequency == FrequencyWeekly {
+		scheduled = scheduled.AddDate(0, 0, -int((local.Weekday()-s.Weekday+7)%7))
+		if scheduled.After(local) {
+			scheduled = scheduled.AddDate(0, 0, -7)
+		}
+		return scheduled
+	}
+
+	if scheduled.After(local) {
+		scheduled = scheduled.AddDate(0, 0, -1)
+	}
+	return scheduled
+}
+
+// NextScheduled возвращает ближайшее время отправки после now
+func (s *Settings) NextScheduled(now time.Time) time.Time {
+	if s.Frequency == FrequencyWeekly {
+		return s.LastScheduled(now).AddDate(0, 0, 7)
+	}
+	return s.LastScheduled(now).AddDate(0, 0, 1)
+}
+
+// Due сообщает, пора ли отправить сводку: время по расписанию прошло, а
+// сводка за него еще не отправлялась. Пропущенные, пока бот не работал,
+// сводки не догоняются - отправляется только последняя.
+func (s *Settings) Due(now time.Time) bool {
+	if !s.Enabled() {
+		return false
+	}
+	if s.LastSentAt == nil {
+		return true
+	}
+	return s.LastSentAt.Before(s.LastScheduled(now))
+}
+
+// ParseTimezone принимает имя пояса IANA (Europe/Moscow) или смещение от UTC:
+// UTC+3, +3, -5:30
+func ParseTimezone(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	offset := strings.TrimPrefix(strings.TrimPrefix(strings.ToUpper(name), "UTC"), "GMT")
+	if offset == "" {
+		return time.UTC, nil
+	}
+	if offset[0] != '+' && offset[0] != '-' {
+		return time.LoadLocation(name)
+	}
+
+	sign := 1
+	if offset[0] == '-' {
+		sign = -1
+	}
+	hoursPart, minutesPart, hasMinutes := strings.Cut(offset[1:], ":")
+	// strconv.Atoi принимает свой знак, поэтому UTC--5 отсекается заранее
+	if !isDigits(hoursPart) || hasMinutes && !isDigits(minutesPart) {
+		return nil, fmt.Errorf("invalid UTC offset %q", name)
+	}
+	hours, err := strconv.Atoi(hoursPart)
+	if err != nil || hours > 14 {
+		return nil, fmt.Errorf("invalid UTC offset %q", name)
+	}
+	minutes := 0
+	if hasMinutes {
+		minutes, err = strconv.Atoi(minutesPart)
+		if err != nil || minutes >= 60 {
+			return nil, fmt.Errorf("invalid UTC offset %q", name)
+		}
+	}
+
+	seconds := sign * (hours*3600 + minutes*60)
+	return time.FixedZone(FormatOffset(seconds), seconds), nil
+}
+
+// isDigits сообщает, состоит ли строка только из цифр
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatOffset записывает смещение от UTC в том виде, в каком его принимает ParseTimezone
+func FormatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	if minutes := seconds % 3600 / 60; minutes != 0 {
+		return fmt.Sprintf("UTC%s%d:%02d", sign, seconds/3600, minutes)
+	}
+	return fmt.Sprintf("UTC%s%d", sign, seconds/3600)
+}
@@ -0,0 +1,191 @@
+package domain
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q): %v", name, err)
+	}
+	return loc
+}
+
+func TestLastScheduled(t *testing.T) {
+	moscow := mustLocation(t, "Europe/Moscow")
+	berlin := mustLocation(t, "Europe/Berlin")
+
+	tests := []struct {
+		name      string
+		settings  Settings
+		now       time.Time
+		want      time.Time
+		wantAfter time.Time
+	}{
+		{
+			name:      "daily before send hour",
+			settings:  Settings{Frequency: FrequencyDaily, Hour: 9, Timezone: "Europe/Moscow"},
+			now:       time.Date(2024, 1, 15, 8, 59, 0, 0, moscow),
+			want:      time.Date(2024, 1, 14, 9, 0, 0, 0, moscow),
+			wantAfter: time.Date(2024, 1, 15, 9, 0, 0, 0, moscow),
+		},
+		{
+			name:      "daily at send hour",
+			settings:  Settings{Frequency: FrequencyDaily, Hour: 9, Timezone: "Europe/Moscow"},
+			now:       time.Date(2024, 1, 15, 9, 0, 0, 0, moscow),
+			want:      time.Date(2024, 1, 15, 9, 0, 0, 0, moscow),
+			wantAfter: time.Date(2024, 1, 16, 9, 0, 0, 0, moscow),
+		},
+		{
+			name:      "weekly same weekday before send hour",
+			settings:  Settings{Frequency: FrequencyWeekly, Hour: 9, Weekday: time.Monday, Timezone: "Europe/Moscow"},
+			now:       time.Date(2024, 1, 15, 8, 0, 0, 0, moscow),
+			want:      time.Date(2024, 1, 8, 9, 0, 0, 0, moscow),
+			wantAfter: time.Date(2024, 1, 15, 9, 0, 0, 0, moscow),
+		},
+		{
+			name:      "weekly same weekday after send hour",
+			settings:  Settings{Frequency: FrequencyWeekly, Hour: 9, Weekday: time.Monday, Timezone: "Europe/Moscow"},
+			now:       time.Date(2024, 1, 15, 10, 0, 0, 0, moscow),
+			want:      time.Date(2024, 1, 15, 9, 0, 0, 0, moscow),
+			wantAfter: time.Date(2024, 1, 22, 9, 0, 0, 0, moscow),
+		},
+		{
+			name:      "weekly other weekday",
+			settings:  Settings{Frequency: FrequencyWeekly, Hour: 9, Weekday: time.Friday, Timezone: "Europe/Moscow"},
+			now:       time.Date(2024, 1, 15, 10, 0, 0, 0, moscow),
+			want:      time.Date(2024, 1, 12, 9, 0, 0, 0, moscow),
+			wantAfter: time.Date(2024, 1, 19, 9, 0, 0, 0, moscow),
+		},
+		{
+			// Переход на летнее время 31 марта: между отправками 23 часа
+			name:      "daily across DST start",
+			settings:  Settings{Frequency: FrequencyDaily, Hour: 9, Timezone: "Europe/Berlin"},
+			now:       time.Date(2024, 3, 30, 10, 0, 0, 0, berlin),
+			want:      time.Date(2024, 3, 30, 9, 0, 0, 0, berlin),
+			wantAfter: time.Date(2024, 3, 31, 9, 0, 0, 0, berlin),
+		},
+		{
+			name:      "weekly across DST end",
+			settings:  Settings{Frequency: FrequencyWeekly, Hour: 9, Weekday: time.Sunday, Timezone: "Europe/Berlin"},
+			now:       time.Date(2024, 10, 27, 8, 30, 0, 0, berlin),
+			want:      time.Date(2024, 10, 20, 9, 0, 0, 0, berlin),
+			wantAfter: time.Date(2024, 10, 27, 9, 0, 0, 0, berlin),
+		},
+		{
+			name:      "fixed offset",
+			settings:  Settings{Frequency: FrequencyDaily, Hour: 9, Timezone: "UTC-5"},
+			now:       time.Date(2024, 1, 15, 13, 0, 0, 0, time.UTC),
+			want:      time.Date(2024, 1, 14, 14, 0, 0, 0, time.UTC),
+			wantAfter: time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.settings.LastScheduled(tt.now); !got.Equal(tt.want) {
+				t.Errorf("LastScheduled = %v, want %v", got, tt.want)
+			}
+			if got := tt.settings.NextScheduled(tt.now); !got.Equal(tt.wantAfter) {
+				t.Errorf("NextScheduled = %v, want %v", got, tt.wantAfter)
+			}
+		})
+	}
+}
+
+func TestDue(t *testing.T) {
+	moscow := mustLocation(t, "Europe/Moscow")
+	scheduled := time.Date(2024, 1, 15, 9, 0, 0, 0, moscow)
+	now := scheduled.Add(30 * time.Minute)
+
+	at := func(d time.Duration) *time.Time {
+		sent := scheduled.Add(d)
+		return &sent
+	}
+
+	tests := []struct {
+		name       string
+		frequency  string
+		lastSentAt *time.Time
+		want       bool
+	}{
+		{"never sent", FrequencyDaily, nil, true},
+		{"sent just before schedule", FrequencyDaily, at(-time.Second), true},
+		{"sent at schedule", FrequencyDaily, at(0), false},
+		{"sent just after schedule", FrequencyDaily, at(time.Second), false},
+		{"sent a day ago", FrequencyDaily, at(-24 * time.Hour), true},
+		{"off", FrequencyOff, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Settings{Frequency: tt.frequency, Hour: 9, Timezone: "Europe/Moscow", LastSentAt: tt.lastSentAt}
+			if got := s.Due(now); got != tt.want {
+				t.Fatalf("Due = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTimezone(t *testing.T) {
+	tests := []struct {
+		input      string
+		wantName   string
+		wantOffset int
+		wantErr    bool
+	}{
+		{"Europe/Moscow", "Europe/Moscow", 3 * 3600, false},
+		{"UTC", "UTC", 0, false},
+		{"UTC+3", "UTC+3", 3 * 3600, false},
+		{"utc+3", "UTC+3", 3 * 3600, false},
+		{"GMT-5", "UTC-5", -5 * 3600, false},
+		{"+3", "UTC+3", 3 * 3600, false},
+		{" -5:30 ", "UTC-5:30", -(5*3600 + 30*60), false},
+		{"UTC+5:45", "UTC+5:45", 5*3600 + 45*60, false},
+		{"UTC+14", "UTC+14", 14 * 3600, false},
+		{"UTC+15", "", 0, true},
+		{"UTC--5", "", 0, true},
+		{"UTC+-5", "", 0, true},
+		{"UTC-+5", "", 0, true},
+		{"UTC+5:-30", "", 0, true},
+		{"UTC+5:60", "", 0, true},
+		{"UTC+", "", 0, true},
+		{"UTC+5:", "", 0, true},
+		{"UTC+abc", "", 0, true},
+		{"Mars/Olympus", "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			loc, err := ParseTimezone(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseTimezone(%q) = %v, want error", tt.input, loc)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTimezone(%q): %v", tt.input, err)
+			}
+			if loc.String() != tt.wantName {
+				t.Errorf("name = %q, want %q", loc.String(), tt.wantName)
+			}
+			if _, offset := time.Date(2024, 1, 15, 12, 0, 0, 0, loc).Zone(); offset != tt.wantOffset {
+				t.Errorf("offset = %d, want %d", offset, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestFormatOffsetRoundTrip(t *testing.T) {
+	for _, seconds := range []int{0, 3 * 3600, -5 * 3600, 5*3600 + 30*60, -(9*3600 + 30*60)} {
+		loc, err := ParseTimezone(FormatOffset(seconds))
+		if err != nil {
+			t.Fatalf("ParseTimezone(%q): %v", FormatOffset(seconds), err)
+		}
+		if _, offset := time.Date(2024, 1, 15, 12, 0, 0, 0, loc).Zone(); offset != seconds {
+			t.Errorf("offset of %q = %d, want %d", FormatOffset(seconds), offset, seconds)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"mail_helper_bot/internal/pkg/digest/domain"
+	"time"
+)
+
+type DigestRepository interface {
+	// GetSettings возвращает расписание сводки пользователя или nil, если он его не настраивал
+	GetSettings(chatID int64) (*domain.Settings, error)
+	SaveSettings(settings *domain.Settings) error
+	// GetEnabledSettings возвращает расписания всех включенных сводок
+	GetEnabledSettings() ([]*domain.Settings, error)
+	MarkSent(chatID int64, sentAt time.Time) error
+}
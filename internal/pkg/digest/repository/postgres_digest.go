@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"database/sql"
+	"mail_helper_bot/internal/pkg/digest/domain"
+	"time"
+)
+
+const settingsColumns = `chat_id, frequency, send_hour, weekday, timezone, last_sent_at`
+
+type DigestStorage struct {
+	db *sql.DB
+}
+
+func NewDigestStorage(db *sql.DB) *DigestStorage {
+	return &DigestStorage{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSettings(row rowScanner) (*domain.Settings, error) {
+	s := &domain.Settings{}
+	var weekday int
+	var lastSentAt sql.NullTime
+	if err := row.Scan(&s.ChatID, &s.Frequency, &s.Hour, &weekday, &s.Timezone, &lastSentAt); err != nil {
+		return nil, err
+	}
+	s.Weekday = time.Weekday(weekday)
+	if lastSentAt.Valid {
+		s.LastSentAt = &lastSentAt.Time
+	}
+	return s, nil
+}
+
+func (d *DigestStorage) GetSettings(chatID int64) (*domain.Settings, error) {
+	s, err := scanSettings(d.db.QueryRow(`
+        SELECT `+settingsColumns+`
+        FROM user_digest_settings
+        WHERE chat_id = $1
+    `, chatID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+func (d *DigestStorage) SaveSettings(settings *domain.Settings) error {
+	_, err := d.db.Exec(`
+        INSERT INTO user_digest_settings (chat_id, frequency, send_hour, weekday, timezone, last_sent_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (chat_id) DO UPDATE
+        SET frequency = EXCLUDED.frequency,
+            send_hour = EXCLUDED.send_hour,
+            weekday = EXCLUDED.weekday,
+            timezone = EXCLUDED.timezone,
+            last_sent_at = EXCLUDED.last_sent_at
+    `, settings.ChatID, settings.Frequency, settings.Hour, int(settings.Weekday), settings.Timezone, settings.LastSentAt)
+	return err
+}
+
+func (d *DigestStorage) GetEnabledSettings() ([]*domain.Settings, error) {
+	rows, err := d.db.Query(`
+        SELECT ` + settingsColumns + `
+        FROM user_digest_settings
+        WHERE frequency IN ('daily', 'weekly')
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var settings []*domain.Settings
+	for rows.Next() {
+		s, err := scanSettings(rows)
+		if err != nil {
+			return nil, err
+		}
+		settings = append(settings, s)
+	}
+	return settings, rows.Err()
+}
+
+func (d *DigestStorage) MarkSent(chatID int64, sentAt time.Time) error {
+	_, err := d.db.Exec(`
+        UPDATE user_digest_settings SET last_sent_at = $2 WHERE chat_id = $1
+    `, chatID, sentAt)
+	return err
+}
@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"log"
+	"mail_helper_bot/internal/pkg/digest/domain"
+	"mail_helper_bot/internal/pkg/digest/repository"
+	"sync"
+	"time"
+)
+
+// checkInterval - как часто проверяется расписание. Сводки отправляются
+// в начале часа, минутной точности достаточно.
+const checkInterval = time.Minute
+
+// Handler собирает и отправляет сводку владельцу
+type Handler func(settings *domain.Settings, now time.Time) error
+
+// Scheduler - фоновая горутина, отправляющая сводки по расписанию владельцев
+type Scheduler struct {
+	repo    repository.DigestRepository
+	handler Handler
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewScheduler(repo repository.DigestRepository, handler Handler) *Scheduler {
+	return &Scheduler{
+		repo:    repo,
+		handler: handler,
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start запускает проверку расписания
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go s.run()
+	log.Printf("Digest scheduler started")
+}
+
+// Stop дожидается отправки текущих сводок и останавливает планировщик
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+func (s *Scheduler) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		s.sendDue(time.Now())
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDue отправляет сводки, время которых наступило. Неудачная сводка
+// не отмечается отправленной и повторяется на следующей проверке.
+func (s *Scheduler) sendDue(now time.Time) {
+	settings, err := s.repo.GetEnabledSettings()
+	if err != nil {
+		log.Printf("Error getting digest settings: %v", err)
+		return
+	}
+
+	for _, userSettings := range settings {
+		if !userSettings.Due(now) {
+			continue
+		}
+
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+
+		if err := s.handler(userSettings, now); err != nil {
+			log.Printf("Error sending digest to %d: %v", userSettings.ChatID, err)
+			continue
+		}
+		if err := s.repo.MarkSent(userSettings.ChatID, now); err != nil {
+			log.Printf("Error marking digest of %d sent: %v", userSettings.ChatID, err)
+		}
+	}
+}
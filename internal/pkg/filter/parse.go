@@ -0,0 +1,142 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Fields - настраиваемые поля правил и их описание для команды /filters
+var Fields = []struct {
+	Name        string
+	Description string
+}{
+	{"include_mime", "разрешенные MIME-типы: image/* video/mp4"},
+	{"exclude_mime", "исключенные MIME-типы"},
+	{"include_ext", "разрешенные расширения: jpg png"},
+	{"exclude_ext", "исключенные расширения"},
+	{"min_size", "минимальный размер: 10KB"},
+	{"max_size", "максимальный размер: 50MB"},
+	{"include_sender", "разрешенные отправители: ID или @username"},
+	{"exclude_sender", "исключенные отправители"},
+	{"caption_include", "регулярное выражение, под которое должна подходить подпись"},
+	{"caption_exclude", "регулярное выражение для исключения по подписи"},
+}
+
+// Set заменяет значение поля правил. Пустые args очищают поле.
+func (r *Rules) Set(field string, args []string) error {
+	switch field {
+	case "include_mime":
+		r.IncludeMIME = normalizeList(args, "")
+	case "exclude_mime":
+		r.ExcludeMIME = normalizeList(args, "")
+	case "include_ext":
+		r.IncludeExt = normalizeList(args, ".")
+	case "exclude_ext":
+		r.ExcludeExt = normalizeList(args, ".")
+	case "include_sender":
+		r.IncludeSenders = args
+	case "exclude_sender":
+		r.ExcludeSenders = args
+	case "min_size", "max_size":
+		var size int64
+		if len(args) > 0 {
+			var err error
+			if size, err = ParseSize(args[0]); err != nil {
+				return err
+			}
+		}
+		if field == "min_size" {
+			r.MinSize = size
+		} else {
+			r.MaxSize = size
+		}
+	case "caption_include":
+		r.CaptionInclude = strings.Join(args, " ")
+	case "caption_exclude":
+		r.CaptionExclude = strings.Join(args, " ")
+	default:
+		return fmt.Errorf("неизвестное поле %s", field)
+	}
+	return r.Validate()
+}
+
+// Describe возвращает правила построчно для показа пользователю
+func (r *Rules) Describe() []string {
+	var lines []string
+	add := func(title string, values []string) {
+		if len(values) > 0 {
+			lines = append(lines, fmt.Sprintf("%s: %s", title, strings.Join(values, ", ")))
+		}
+	}
+
+	add("✅ MIME", r.IncludeMIME)
+	add("🚫 MIME", r.ExcludeMIME)
+	add("✅ Расширения", r.IncludeExt)
+	add("🚫 Расширения", r.ExcludeExt)
+	if r.MinSize > 0 {
+		lines = append(lines, "📏 Не меньше "+FormatSize(r.MinSize))
+	}
+	if r.MaxSize > 0 {
+		lines = append(lines, "📏 Не больше "+FormatSize(r.MaxSize))
+	}
+	add("✅ Отправители", r.IncludeSenders)
+	add("🚫 Отправители", r.ExcludeSenders)
+	if r.CaptionInclude != "" {
+		lines = append(lines, fmt.Sprintf("✅ Подпись: /%s/", r.CaptionInclude))
+	}
+	if r.CaptionExclude != "" {
+		lines = append(lines, fmt.Sprintf("🚫 Подпись: /%s/", r.CaptionExclude))
+	}
+	return lines
+}
+
+var sizeUnits = []struct {
+	Suffix string
+	Bytes  int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize разбирает размер вида 500, 10KB, 1.5MB, 2GB
+func ParseSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(value, unit.Suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.Suffix))
+			multiplier = unit.Bytes
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("некорректный размер %q, пример: 10KB, 50MB", value)
+	}
+	return int64(number * float64(multiplier)), nil
+}
+
+// FormatSize показывает размер в самых крупных целых единицах
+func FormatSize(size int64) string {
+	for _, unit := range sizeUnits {
+		if size >= unit.Bytes && size%unit.Bytes == 0 {
+			return fmt.Sprintf("%d%s", size/unit.Bytes, unit.Suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", size)
+}
+
+func normalizeList(values []string, trimPrefix string) []string {
+	var result []string
+	for _, v := range values {
+		v = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(v), trimPrefix))
+		if v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
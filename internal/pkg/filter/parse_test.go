@@ -0,0 +1,92 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"500", 500, false},
+		{"500B", 500, false},
+		{"10KB", 10 << 10, false},
+		{"10 kb", 10 << 10, false},
+		{"1.5MB", 3 << 19, false},
+		{"2GB", 2 << 30, false},
+		{"", 0, true},
+		{"MB", 0, true},
+		{"-1KB", 0, true},
+		{"ten", 0, true},
+		{"10TB", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseSize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSize(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseSize(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		0:        "0B",
+		500:      "500B",
+		10 << 10: "10KB",
+		3 << 19:  "1536KB",
+		2 << 30:  "2GB",
+	}
+	for size, want := range tests {
+		if got := FormatSize(size); got != want {
+			t.Errorf("FormatSize(%d) = %q, want %q", size, got, want)
+		}
+	}
+}
+
+func TestSetErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		prior Rules
+		field string
+		args  []string
+	}{
+		{"unknown field", Rules{}, "include_color", []string{"red"}},
+		{"bad size", Rules{}, "max_size", []string{"big"}},
+		{"min above max", Rules{MaxSize: 1 << 20}, "min_size", []string{"2MB"}},
+		{"max below min", Rules{MinSize: 2 << 20}, "max_size", []string{"1MB"}},
+		{"bad caption regexp", Rules{}, "caption_include", []string{"(unclosed"}},
+		{"bad exclude regexp", Rules{}, "caption_exclude", []string{"[a-"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := tt.prior
+			if err := rules.Set(tt.field, tt.args); err == nil {
+				t.Fatalf("Set(%q, %q) succeeded, want error", tt.field, tt.args)
+			}
+		})
+	}
+}
+
+func TestSetNormalizes(t *testing.T) {
+	var rules Rules
+	if err := rules.Set("include_ext", []string{".JPG", " png ", ""}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if len(rules.IncludeExt) != 2 || rules.IncludeExt[0] != "jpg" || rules.IncludeExt[1] != "png" {
+		t.Fatalf("IncludeExt = %q", rules.IncludeExt)
+	}
+
+	if err := rules.Set("include_ext", nil); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if !rules.IsEmpty() {
+		t.Fatalf("rules are not empty after clearing: %+v", rules)
+	}
+}
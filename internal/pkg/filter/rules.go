@@ -0,0 +1,200 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rules - правила отбора медиа группы. Пустые списки и нулевые лимиты не
+// ограничивают ничего. Хранятся в group_sessions.filter_rules как JSON.
+type Rules struct {
+	IncludeMIME    []string `json:"include_mime,omitempty"` // image/*, video/mp4
+	ExcludeMIME    []string `json:"exclude_mime,omitempty"`
+	IncludeExt     []string `json:"include_ext,omitempty"` // Без точки, в нижнем регистре
+	ExcludeExt     []string `json:"exclude_ext,omitempty"`
+	MinSize        int64    `json:"min_size,omitempty"` // Байты
+	MaxSize        int64    `json:"max_size,omitempty"`
+	IncludeSenders []string `json:"include_senders,omitempty"` // ID пользователя или @username
+	ExcludeSenders []string `json:"exclude_senders,omitempty"`
+	CaptionInclude string   `json:"caption_include,omitempty"` // Регулярное выражение
+	CaptionExclude string   `json:"caption_exclude,omitempty"`
+
+	// Выражения подписи компилируются один раз при разборе и загрузке правил
+	captionInclude *regexp.Regexp
+	captionExclude *regexp.Regexp
+}
+
+// Input - данные медиа, по которым принимается решение
+type Input struct {
+	MimeType       string
+	Ext            string
+	Size           int64 // 0 - размер неизвестен, лимиты не проверяются
+	SenderID       int64
+	SenderUsername string
+	Caption        string
+}
+
+// Decision - решение фильтра и его причина
+type Decision struct {
+	Allowed bool
+	Reason  string
+}
+
+func allow() Decision {
+	return Decision{Allowed: true, Reason: "подходит под правила"}
+}
+
+func deny(format string, args ...interface{}) Decision {
+	return Decision{Allowed: false, Reason: fmt.Sprintf(format, args...)}
+}
+
+// IsEmpty сообщает, что правила ничего не ограничивают
+func (r *Rules) IsEmpty() bool {
+	return len(r.IncludeMIME) == 0 && len(r.ExcludeMIME) == 0 &&
+		len(r.IncludeExt) == 0 && len(r.ExcludeExt) == 0 &&
+		r.MinSize == 0 && r.MaxSize == 0 &&
+		len(r.IncludeSenders) == 0 && len(r.ExcludeSenders) == 0 &&
+		r.CaptionInclude == "" && r.CaptionExclude == ""
+}
+
+// UnmarshalJSON загружает правила из JSON и сразу компилирует выражения подписи.
+// Некорректное выражение из базы не мешает загрузке: оно ни с чем не совпадает.
+func (r *Rules) UnmarshalJSON(data []byte) error {
+	type plain Rules
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+	r.captionInclude, _ = compileCaption(r.CaptionInclude)
+	r.captionExclude, _ = compileCaption(r.CaptionExclude)
+	return nil
+}
+
+// Validate компилирует регулярные выражения и проверяет лимиты размера
+func (r *Rules) Validate() error {
+	var err error
+	if r.captionInclude, err = compileCaption(r.CaptionInclude); err != nil {
+		return err
+	}
+	if r.captionExclude, err = compileCaption(r.CaptionExclude); err != nil {
+		return err
+	}
+	if r.MinSize < 0 || r.MaxSize < 0 {
+		return fmt.Errorf("размер не может быть отрицательным")
+	}
+	if r.MaxSize > 0 && r.MinSize > r.MaxSize {
+		return fmt.Errorf("минимальный размер больше максимального")
+	}
+	return nil
+}
+
+// Evaluate решает, загружать ли медиа. Сначала проверяются исключения,
+// затем лимиты размера, затем списки разрешенного.
+func (r *Rules) Evaluate(in Input) Decision {
+	mimeType := strings.ToLower(in.MimeType)
+	ext := strings.ToLower(in.Ext)
+
+	if pattern, ok := matchMIME(r.ExcludeMIME, mimeType); ok {
+		return deny("MIME %s исключен правилом %s", mimeType, pattern)
+	}
+	if containsFold(r.ExcludeExt, ext) {
+		return deny("расширение .%s исключено", ext)
+	}
+	if sender, ok := matchSender(r.ExcludeSenders, in); ok {
+		return deny("отправитель %s исключен", sender)
+	}
+	if r.CaptionExclude != "" && matchCaption(&r.captionExclude, r.CaptionExclude, in.Caption) {
+		return deny("подпись подходит под исключающее выражение %q", r.CaptionExclude)
+	}
+
+	if in.Size > 0 && r.MinSize > 0 && in.Size < r.MinSize {
+		return deny("размер %d байт меньше минимума %d", in.Size, r.MinSize)
+	}
+	if in.Size > 0 && r.MaxSize > 0 && in.Size > r.MaxSize {
+		return deny("размер %d байт больше максимума %d", in.Size, r.MaxSize)
+	}
+
+	if len(r.IncludeMIME) > 0 {
+		if _, ok := matchMIME(r.IncludeMIME, mimeType); !ok {
+			return deny("MIME %q нет в списке разрешенных", mimeType)
+		}
+	}
+	if len(r.IncludeExt) > 0 && !containsFold(r.IncludeExt, ext) {
+		return deny("расширения .%s нет в списке разрешенных", ext)
+	}
+	if len(r.IncludeSenders) > 0 {
+		if _, ok := matchSender(r.IncludeSenders, in); !ok {
+			return deny("отправителя %d нет в списке разрешенных", in.SenderID)
+		}
+	}
+	if r.CaptionInclude != "" && !matchCaption(&r.captionInclude, r.CaptionInclude, in.Caption) {
+		return deny("подпись не подходит под выражение %q", r.CaptionInclude)
+	}
+
+	return allow()
+}
+
+// matchMIME ищет шаблон, под который подходит MIME-тип. Шаблон type/* подходит
+// под все подтипы.
+func matchMIME(patterns []string, mimeType string) (string, bool) {
+	for _, p := range patterns {
+		p = strings.ToLower(p)
+		if p == mimeType || (strings.HasSuffix(p, "/*") && strings.HasPrefix(mimeType, strings.TrimSuffix(p, "*"))) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// matchSender ищет отправителя в списке ID и @username
+func matchSender(senders []string, in Input) (string, bool) {
+	for _, s := range senders {
+		if strings.HasPrefix(s, "@") {
+			if in.SenderUsername != "" && strings.EqualFold(s[1:], in.SenderUsername) {
+				return s, true
+			}
+			continue
+		}
+		if id, err := strconv.ParseInt(s, 10, 64); err == nil && id == in.SenderID {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// compileCaption компилирует выражение подписи; пустое выражение дает nil
+func compileCaption(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("некорректное регулярное выражение %q: %v", expr, err)
+	}
+	return re, nil
+}
+
+// matchCaption проверяет подпись скомпилированным выражением. Если правила
+// собраны без Validate или поле поменяли напрямую, выражение компилируется
+// и запоминается; некорректное выражение не совпадает ни с чем.
+func matchCaption(compiled **regexp.Regexp, expr, caption string) bool {
+	if *compiled == nil || (*compiled).String() != expr {
+		re, err := compileCaption(expr)
+		if err != nil {
+			return false
+		}
+		*compiled = re
+	}
+	return (*compiled).MatchString(caption)
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimPrefix(v, "."), value) {
+			return true
+		}
+	}
+	return false
+}
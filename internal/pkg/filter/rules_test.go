@@ -0,0 +1,110 @@
+package filter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	photo := Input{MimeType: "image/jpeg", Ext: "jpg", Size: 2 << 20, SenderID: 42, SenderUsername: "ivan", Caption: "море #отпуск"}
+	with := func(change func(in *Input)) Input {
+		in := photo
+		change(&in)
+		return in
+	}
+
+	tests := []struct {
+		name  string
+		rules Rules
+		input Input
+		want  bool
+	}{
+		{"empty rules allow everything", Rules{}, photo, true},
+
+		// Исключения сильнее разрешений
+		{"excluded mime wins over included", Rules{IncludeMIME: []string{"image/*"}, ExcludeMIME: []string{"image/jpeg"}}, photo, false},
+		{"excluded ext wins over included mime", Rules{IncludeMIME: []string{"image/*"}, ExcludeExt: []string{"jpg"}}, photo, false},
+		{"excluded sender wins over included", Rules{IncludeSenders: []string{"42"}, ExcludeSenders: []string{"@Ivan"}}, photo, false},
+		{"excluded caption wins over included", Rules{CaptionInclude: "отпуск", CaptionExclude: "море"}, photo, false},
+		{"exclusion wins over size", Rules{ExcludeMIME: []string{"image/*"}, MaxSize: 10 << 20}, photo, false},
+
+		// Лимиты размера
+		{"below min size", Rules{MinSize: 3 << 20}, photo, false},
+		{"above max size", Rules{MaxSize: 1 << 20}, photo, false},
+		{"unknown size skips limits", Rules{MinSize: 3 << 20}, with(func(in *Input) { in.Size = 0 }), true},
+		{"size limit before include list", Rules{IncludeMIME: []string{"image/jpeg"}, MaxSize: 1 << 20}, photo, false},
+
+		// Списки разрешенного
+		{"mime wildcard", Rules{IncludeMIME: []string{"image/*"}}, photo, true},
+		{"mime wildcard is not a prefix", Rules{IncludeMIME: []string{"image/*"}}, with(func(in *Input) { in.MimeType = "imagex/jpeg" }), false},
+		{"mime case folding", Rules{IncludeMIME: []string{"IMAGE/JPEG"}}, with(func(in *Input) { in.MimeType = "Image/JPEG" }), true},
+		{"mime not included", Rules{IncludeMIME: []string{"video/*"}}, photo, false},
+		{"ext included", Rules{IncludeExt: []string{"png", "jpg"}}, with(func(in *Input) { in.Ext = "JPG" }), true},
+		{"ext not included", Rules{IncludeExt: []string{"png"}}, photo, false},
+		{"sender by id", Rules{IncludeSenders: []string{"42"}}, photo, true},
+		{"sender by username", Rules{IncludeSenders: []string{"@IVAN"}}, photo, true},
+		{"sender not included", Rules{IncludeSenders: []string{"7", "@petr"}}, photo, false},
+		{"caption included", Rules{CaptionInclude: "#отпуск"}, photo, true},
+		{"caption not included", Rules{CaptionInclude: "^работа"}, photo, false},
+		{"invalid caption expression matches nothing", Rules{CaptionExclude: "(море"}, photo, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := tt.rules.Evaluate(tt.input)
+			if decision.Allowed != tt.want {
+				t.Fatalf("Allowed = %v, want %v (%s)", decision.Allowed, tt.want, decision.Reason)
+			}
+			if decision.Reason == "" {
+				t.Fatal("decision has no reason")
+			}
+		})
+	}
+}
+
+func TestRulesCompileCaptionOnce(t *testing.T) {
+	var rules Rules
+	if err := json.Unmarshal([]byte(`{"caption_include": "#отпуск", "caption_exclude": "(broken"}`), &rules); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if rules.captionInclude == nil {
+		t.Fatal("caption expression is not compiled on load")
+	}
+	if rules.captionExclude != nil {
+		t.Fatal("invalid expression compiled")
+	}
+
+	compiled := rules.captionInclude
+	rules.Evaluate(Input{Caption: "#отпуск"})
+	if rules.captionInclude != compiled {
+		t.Fatal("caption expression is compiled again on evaluate")
+	}
+
+	// Поле, измененное напрямую, не проверяется старым выражением
+	rules.CaptionInclude = "#работа"
+	if rules.Evaluate(Input{Caption: "#отпуск"}).Allowed {
+		t.Fatal("stale caption expression is used")
+	}
+}
+
+func TestRulesJSONRoundTrip(t *testing.T) {
+	var rules Rules
+	if err := rules.Set("caption_exclude", []string{"реклама"}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	data, err := json.Marshal(&rules)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if strings.Contains(string(data), "captionExclude") {
+		t.Fatalf("compiled expression is stored: %s", data)
+	}
+
+	var loaded Rules
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if loaded.Evaluate(Input{Caption: "это реклама"}).Allowed {
+		t.Fatal("loaded rules do not apply the caption expression")
+	}
+}
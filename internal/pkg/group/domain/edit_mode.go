@@ -0,0 +1,14 @@
+package domain
+
+// Что делать с новой версией медиа, когда сообщение отредактировано
+const (
+	// EditModeVersion - сохранить рядом с прошлой версией с суффиксом _v2, _v3...
+	EditModeVersion = "version"
+	// EditModeReplace - заменить файл прошлой версии
+	EditModeReplace = "replace"
+)
+
+// IsEditMode проверяет режим обработки отредактированных медиа
+func IsEditMode(mode string) bool {
+	return mode == EditModeVersion || mode == EditModeReplace
+}
@@ -1,37 +1,101 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"mail_helper_bot/internal/pkg/filter"
+	"mail_helper_bot/internal/pkg/routing"
+)
 
 type GroupSession struct {
-	GroupID          int64     `json:"group_id"`
-	GroupTitle       string    `json:"group_title"`
-	OwnerChatID      int64     `json:"owner_id"`
-	MediaType        string    `json:"media_type"` // "photos", "videos", "all"
-	CloudFolderPath  string    `json:"cloud_folder_path"`
-	PublicURL        string    `json:"public_url"`
-	HistoryProcessed bool      `json:"history_processed"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	GroupID          int64         `json:"group_id"`
+	GroupTitle       string        `json:"group_title"`
+	OwnerChatID      int64         `json:"owner_id"`
+	MediaType        string        `json:"media_type"` // Категории через запятую, см. MediaCategories
+	CloudFolderPath  string        `json:"cloud_folder_path"`
+	PublicURL        string        `json:"public_url"`
+	HistoryProcessed bool          `json:"history_processed"`
+	NamingTemplate   string        `json:"naming_template"`  // Пустой - naming.DefaultTemplate
+	FolderLayout     string        `json:"folder_layout"`    // naming.Layout*, пустой - flat
+	AlbumSubfolders  bool          `json:"album_subfolders"` // Альбом в отдельной подпапке по подписи
+	Filters          filter.Rules  `json:"filters"`
+	Routes           routing.Rules `json:"routes"`
+	SidecarFormat    string        `json:"sidecar_format"` // sidecar.Format*, пустой - без спутников
+	FolderIndex      bool          `json:"folder_index"`   // Вести index.json в каждой папке
+	StripMetadata    bool          `json:"strip_metadata"` // Убирать из JPEG/PNG геолокацию и данные устройства
+	KeepOriginals    bool          `json:"keep_originals"` // Хранить оригиналы с метаданными вне публичной папки
+	EditMode         string        `json:"edit_mode"`      // EditMode*, пустой - EditModeVersion
+	IsChannel        bool          `json:"is_channel"`     // Канал: посты без автора, сообщения бота идут владельцу
+	State            string        `json:"state"`          // State*, пустое - StateActive
+	DryRunCount      int           `json:"dry_run_count"`  // Файлов учтено в пробном режиме, меняется только через репозиторий
+	DryRunBytes      int64         `json:"dry_run_bytes"`
+	CreatedAt        time.Time     `json:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at"`
 }
 
 type ProcessedMedia struct {
-	ID            string    `json:"id"`
+	ID            string     `json:"id"`
+	GroupID       int64      `json:"group_id"`
+	FileUniqueID  string     `json:"file_unique_id"`
+	FileName      string     `json:"file_name"`
+	MediaType     string     `json:"media_type"`
+	FileSizeBytes int64      `json:"file_size_bytes"`
+	ContentHash   string     `json:"content_hash"`
+	CloudPath     string     `json:"cloud_path"`
+	OriginalPath  string     `json:"original_path"` // Оригинал с метаданными, пустой - не сохранялся
+	MimeType      string     `json:"mime_type"`
+	Width         int        `json:"width"`
+	Height        int        `json:"height"`
+	Duration      int        `json:"duration"`
+	MessageID     int        `json:"message_id"`
+	MessageDate   *time.Time `json:"message_date"`
+	SenderID      int64      `json:"sender_id"`
+	SenderName    string     `json:"sender_name"`
+	Caption       string     `json:"caption"`
+	AlbumID       string     `json:"album_id"`      // media_group_id альбома
+	MatchedRoute  string     `json:"matched_route"` // Сработавшее правило маршрутизации, см. routing.Rule.Name
+	Sidecar       []byte     `json:"-"`             // sidecar.Record в JSON, из него собирается index.json
+	Version       int        `json:"version"`       // Номер версии медиа сообщения, начиная с 1
+	PreviousID    string     `json:"previous_id"`   // ID прошлой версии, пустой у первой
+	Replaces      bool       `json:"-"`             // Файл прошлой версии перезаписан этой, прошлая становится superseded
+	UploadedAt    time.Time  `json:"uploaded_at"`
+}
+
+// MediaAlbum - альбом Telegram (сообщения с общим media_group_id)
+type MediaAlbum struct {
 	GroupID       int64     `json:"group_id"`
-	FileUniqueID  string    `json:"file_unique_id"`
-	FileName      string    `json:"file_name"`
-	MediaType     string    `json:"media_type"`
-	FileSizeBytes int64     `json:"file_size_bytes"`
-	UploadedAt    time.Time `json:"uploaded_at"`
+	MediaGroupID  string    `json:"media_group_id"`
+	Caption       string    `json:"caption"`
+	FolderPath    string    `json:"folder_path"`
+	ExpectedCount int       `json:"expected_count"`
+	UploadedCount int       `json:"uploaded_count"`
+	FailedCount   int       `json:"failed_count"`
+	CreatedAt     time.Time `json:"created_at"`
 }
 
 type GroupStats struct {
 	PhotosCount    int
 	VideosCount    int
+	Counts         map[string]int // Количество по категориям медиа
 	TotalSizeBytes int64
 	MediaType      string
 	PublicURL      string
 }
 
+// PeriodStats - загрузки группы за период для сводки владельцу
+type PeriodStats struct {
+	Count          int
+	TotalSizeBytes int64
+	TopSenders     []SenderStats
+}
+
+// SenderStats - сколько файлов группы пришло от участника
+type SenderStats struct {
+	SenderID   int64
+	SenderName string
+	Count      int
+}
+
 type SharedFolder struct {
 	ID         int
 	ChatID     int64
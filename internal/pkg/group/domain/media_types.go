@@ -0,0 +1,105 @@
+package domain
+
+import "strings"
+
+// Категории медиа: значения processed_media.media_type и элементы GroupSession.MediaType
+const (
+	MediaPhoto     = "photo"
+	MediaVideo     = "video"
+	MediaAnimation = "animation"
+	MediaVideoNote = "video_note"
+	MediaAudio     = "audio"
+	MediaVoice     = "voice"
+	MediaDocument  = "document"
+)
+
+// DefaultMediaTypes - категории, которые загружает только что настроенная группа
+const DefaultMediaTypes = MediaPhoto
+
+// MediaCategories - все категории в порядке показа
+var MediaCategories = []struct {
+	Type  string
+	Icon  string
+	Title string
+}{
+	{MediaPhoto, "📷", "Фото"},
+	{MediaVideo, "🎥", "Видео"},
+	{MediaAnimation, "🎞", "GIF"},
+	{MediaVideoNote, "⏺", "Кружки"},
+	{MediaAudio, "🎵", "Аудио"},
+	{MediaVoice, "🎤", "Голосовые"},
+	{MediaDocument, "📄", "Документы"},
+}
+
+// ParseMediaTypes разбирает список категорий группы через запятую.
+// Неизвестные значения отбрасываются, порядок - как в MediaCategories.
+func ParseMediaTypes(value string) []string {
+	selected := make(map[string]bool)
+	for _, t := range strings.Split(value, ",") {
+		selected[strings.TrimSpace(t)] = true
+	}
+
+	var types []string
+	for _, c := range MediaCategories {
+		if selected[c.Type] {
+			types = append(types, c.Type)
+		}
+	}
+	return types
+}
+
+// FormatMediaTypes собирает список категорий для хранения в GroupSession.MediaType
+func FormatMediaTypes(types []string) string {
+	return strings.Join(ParseMediaTypes(strings.Join(types, ",")), ",")
+}
+
+// IsMediaType сообщает, является ли значение известной категорией
+func IsMediaType(t string) bool {
+	for _, c := range MediaCategories {
+		if c.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// MediaTypeIcon возвращает значок категории
+func MediaTypeIcon(t string) string {
+	for _, c := range MediaCategories {
+		if c.Type == t {
+			return c.Icon
+		}
+	}
+	return "📎"
+}
+
+// MediaTypesTitle возвращает список категорий для показа: "📷 Фото, 🎥 Видео"
+func MediaTypesTitle(value string) string {
+	types := ParseMediaTypes(value)
+	if len(types) == len(MediaCategories) {
+		return "📦 Все медиа"
+	}
+
+	var titles []string
+	for _, c := range MediaCategories {
+		for _, t := range types {
+			if c.Type == t {
+				titles = append(titles, c.Icon+" "+c.Title)
+			}
+		}
+	}
+	if len(titles) == 0 {
+		return "не выбраны"
+	}
+	return strings.Join(titles, ", ")
+}
+
+// AcceptsMediaType сообщает, загружает ли группа медиа этой категории
+func (g *GroupSession) AcceptsMediaType(t string) bool {
+	for _, selected := range ParseMediaTypes(g.MediaType) {
+		if selected == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,12 @@
+package domain
+
+// Состояние выгрузки группы
+const (
+	// StateActive - медиа загружаются в облако
+	StateActive = "active"
+	// StatePaused - новые медиа не принимаются, настройки и история сохраняются
+	StatePaused = "paused"
+	// StateDryRun - медиа проходят весь путь до загрузки, но в облако не попадают,
+	// а только учитываются в счетчиках
+	StateDryRun = "dry_run"
+)
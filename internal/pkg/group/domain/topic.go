@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"mail_helper_bot/internal/pkg/naming"
+)
+
+// GroupTopic - тема форума (message_thread_id). Медиа темы складываются
+// в подпапку с ее названием.
+type GroupTopic struct {
+	GroupID   int64     `json:"group_id"`
+	ThreadID  int       `json:"thread_id"`
+	Name      string    `json:"name"`       // Пустое, пока бот не видел создания или переименования темы
+	Enabled   bool      `json:"enabled"`    // Загружать ли медиа темы
+	MediaType string    `json:"media_type"` // Категории через запятую, пустое - как в группе
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Folder возвращает подпапку темы в папке группы
+func (t *GroupTopic) Folder() string {
+	if name := naming.Sanitize(t.Name); name != "" {
+		return name
+	}
+	return fmt.Sprintf("topic_%d", t.ThreadID)
+}
+
+// Title возвращает название темы для показа
+func (t *GroupTopic) Title() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return fmt.Sprintf("Тема #%d", t.ThreadID)
+}
+
+// AcceptsMediaType сообщает, загружаются ли медиа этой категории из темы:
+// свои категории темы, если они заданы, иначе категории группы
+func (t *GroupTopic) AcceptsMediaType(group *GroupSession, mediaType string) bool {
+	if t.MediaType == "" {
+		return group.AcceptsMediaType(mediaType)
+	}
+	for _, selected := range ParseMediaTypes(t.MediaType) {
+		if selected == mediaType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"database/sql"
+
+	"mail_helper_bot/internal/pkg/group/domain"
+)
+
+// OpenAlbum сохраняет альбом при первой его части. Если альбом уже есть -
+// часть пришла после постановки альбома в очередь или после перезапуска
+// бота, - в album записываются сохраненные подпись и папка, а возвращается true.
+func (g *GroupStorage) OpenAlbum(album *domain.MediaAlbum) (bool, error) {
+	result, err := g.db.Exec(`
+        INSERT INTO media_albums (group_id, media_group_id, caption, folder_path)
+        VALUES ($1, $2, NULLIF($3, ''), $4)
+        ON CONFLICT (group_id, media_group_id) DO NOTHING
+    `, album.GroupID, album.MediaGroupID, album.Caption, album.FolderPath)
+	if err != nil {
+		return false, err
+	}
+	if inserted, err := result.RowsAffected(); err != nil || inserted > 0 {
+		return false, err
+	}
+
+	err = g.db.QueryRow(`
+        SELECT COALESCE(caption, ''), folder_path, created_at
+        FROM media_albums
+        WHERE group_id = $1 AND media_group_id = $2
+    `, album.GroupID, album.MediaGroupID).Scan(&album.Caption, &album.FolderPath, &album.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UpdateAlbumFolder меняет подпись и папку альбома, если подпись пришла
+// не с первой частью
+func (g *GroupStorage) UpdateAlbumFolder(album *domain.MediaAlbum) error {
+	_, err := g.db.Exec(`
+        UPDATE media_albums
+        SET caption = NULLIF($3, ''), folder_path = $4, updated_at = now()
+        WHERE group_id = $1 AND media_group_id = $2
+    `, album.GroupID, album.MediaGroupID, album.Caption, album.FolderPath)
+	return err
+}
+
+// ReopenAlbum снова открывает альбом перед постановкой в очередь поздних
+// частей, чтобы еще не отправленный итог учел и их. Уже отправленный итог
+// повторно не отправляется: на альбом приходит одно уведомление.
+func (g *GroupStorage) ReopenAlbum(groupID int64, mediaGroupID string) error {
+	_, err := g.db.Exec(`
+        UPDATE media_albums
+        SET collecting = TRUE, updated_at = now()
+        WHERE group_id = $1 AND media_group_id = $2
+    `, groupID, mediaGroupID)
+	return err
+}
+
+// CloseAlbum учитывает queued поставленных в очередь заданий и закрывает альбом
+func (g *GroupStorage) CloseAlbum(groupID int64, mediaGroupID string, queued int) (*domain.MediaAlbum, error) {
+	_, err := g.db.Exec(`
+        UPDATE media_albums
+        SET expected_count = expected_count + $3, collecting = FALSE, updated_at = now()
+        WHERE group_id = $1 AND media_group_id = $2
+    `, groupID, mediaGroupID, queued)
+	if err != nil {
+		return nil, err
+	}
+	return g.completeAlbum(groupID, mediaGroupID)
+}
+
+// FinishAlbumItem учитывает окончательный результат одного задания альбома
+func (g *GroupStorage) FinishAlbumItem(groupID int64, mediaGroupID string, uploaded bool) (*domain.MediaAlbum, error) {
+	column := "failed_count"
+	if uploaded {
+		column = "uploaded_count"
+	}
+
+	_, err := g.db.Exec(`
+        UPDATE media_albums
+        SET `+column+` = `+column+` + 1, updated_at = now()
+        WHERE group_id = $1 AND media_group_id = $2
+    `, groupID, mediaGroupID)
+	if err != nil {
+		return nil, err
+	}
+	return g.completeAlbum(groupID, mediaGroupID)
+}
+
+// completeAlbum помечает итог альбома отправленным, если все его задания
+// завершены. Условие проверяется и меняется одним запросом, поэтому итог
+// получит ровно один из одновременно завершившихся воркеров.
+func (g *GroupStorage) completeAlbum(groupID int64, mediaGroupID string) (*domain.MediaAlbum, error) {
+	row := g.db.QueryRow(`
+        UPDATE media_albums
+        SET summary_sent = TRUE, updated_at = now()
+        WHERE group_id = $1 AND media_group_id = $2
+          AND NOT collecting AND NOT summary_sent
+          AND uploaded_count + failed_count >= expected_count
+        RETURNING group_id, media_group_id, COALESCE(caption, ''), folder_path,
+                  expected_count, uploaded_count, failed_count, created_at
+    `, groupID, mediaGroupID)
+
+	album := &domain.MediaAlbum{}
+	err := row.Scan(&album.GroupID, &album.MediaGroupID, &album.Caption, &album.FolderPath,
+		&album.ExpectedCount, &album.UploadedCount, &album.FailedCount, &album.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return album, nil
+}
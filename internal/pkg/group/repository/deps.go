@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"encoding/json"
+	"time"
+
 	"mail_helper_bot/internal/pkg/group/domain"
 )
 
@@ -13,6 +16,44 @@ type GroupRepository interface {
 	SaveProcessedMedia(media *domain.ProcessedMedia) error
 	IsMediaProcessed(mediaID string, groupID int64) (bool, error)
 	GetGroupProcessedMedia(groupID int64) ([]*domain.ProcessedMedia, error)
+	GetRecentProcessedMedia(groupID int64, limit int) ([]*domain.ProcessedMedia, error)
+	GetLatestMediaVersion(groupID int64, messageID int) (*domain.ProcessedMedia, error)
+	GetMessageMedia(groupID int64, messageID int) ([]*domain.ProcessedMedia, error)
+	GetFolderSidecars(groupID int64, folderPath string) ([]json.RawMessage, error)
 	GetGroupMediaStats(groupID int64) (stats *domain.GroupStats, err error)
+	// GetGroupPeriodStats считает загрузки группы для сводки владельцу
+	GetGroupPeriodStats(groupID int64, since time.Time, topSenders int) (*domain.PeriodStats, error)
+	IsCloudPathUsed(cloudPath string, groupID int64) (bool, error)
 	MarkHistoryProcessed(groupID int64) error
+
+	// Счетчики пробного режима, см. domain.StateDryRun
+	AddDryRunMedia(groupID int64, fileUniqueID string, size int64) error
+	ResetDryRunStats(groupID int64) error
+
+	// Общий для всех групп индекс содержимого: file_unique_id -> SHA1
+	GetMediaContentHash(fileUniqueID string) (contentHash string, size int64, err error)
+	SaveMediaContentHash(fileUniqueID, contentHash string, size int64) error
+	IsContentProcessed(contentHash string, groupID int64) (bool, error)
+	IsContentOwnedBy(contentHash string, ownerChatID int64) (bool, error)
+
+	// Альбомы: итоговое уведомление отправляется, когда завершены все задания альбома.
+	// CloseAlbum и FinishAlbumItem возвращают альбом, если он только что завершился.
+	// OpenAlbum сохраняет альбом при первой части и возвращает true, если альбом уже был.
+	OpenAlbum(album *domain.MediaAlbum) (bool, error)
+	UpdateAlbumFolder(album *domain.MediaAlbum) error
+	ReopenAlbum(groupID int64, mediaGroupID string) error
+	CloseAlbum(groupID int64, mediaGroupID string, queued int) (*domain.MediaAlbum, error)
+	FinishAlbumItem(groupID int64, mediaGroupID string, uploaded bool) (*domain.MediaAlbum, error)
+
+	// Участники, отказавшиеся от выгрузки своих медиа
+	AddMemberOptOut(groupID, userID int64) error
+	RemoveMemberOptOut(groupID, userID int64) error
+	IsMemberOptedOut(groupID, userID int64) (bool, error)
+	GetSenderMedia(groupID, senderID int64) ([]*domain.ProcessedMedia, error)
+	DeleteProcessedMedia(groupID int64, ids []string) error
+
+	// Темы форумов: название подпапки и настройки загрузки темы
+	SaveGroupTopic(topic *domain.GroupTopic) error
+	GetGroupTopic(groupID int64, threadID int) (*domain.GroupTopic, error)
+	GetGroupTopics(groupID int64) ([]*domain.GroupTopic, error)
 }
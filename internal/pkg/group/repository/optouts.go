@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"github.com/lib/pq"
+	"mail_helper_bot/internal/pkg/group/domain"
+)
+
+// AddMemberOptOut отмечает, что медиа участника не загружаются в облако группы
+func (g *GroupStorage) AddMemberOptOut(groupID, userID int64) error {
+	_, err := g.db.Exec(`
+        INSERT INTO member_optouts (group_id, user_id)
+        VALUES ($1, $2)
+        ON CONFLICT (group_id, user_id) DO NOTHING
+    `, groupID, userID)
+	return err
+}
+
+// RemoveMemberOptOut снова разрешает загрузку медиа участника
+func (g *GroupStorage) RemoveMemberOptOut(groupID, userID int64) error {
+	_, err := g.db.Exec(`
+        DELETE FROM member_optouts WHERE group_id = $1 AND user_id = $2
+    `, groupID, userID)
+	return err
+}
+
+// IsMemberOptedOut проверяет, отказался ли участник от выгрузки своих медиа
+func (g *GroupStorage) IsMemberOptedOut(groupID, userID int64) (bool, error) {
+	var exists bool
+	err := g.db.QueryRow(`
+        SELECT EXISTS(SELECT 1 FROM member_optouts WHERE group_id = $1 AND user_id = $2)
+    `, groupID, userID).Scan(&exists)
+	return exists, err
+}
+
+// GetSenderMedia возвращает все загруженные медиа участника в группе
+func (g *GroupStorage) GetSenderMedia(groupID, senderID int64) ([]*domain.ProcessedMedia, error) {
+	rows, err := g.db.Query(`
+        SELECT `+processedMediaColumns+`
+        FROM processed_media
+        WHERE group_id = $1 AND sender_id = $2
+        ORDER BY uploaded_at
+    `, groupID, senderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var media []*domain.ProcessedMedia
+	for rows.Next() {
+		m, err := scanProcessedMedia(rows)
+		if err != nil {
+			return nil, err
+		}
+		media = append(media, m)
+	}
+	return media, rows.Err()
+}
+
+// DeleteProcessedMedia забывает загруженные медиа по их ID одним запросом
+func (g *GroupStorage) DeleteProcessedMedia(groupID int64, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := g.db.Exec(`
+        DELETE FROM processed_media WHERE group_id = $1 AND id = ANY($2::INTEGER[])
+    `, groupID, pq.Array(ids))
+	return err
+}
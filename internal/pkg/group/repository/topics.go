@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"database/sql"
+
+	"mail_helper_bot/internal/pkg/group/domain"
+)
+
+const groupTopicColumns = `group_id, thread_id, name, enabled, media_type, created_at, updated_at`
+
+func scanGroupTopic(scanner rowScanner) (*domain.GroupTopic, error) {
+	topic := &domain.GroupTopic{}
+	err := scanner.Scan(&topic.GroupID, &topic.ThreadID, &topic.Name, &topic.Enabled, &topic.MediaType,
+		&topic.CreatedAt, &topic.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return topic, nil
+}
+
+// SaveGroupTopic создает или обновляет тему форума
+func (g *GroupStorage) SaveGroupTopic(topic *domain.GroupTopic) error {
+	_, err := g.db.Exec(`
+        INSERT INTO group_topics (group_id, thread_id, name, enabled, media_type)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (group_id, thread_id) DO UPDATE
+        SET name = EXCLUDED.name,
+            enabled = EXCLUDED.enabled,
+            media_type = EXCLUDED.media_type,
+            updated_at = now()
+    `, topic.GroupID, topic.ThreadID, topic.Name, topic.Enabled, topic.MediaType)
+	return err
+}
+
+// GetGroupTopic возвращает тему форума или nil, если бот ее еще не видел
+func (g *GroupStorage) GetGroupTopic(groupID int64, threadID int) (*domain.GroupTopic, error) {
+	row := g.db.QueryRow(`SELECT `+groupTopicColumns+` FROM group_topics
+        WHERE group_id = $1 AND thread_id = $2`, groupID, threadID)
+
+	topic, err := scanGroupTopic(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return topic, err
+}
+
+// GetGroupTopics возвращает известные темы форума группы
+func (g *GroupStorage) GetGroupTopics(groupID int64) ([]*domain.GroupTopic, error) {
+	rows, err := g.db.Query(`SELECT `+groupTopicColumns+` FROM group_topics
+        WHERE group_id = $1 ORDER BY thread_id`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var topics []*domain.GroupTopic
+	for rows.Next() {
+		topic, err := scanGroupTopic(rows)
+		if err != nil {
+			return nil, err
+		}
+		topics = append(topics, topic)
+	}
+	return topics, rows.Err()
+}
@@ -2,7 +2,10 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"mail_helper_bot/internal/pkg/group/domain"
+	"time"
 )
 
 type GroupStorage struct {
@@ -14,30 +17,81 @@ func NewGroupStorage(db *sql.DB) *GroupStorage {
 }
 
 func (g *GroupStorage) SaveGroupSession(group *domain.GroupSession) error {
-	_, err := g.db.Exec(`
-        INSERT INTO group_sessions (group_id, group_title, owner_chat_id, media_type, cloud_folder_path, public_url, history_processed)
-        VALUES ($1, $2, $3, $4, $5, $6, $7)
+	filters, err := json.Marshal(group.Filters)
+	if err != nil {
+		return fmt.Errorf("failed to marshal filter rules: %w", err)
+	}
+	routes, err := json.Marshal(group.Routes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal routing rules: %w", err)
+	}
+
+	_, err = g.db.Exec(`
+        INSERT INTO group_sessions (group_id, group_title, owner_chat_id, media_type, cloud_folder_path, public_url, history_processed,
+                                    naming_template, folder_layout, album_subfolders, filter_rules, routing_rules,
+                                    sidecar_format, folder_index, strip_metadata, keep_originals, edit_mode, is_channel, state)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), COALESCE(NULLIF($9, ''), 'flat'), $10, $11, $12, NULLIF($13, ''), $14, $15, $16,
+                COALESCE(NULLIF($17, ''), 'version'), $18, COALESCE(NULLIF($19, ''), 'active'))
         ON CONFLICT (group_id) DO UPDATE
         SET group_title = $2, 
             media_type = $4, 
             cloud_folder_path = $5, 
             public_url = $6, 
             history_processed = $7,
+            naming_template = NULLIF($8, ''),
+            folder_layout = COALESCE(NULLIF($9, ''), 'flat'),
+            album_subfolders = $10,
+            filter_rules = $11,
+            routing_rules = $12,
+            sidecar_format = NULLIF($13, ''),
+            folder_index = $14,
+            strip_metadata = $15,
+            keep_originals = $16,
+            edit_mode = COALESCE(NULLIF($17, ''), 'version'),
+            is_channel = $18,
+            state = COALESCE(NULLIF($19, ''), 'active'),
             updated_at = now()
-    `, group.GroupID, group.GroupTitle, group.OwnerChatID, group.MediaType, group.CloudFolderPath, group.PublicURL, group.HistoryProcessed)
+    `, group.GroupID, group.GroupTitle, group.OwnerChatID, group.MediaType, group.CloudFolderPath, group.PublicURL, group.HistoryProcessed,
+		group.NamingTemplate, group.FolderLayout, group.AlbumSubfolders, filters, routes,
+		group.SidecarFormat, group.FolderIndex, group.StripMetadata, group.KeepOriginals, group.EditMode, group.IsChannel, group.State)
 	return err
 }
 
+// groupSessionColumns - колонки group_sessions в порядке scanGroupSession
+const groupSessionColumns = `
+        group_id, group_title, owner_chat_id, media_type, cloud_folder_path,
+        COALESCE(public_url, ''), history_processed, COALESCE(naming_template, ''), folder_layout,
+        album_subfolders, filter_rules, routing_rules, COALESCE(sidecar_format, ''), folder_index,
+        strip_metadata, keep_originals, edit_mode, is_channel, state, dry_run_count, dry_run_bytes,
+        created_at, updated_at`
+
+func scanGroupSession(scanner rowScanner) (*domain.GroupSession, error) {
+	group := &domain.GroupSession{}
+	var filters, routes []byte
+	err := scanner.Scan(&group.GroupID, &group.GroupTitle, &group.OwnerChatID, &group.MediaType,
+		&group.CloudFolderPath, &group.PublicURL, &group.HistoryProcessed, &group.NamingTemplate, &group.FolderLayout,
+		&group.AlbumSubfolders, &filters, &routes, &group.SidecarFormat, &group.FolderIndex,
+		&group.StripMetadata, &group.KeepOriginals, &group.EditMode, &group.IsChannel, &group.State, &group.DryRunCount, &group.DryRunBytes, &group.CreatedAt, &group.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(filters, &group.Filters); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal filter rules of group %d: %w", group.GroupID, err)
+	}
+	if err := json.Unmarshal(routes, &group.Routes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal routing rules of group %d: %w", group.GroupID, err)
+	}
+	return group, nil
+}
+
 func (g *GroupStorage) GetGroupSession(groupID int64) (*domain.GroupSession, error) {
 	row := g.db.QueryRow(`
-        SELECT group_id, group_title, owner_chat_id, media_type, cloud_folder_path, COALESCE(public_url, ''), history_processed, created_at, updated_at
+        SELECT `+groupSessionColumns+`
         FROM group_sessions
         WHERE group_id = $1
     `, groupID)
 
-	group := &domain.GroupSession{}
-	err := row.Scan(&group.GroupID, &group.GroupTitle, &group.OwnerChatID, &group.MediaType,
-		&group.CloudFolderPath, &group.PublicURL, &group.HistoryProcessed, &group.CreatedAt, &group.UpdatedAt)
+	group, err := scanGroupSession(row)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -47,6 +101,42 @@ func (g *GroupStorage) GetGroupSession(groupID int64) (*domain.GroupSession, err
 	return group, nil
 }
 
+// AddDryRunMedia учитывает файл, который был бы загружен в пробном режиме.
+// Файл, уже учтенный с последнего сброса, счетчики не увеличивает.
+func (g *GroupStorage) AddDryRunMedia(groupID int64, fileUniqueID string, size int64) error {
+	_, err := g.db.Exec(`
+        WITH added AS (
+            INSERT INTO dry_run_media (group_id, file_unique_id)
+            VALUES ($1, $2)
+            ON CONFLICT DO NOTHING
+            RETURNING group_id
+        )
+        UPDATE group_sessions
+        SET dry_run_count = dry_run_count + 1, dry_run_bytes = dry_run_bytes + $3
+        WHERE group_id IN (SELECT group_id FROM added)
+    `, groupID, fileUniqueID, size)
+	return err
+}
+
+// ResetDryRunStats обнуляет счетчики пробного режима и список учтенных файлов
+func (g *GroupStorage) ResetDryRunStats(groupID int64) error {
+	tx, err := g.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM dry_run_media WHERE group_id = $1`, groupID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+        UPDATE group_sessions SET dry_run_count = 0, dry_run_bytes = 0 WHERE group_id = $1
+    `, groupID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func (g *GroupStorage) DeleteGroupSession(groupID int64) error {
 	_, err := g.db.Exec(`DELETE FROM group_sessions WHERE group_id = $1`, groupID)
 	return err
@@ -54,8 +144,7 @@ func (g *GroupStorage) DeleteGroupSession(groupID int64) error {
 
 func (g *GroupStorage) GetUserGroups(ownerChatID int64) ([]*domain.GroupSession, error) {
 	rows, err := g.db.Query(`
-        SELECT group_id, group_title, owner_chat_id, media_type, cloud_folder_path, 
-               COALESCE(public_url, ''), history_processed, created_at, updated_at
+        SELECT `+groupSessionColumns+`
         FROM group_sessions
         WHERE owner_chat_id = $1
         ORDER BY created_at DESC
@@ -67,9 +156,7 @@ func (g *GroupStorage) GetUserGroups(ownerChatID int64) ([]*domain.GroupSession,
 
 	var groups []*domain.GroupSession
 	for rows.Next() {
-		group := &domain.GroupSession{}
-		err := rows.Scan(&group.GroupID, &group.GroupTitle, &group.OwnerChatID, &group.MediaType,
-			&group.CloudFolderPath, &group.PublicURL, &group.HistoryProcessed, &group.CreatedAt, &group.UpdatedAt)
+		group, err := scanGroupSession(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -78,12 +165,27 @@ func (g *GroupStorage) GetUserGroups(ownerChatID int64) ([]*domain.GroupSession,
 	return groups, nil
 }
 
+// SaveProcessedMedia записывает загруженное медиа. Если новая версия
+// перезаписала файл прошлой, прошлая в том же запросе помечается замененной.
 func (g *GroupStorage) SaveProcessedMedia(media *domain.ProcessedMedia) error {
 	_, err := g.db.Exec(`
-        INSERT INTO processed_media (group_id, file_unique_id, file_name, media_type, file_size_bytes)
-        VALUES ($1, $2, $3, $4, $5)
-        ON CONFLICT (file_unique_id) DO NOTHING
-    `, media.GroupID, media.FileUniqueID, media.FileName, media.MediaType, media.FileSizeBytes)
+        WITH replaced AS (
+            UPDATE processed_media SET superseded = TRUE
+            WHERE $22::BOOLEAN AND id = NULLIF($21, '')::INTEGER AND group_id = $1
+        )
+        INSERT INTO processed_media (group_id, file_unique_id, file_name, media_type, file_size_bytes, content_hash,
+                                     cloud_path, mime_type, width, height, duration_seconds,
+                                     message_id, message_date, sender_id, sender_name, caption, album_id, matched_route, sidecar,
+                                     version, previous_id, original_path)
+        VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''),
+                NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, 0), NULLIF($10, 0), NULLIF($11, 0),
+                NULLIF($12::BIGINT, 0), $13, NULLIF($14::BIGINT, 0), NULLIF($15, ''), NULLIF($16, ''), NULLIF($17, ''), NULLIF($18, ''), NULLIF($19::TEXT, '')::JSONB,
+                GREATEST($20, 1), NULLIF($21, '')::INTEGER, NULLIF($23, ''))
+        ON CONFLICT (group_id, file_unique_id) DO NOTHING
+    `, media.GroupID, media.FileUniqueID, media.FileName, media.MediaType, media.FileSizeBytes, media.ContentHash,
+		media.CloudPath, media.MimeType, media.Width, media.Height, media.Duration,
+		media.MessageID, media.MessageDate, media.SenderID, media.SenderName, media.Caption, media.AlbumID, media.MatchedRoute, media.Sidecar,
+		media.Version, media.PreviousID, media.Replaces, media.OriginalPath)
 	return err
 }
 
@@ -103,9 +205,37 @@ func (g *GroupStorage) IsMediaProcessed(fileUniqueID string, groupID int64) (boo
 	return exists, nil
 }
 
+// processedMediaColumns - колонки processed_media в порядке scanProcessedMedia
+const processedMediaColumns = `
+        id, group_id, file_unique_id, COALESCE(file_name, ''), media_type, COALESCE(file_size_bytes, 0),
+        COALESCE(content_hash, ''), COALESCE(cloud_path, ''), COALESCE(mime_type, ''),
+        COALESCE(width, 0), COALESCE(height, 0), COALESCE(duration_seconds, 0),
+        COALESCE(message_id, 0), message_date, COALESCE(sender_id, 0), COALESCE(sender_name, ''),
+        COALESCE(caption, ''), COALESCE(album_id, ''), COALESCE(matched_route, ''),
+        version, COALESCE(previous_id::TEXT, ''), COALESCE(original_path, ''), uploaded_at`
+
+// rowScanner - общий интерфейс *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProcessedMedia(scanner rowScanner) (*domain.ProcessedMedia, error) {
+	m := &domain.ProcessedMedia{}
+	err := scanner.Scan(&m.ID, &m.GroupID, &m.FileUniqueID, &m.FileName, &m.MediaType, &m.FileSizeBytes,
+		&m.ContentHash, &m.CloudPath, &m.MimeType,
+		&m.Width, &m.Height, &m.Duration,
+		&m.MessageID, &m.MessageDate, &m.SenderID, &m.SenderName,
+		&m.Caption, &m.AlbumID, &m.MatchedRoute,
+		&m.Version, &m.PreviousID, &m.OriginalPath, &m.UploadedAt)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (g *GroupStorage) GetGroupProcessedMedia(groupID int64) ([]*domain.ProcessedMedia, error) {
 	rows, err := g.db.Query(`
-        SELECT id, group_id, file_unique_id, file_name, media_type, file_size_bytes, uploaded_at
+        SELECT `+processedMediaColumns+`
         FROM processed_media
         WHERE group_id = $1
         ORDER BY uploaded_at DESC
@@ -117,8 +247,7 @@ func (g *GroupStorage) GetGroupProcessedMedia(groupID int64) ([]*domain.Processe
 
 	var media []*domain.ProcessedMedia
 	for rows.Next() {
-		m := &domain.ProcessedMedia{}
-		err := rows.Scan(&m.ID, &m.GroupID, &m.FileUniqueID, &m.FileName, &m.MediaType, &m.FileSizeBytes, &m.UploadedAt)
+		m, err := scanProcessedMedia(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -127,24 +256,136 @@ func (g *GroupStorage) GetGroupProcessedMedia(groupID int64) ([]*domain.Processe
 	return media, nil
 }
 
-func (g *GroupStorage) GetGroupMediaStats(groupID int64) (*domain.GroupStats, error) {
+// GetLatestMediaVersion возвращает последнюю загруженную версию медиа сообщения
+func (g *GroupStorage) GetLatestMediaVersion(groupID int64, messageID int) (*domain.ProcessedMedia, error) {
 	row := g.db.QueryRow(`
-        SELECT 
-            COUNT(CASE WHEN media_type = 'photo' THEN 1 END) as photos_count,
-            COUNT(CASE WHEN media_type = 'video' THEN 1 END) as videos_count,
-            COALESCE(SUM(file_size_bytes), 0) as total_size_bytes
-        FROM processed_media 
+        SELECT `+processedMediaColumns+`
+        FROM processed_media
+        WHERE group_id = $1 AND message_id = $2
+        ORDER BY version DESC, uploaded_at DESC
+        LIMIT 1
+    `, groupID, messageID)
+
+	m, err := scanProcessedMedia(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// GetMessageMedia возвращает все загруженные версии медиа сообщения
+func (g *GroupStorage) GetMessageMedia(groupID int64, messageID int) ([]*domain.ProcessedMedia, error) {
+	rows, err := g.db.Query(`
+        SELECT `+processedMediaColumns+`
+        FROM processed_media
+        WHERE group_id = $1 AND message_id = $2
+        ORDER BY version, uploaded_at
+    `, groupID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var media []*domain.ProcessedMedia
+	for rows.Next() {
+		m, err := scanProcessedMedia(rows)
+		if err != nil {
+			return nil, err
+		}
+		media = append(media, m)
+	}
+	return media, rows.Err()
+}
+
+// GetRecentProcessedMedia возвращает последние limit загруженных медиа группы
+func (g *GroupStorage) GetRecentProcessedMedia(groupID int64, limit int) ([]*domain.ProcessedMedia, error) {
+	rows, err := g.db.Query(`
+        SELECT `+processedMediaColumns+`
+        FROM processed_media
         WHERE group_id = $1
-    `, groupID)
+        ORDER BY uploaded_at DESC
+        LIMIT $2
+    `, groupID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var media []*domain.ProcessedMedia
+	for rows.Next() {
+		m, err := scanProcessedMedia(rows)
+		if err != nil {
+			return nil, err
+		}
+		media = append(media, m)
+	}
+	return media, rows.Err()
+}
+
+// GetFolderSidecars возвращает описания файлов, лежащих прямо в папке folderPath,
+// в порядке загрузки. Замененные версии не возвращаются.
+func (g *GroupStorage) GetFolderSidecars(groupID int64, folderPath string) ([]json.RawMessage, error) {
+	rows, err := g.db.Query(`
+        SELECT pm.sidecar
+        FROM processed_media pm
+        WHERE pm.group_id = $1
+          AND pm.sidecar IS NOT NULL
+          AND left(pm.cloud_path, length($2) + 1) = $2 || '/'
+          AND strpos(substr(pm.cloud_path, length($2) + 2), '/') = 0
+          -- Версия, замененная новой по тому же пути, в папке уже не лежит
+          AND NOT pm.superseded
+        ORDER BY pm.uploaded_at, pm.id
+    `, groupID, folderPath)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []json.RawMessage
+	for rows.Next() {
+		var record []byte
+		if err := rows.Scan(&record); err != nil {
+			return nil, err
+		}
+		records = append(records, json.RawMessage(record))
+	}
+	return records, rows.Err()
+}
 
-	stats := &domain.GroupStats{}
-	err := row.Scan(&stats.PhotosCount, &stats.VideosCount, &stats.TotalSizeBytes)
+func (g *GroupStorage) GetGroupMediaStats(groupID int64) (*domain.GroupStats, error) {
+	rows, err := g.db.Query(`
+        SELECT media_type, COUNT(*), COALESCE(SUM(file_size_bytes), 0)
+        FROM processed_media 
+        WHERE group_id = $1 AND NOT superseded
+        GROUP BY media_type
+    `, groupID)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	stats := &domain.GroupStats{Counts: make(map[string]int)}
+	for rows.Next() {
+		var mediaType string
+		var count int
+		var size int64
+		if err := rows.Scan(&mediaType, &count, &size); err != nil {
+			return nil, err
+		}
+		stats.Counts[mediaType] = count
+		stats.TotalSizeBytes += size
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	stats.PhotosCount = stats.Counts[domain.MediaPhoto]
+	stats.VideosCount = stats.Counts[domain.MediaVideo]
 
 	// Получаем информацию о папке
-	row = g.db.QueryRow(`
+	row := g.db.QueryRow(`
         SELECT COALESCE(public_url, ''), media_type
         FROM group_sessions
         WHERE group_id = $1
@@ -158,6 +399,42 @@ func (g *GroupStorage) GetGroupMediaStats(groupID int64) (*domain.GroupStats, er
 	return stats, nil
 }
 
+// GetGroupPeriodStats считает загрузки группы с момента since и самых
+// активных участников, не больше topSenders
+func (g *GroupStorage) GetGroupPeriodStats(groupID int64, since time.Time, topSenders int) (*domain.PeriodStats, error) {
+	stats := &domain.PeriodStats{}
+	err := g.db.QueryRow(`
+        SELECT COUNT(*), COALESCE(SUM(file_size_bytes), 0)
+        FROM processed_media
+        WHERE group_id = $1 AND uploaded_at >= $2 AND NOT superseded
+    `, groupID, since).Scan(&stats.Count, &stats.TotalSizeBytes)
+	if err != nil || stats.Count == 0 {
+		return stats, err
+	}
+
+	rows, err := g.db.Query(`
+        SELECT sender_id, COALESCE(MAX(sender_name), ''), COUNT(*)
+        FROM processed_media
+        WHERE group_id = $1 AND uploaded_at >= $2 AND NOT superseded AND sender_id IS NOT NULL AND sender_id <> 0
+        GROUP BY sender_id
+        ORDER BY COUNT(*) DESC, sender_id
+        LIMIT $3
+    `, groupID, since, topSenders)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sender domain.SenderStats
+		if err := rows.Scan(&sender.SenderID, &sender.SenderName, &sender.Count); err != nil {
+			return nil, err
+		}
+		stats.TopSenders = append(stats.TopSenders, sender)
+	}
+	return stats, rows.Err()
+}
+
 func (g *GroupStorage) MarkHistoryProcessed(groupID int64) error {
 	_, err := g.db.Exec(`
         UPDATE group_sessions 
@@ -168,6 +445,76 @@ func (g *GroupStorage) MarkHistoryProcessed(groupID int64) error {
 	return err
 }
 
+func (g *GroupStorage) IsCloudPathUsed(cloudPath string, groupID int64) (bool, error) {
+	row := g.db.QueryRow(`
+        SELECT EXISTS(
+            SELECT 1 FROM processed_media
+            WHERE cloud_path = $1 AND group_id = $2
+        )
+    `, cloudPath, groupID)
+
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+func (g *GroupStorage) GetMediaContentHash(fileUniqueID string) (string, int64, error) {
+	row := g.db.QueryRow(`
+        SELECT content_hash, file_size_bytes
+        FROM media_content_index
+        WHERE file_unique_id = $1
+    `, fileUniqueID)
+
+	var contentHash string
+	var size int64
+	err := row.Scan(&contentHash, &size)
+	if err == sql.ErrNoRows {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	return contentHash, size, nil
+}
+
+func (g *GroupStorage) SaveMediaContentHash(fileUniqueID, contentHash string, size int64) error {
+	_, err := g.db.Exec(`
+        INSERT INTO media_content_index (file_unique_id, content_hash, file_size_bytes)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (file_unique_id) DO UPDATE
+        SET content_hash = $2,
+            file_size_bytes = $3
+    `, fileUniqueID, contentHash, size)
+	return err
+}
+
+func (g *GroupStorage) IsContentProcessed(contentHash string, groupID int64) (bool, error) {
+	row := g.db.QueryRow(`
+        SELECT EXISTS(
+            SELECT 1 FROM processed_media
+            WHERE content_hash = $1 AND group_id = $2 AND NOT superseded
+        )
+    `, contentHash, groupID)
+
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+func (g *GroupStorage) IsContentOwnedBy(contentHash string, ownerChatID int64) (bool, error) {
+	row := g.db.QueryRow(`
+        SELECT EXISTS(
+            SELECT 1 FROM processed_media pm
+            JOIN group_sessions gs ON gs.group_id = pm.group_id
+            WHERE pm.content_hash = $1 AND gs.owner_chat_id = $2 AND NOT pm.superseded
+        )
+    `, contentHash, ownerChatID)
+
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 // Новые методы для работы с расшаренными папками
 func (g *GroupStorage) SaveSharedFolder(chatID int64, folderName, folderPath, publicURL string) error {
 	_, err := g.db.Exec(`
@@ -37,10 +37,22 @@ func NewLoggedClient(logServerURL string) *LoggedClient {
 	}
 }
 
-func (c *LoggedClient) Do(req *http.Request) (*http.Response, error) {
-	startTime := time.Now()
-	requestID := fmt.Sprintf("%d", time.Now().UnixNano())
+// NewLoggedStreamClient создает клиент для запросов с большими телами.
+// Общего таймаута у него нет, чтобы не обрывать долгие загрузки,
+// ограничено только ожидание ответа после отправки тела.
+func NewLoggedStreamClient(logServerURL string) *LoggedClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = 2 * time.Minute
+
+	return &LoggedClient{
+		Client: &http.Client{
+			Transport: transport,
+		},
+		logServerURL: logServerURL,
+	}
+}
 
+func (c *LoggedClient) Do(req *http.Request) (*http.Response, error) {
 	// Копируем тело запроса для логирования
 	var requestBody []byte
 	if req.Body != nil {
@@ -48,6 +60,19 @@ func (c *LoggedClient) Do(req *http.Request) (*http.Response, error) {
 		req.Body = io.NopCloser(bytes.NewBuffer(requestBody))
 	}
 
+	return c.do(req, string(requestBody))
+}
+
+// DoStream выполняет запрос, не вычитывая тело в память: вместо тела в лог
+// попадает только его заявленный размер
+func (c *LoggedClient) DoStream(req *http.Request) (*http.Response, error) {
+	return c.do(req, fmt.Sprintf("<stream, content-length=%d>", req.ContentLength))
+}
+
+func (c *LoggedClient) do(req *http.Request, requestBody string) (*http.Response, error) {
+	startTime := time.Now()
+	requestID := fmt.Sprintf("%d", time.Now().UnixNano())
+
 	// Копируем ВСЕ заголовки
 	headers := make(map[string][]string)
 	for key, values := range req.Header {
@@ -64,7 +89,7 @@ func (c *LoggedClient) Do(req *http.Request) (*http.Response, error) {
 		Method:      req.Method,
 		URL:         req.URL.String(),
 		Headers:     headers,
-		RequestBody: string(requestBody),
+		RequestBody: requestBody,
 		Duration:    time.Since(startTime).Milliseconds(),
 	}
 
@@ -154,5 +179,5 @@ func (c *LoggedClient) printToConsole(entry LogEntry) {
 	}
 
 	fmt.Printf("Duration: %dms\n", entry.Duration)
-	fmt.Println("=======================================\n")
+	fmt.Print("=======================================\n\n")
 }
@@ -0,0 +1,97 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mail_helper_bot/internal/pkg/cloud/cloud_service"
+	"mail_helper_bot/internal/pkg/privacy"
+)
+
+// ErrFileTooBig - файл превышает лимит скачивания Telegram Bot API
+var ErrFileTooBig = errors.New("file is too big to download via Bot API")
+
+// FileTooBigError - файл больше лимита текущего режима Bot API.
+// Size равен 0, если размер файла неизвестен.
+type FileTooBigError struct {
+	Size  int64
+	Limit int64
+}
+
+func (e *FileTooBigError) Error() string {
+	if e.Size > 0 {
+		return fmt.Sprintf("%v: size=%d, limit=%d", ErrFileTooBig, e.Size, e.Limit)
+	}
+	return fmt.Sprintf("%v: limit=%d", ErrFileTooBig, e.Limit)
+}
+
+func (e *FileTooBigError) Is(target error) bool {
+	return target == ErrFileTooBig
+}
+
+// DownloadError - неуспешный HTTP-статус при скачивании файла из Telegram
+type DownloadError struct {
+	StatusCode int
+}
+
+func (e *DownloadError) Error() string {
+	return fmt.Sprintf("failed to download file from Telegram: status=%d", e.StatusCode)
+}
+
+// IsTransient сообщает, может ли ошибка загрузки пройти при повторной попытке.
+// Ответы 5xx и 429 (от облака и 408) считаются временными, прочие 4xx,
+// слишком большие и пропавшие с диска файлы - постоянными. 404 временный
+// только для облака: удаленная папка будет создана при повторе, а файл,
+// которого нет в Telegram, не появится. Остальные ошибки (обрывы соединения,
+// таймауты) считаются временными: число повторов все равно ограничено.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, ErrFileTooBig) || errors.Is(err, privacy.ErrMalformed) {
+		return false
+	}
+
+	// Пропавший с диска файл при повторе не появится
+	if errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+
+	// Папку могли удалить из облака: кеш папок уже сброшен, и при повторе
+	// она будет создана заново
+	var apiErr *cloud_service.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Temporary() || apiErr.NotFound()
+	}
+
+	var downloadErr *DownloadError
+	if errors.As(err, &downloadErr) {
+		return isTransientStatus(downloadErr.StatusCode)
+	}
+
+	var tgErr *tgbotapi.Error
+	if errors.As(err, &tgErr) {
+		return isTransientStatus(tgErr.Code)
+	}
+
+	// Сетевые ошибки и оборванные потоки
+	return true
+}
+
+func isTransientStatus(code int) bool {
+	return code >= http.StatusInternalServerError || code == http.StatusTooManyRequests
+}
+
+// wrapGetFileError выделяет из ошибки getFile превышение лимита размера
+func wrapGetFileError(err error, limit int64) error {
+	var tgErr *tgbotapi.Error
+	if errors.As(err, &tgErr) && strings.Contains(strings.ToLower(tgErr.Message), "file is too big") {
+		return fmt.Errorf("failed to get file from Telegram: %w", &FileTooBigError{Limit: limit})
+	}
+	return fmt.Errorf("failed to get file from Telegram: %w", err)
+}
@@ -0,0 +1,45 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mail_helper_bot/internal/pkg/cloud/cloud_service"
+	"mail_helper_bot/internal/pkg/privacy"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"network", errors.New("connection reset by peer"), true},
+		{"file too big", fmt.Errorf("upload: %w", &FileTooBigError{Limit: 20 << 20}), false},
+		{"malformed image", fmt.Errorf("strip: %w", privacy.ErrMalformed), false},
+		{"missing local file", fmt.Errorf("open: %w", fs.ErrNotExist), false},
+		{"cloud 500", &cloud_service.APIError{StatusCode: 500}, true},
+		{"cloud 429", &cloud_service.APIError{StatusCode: 429}, true},
+		{"cloud 408", &cloud_service.APIError{StatusCode: 408}, true},
+		{"cloud 404", fmt.Errorf("upload: %w", &cloud_service.APIError{StatusCode: 404}), true},
+		{"cloud 400", &cloud_service.APIError{StatusCode: 400}, false},
+		{"cloud 507", &cloud_service.APIError{StatusCode: 507}, true},
+		{"telegram download 502", &DownloadError{StatusCode: 502}, true},
+		{"telegram download 429", &DownloadError{StatusCode: 429}, true},
+		{"telegram download 404", fmt.Errorf("download: %w", &DownloadError{StatusCode: 404}), false},
+		{"telegram download 403", &DownloadError{StatusCode: 403}, false},
+		{"telegram api 429", &tgbotapi.Error{Code: 429}, true},
+		{"telegram api 400", &tgbotapi.Error{Code: 400, Message: "Bad Request: wrong file_id"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Fatalf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
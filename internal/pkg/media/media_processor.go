@@ -1,34 +1,106 @@
 package media
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"net"
 	"net/http"
+	"os"
+	"path"
+	"path/filepath"
 	"strings"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"mail_helper_bot/internal/pkg/cloud/cloud_service"
+	"mail_helper_bot/internal/pkg/naming"
+	"mail_helper_bot/internal/pkg/privacy"
+	"mail_helper_bot/internal/pkg/sidecar"
 )
 
+// maxRenameAttempts - сколько занятых в облаке имен перебирается при загрузке
+const maxRenameAttempts = 100
+
 type MediaInfo struct {
-	FileID          string
-	Type            string // "photo" or "video"
-	FileName        string
-	CloudFolderPath string
+	FileID          string `json:"file_id"`
+	FileUniqueID    string `json:"file_unique_id"` // Стабильный идентификатор файла в Telegram
+	Type            string `json:"type"`           // Категория медиа, см. group/domain.MediaCategories
+	FileName        string `json:"file_name"`
+	CloudFolderPath string `json:"cloud_folder_path"`
+
+	// Метаданные сообщения и файла
+	MessageID   int              `json:"message_id,omitempty"`
+	MessageDate int64            `json:"message_date,omitempty"` // Unix time
+	SenderID    int64            `json:"sender_id,omitempty"`
+	SenderName  string           `json:"sender_name,omitempty"`
+	Caption     string           `json:"caption,omitempty"`
+	AlbumID     string           `json:"album_id,omitempty"`     // media_group_id альбома
+	Route       string           `json:"route,omitempty"`        // Сработавшее правило маршрутизации
+	TopicFolder string           `json:"topic_folder,omitempty"` // Подпапка темы форума
+	Context     *sidecar.Context `json:"context,omitempty"`      // Форматирование подписи, пересылка и ответ
+	MimeType    string           `json:"mime_type,omitempty"`
+	FileSize    int64            `json:"file_size,omitempty"` // Размер по данным Telegram
+	Width       int              `json:"width,omitempty"`
+	Height      int              `json:"height,omitempty"`
+	Duration    int              `json:"duration,omitempty"` // Секунды
+
+	// Версия медиа отредактированного сообщения
+	Version    int    `json:"version,omitempty"`     // 0 и 1 - первая версия
+	PreviousID string `json:"previous_id,omitempty"` // processed_media.id прошлой версии
+	Overwrite  bool   `json:"overwrite,omitempty"`   // Заменить файл прошлой версии по тому же пути
+
+	// Файл на диске бота (импорт истории); если задан, Telegram не запрашивается
+	LocalPath string `json:"local_path,omitempty"`
+
+	// Настройки приватности группы, задаются при выполнении задания
+	StripMetadata   bool   `json:"-"` // Убрать из JPEG/PNG геолокацию и данные устройства
+	OriginalsFolder string `json:"-"` // Куда сохранить оригинал с метаданными, пустая - не сохранять
+	OriginalPath    string `json:"-"` // Куда загружен оригинал, заполняется после загрузки
+}
+
+// CloudFilePath возвращает полный путь к файлу в облаке
+func (m *MediaInfo) CloudFilePath() string {
+	return fmt.Sprintf("%s/%s", m.CloudFolderPath, m.FileName)
 }
 
 type MediaProcessor struct {
-	cloudService *cloud_service.CloudService
-	botAPI       *tgbotapi.BotAPI
+	cloudService   *cloud_service.CloudService
+	botAPI         *tgbotapi.BotAPI
+	apiConfig      BotAPIConfig
+	downloadClient *http.Client
 }
 
-func NewMediaProcessor(botAPI *tgbotapi.BotAPI) *MediaProcessor {
+func NewMediaProcessor(botAPI *tgbotapi.BotAPI, apiConfig BotAPIConfig) *MediaProcessor {
 	return &MediaProcessor{
-		cloudService: cloud_service.NewCloudService(),
-		botAPI:       botAPI,
+		cloudService:   cloud_service.NewCloudService(),
+		botAPI:         botAPI,
+		apiConfig:      apiConfig,
+		downloadClient: newDownloadClient(),
 	}
 }
 
+// newDownloadClient создает клиент для скачивания файлов из Telegram. Общего
+// таймаута нет, так как большие файлы скачиваются долго, но зависшее
+// соединение или ответ не должны навсегда занимать воркер очереди.
+func newDownloadClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.ResponseHeaderTimeout = 2 * time.Minute
+
+	return &http.Client{Transport: transport}
+}
+
+// APIConfig возвращает настройки сервера Bot API, через который скачиваются файлы
+func (mp *MediaProcessor) APIConfig() BotAPIConfig {
+	return mp.apiConfig
+}
+
 // GenerateCloudFolderPath генерирует путь к папке в облаке для группы
 func (mp *MediaProcessor) GenerateCloudFolderPath(groupID int64, groupName string) string {
 	safeName := strings.ReplaceAll(groupName, " ", "_")
@@ -48,39 +120,278 @@ func (mp *MediaProcessor) CreatePublicLink(accessToken string, folderPath string
 	return mp.cloudService.CreatePublicLink(accessToken, folderPath)
 }
 
+// DeleteCloudFile удаляет файл из облака
+func (mp *MediaProcessor) DeleteCloudFile(accessToken, cloudPath string) error {
+	if err := mp.cloudService.DeleteFile(accessToken, cloudPath); err != nil {
+		return fmt.Errorf("failed to delete %s from cloud: %w", cloudPath, err)
+	}
+	return nil
+}
+
 // ProcessSingleMedia загружает одиночный медиа файл напрямую в облако
-func (mp *MediaProcessor) ProcessSingleMedia(accessToken string, mediaInfo *MediaInfo) error {
+// и возвращает хеш и размер загруженного содержимого
+func (mp *MediaProcessor) ProcessSingleMedia(accessToken string, mediaInfo *MediaInfo) (*cloud_service.UploadResult, error) {
+	body, size, err := mp.openMedia(mediaInfo)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	if err := mp.cloudService.EnsureFolder(accessToken, mediaInfo.CloudFolderPath); err != nil {
+		return nil, fmt.Errorf("failed to create cloud folder: %w", err)
+	}
+
+	var reader io.Reader = body
+	if mediaInfo.StripMetadata {
+		buffered := bufio.NewReader(body)
+		header, _ := buffered.Peek(privacy.HeaderSize)
+		if privacy.Detect(header) != privacy.FormatUnknown {
+			return mp.uploadStripped(accessToken, mediaInfo, buffered)
+		}
+		reader = buffered
+	}
+
+	// Передаем файл в облако потоком, не вычитывая его в память
+	result, err := mp.putFile(accessToken, reader, size, mediaInfo)
+	if err != nil {
+		mp.cloudService.ForgetFolder(accessToken, mediaInfo.CloudFolderPath)
+		return nil, fmt.Errorf("failed to upload file to cloud: %w", err)
+	}
+
+	return result, nil
+}
+
+// uploadStripped загружает изображение без метаданных. Очищенный файл и,
+// если нужен, оригинал пишутся во временные файлы: в памяти держится только
+// текущий блок заголовка, сколько бы весило изображение. Оригинал загружается
+// в OriginalsFolder, только если он задан и в файле были метаданные.
+func (mp *MediaProcessor) uploadStripped(accessToken string, mediaInfo *MediaInfo, reader io.Reader) (*cloud_service.UploadResult, error) {
+	stripped, err := createSpoolFile()
+	if err != nil {
+		return nil, err
+	}
+	defer removeSpoolFile(stripped)
+
+	var original *os.File
+	if mediaInfo.OriginalsFolder != "" {
+		if original, err = createSpoolFile(); err != nil {
+			return nil, err
+		}
+		defer removeSpoolFile(original)
+		reader = io.TeeReader(reader, original)
+	}
+
+	changed, err := privacy.StripTo(stripped, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to strip metadata: %w", err)
+	}
+
+	if changed && original != nil {
+		// Хвост после конца изображения тоже часть оригинала
+		if _, err := io.Copy(io.Discard, reader); err != nil {
+			return nil, fmt.Errorf("failed to download file from Telegram: %w", err)
+		}
+		if err := mp.cloudService.EnsureFolder(accessToken, mediaInfo.OriginalsFolder); err != nil {
+			return nil, fmt.Errorf("failed to create originals folder: %w", err)
+		}
+		// Оригинал перезаписывается, чтобы повтор задания не упирался в уже загруженный файл
+		originalPath := path.Join(mediaInfo.OriginalsFolder, mediaInfo.FileName)
+		originalSize, err := rewindSpoolFile(original)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := mp.cloudService.ReplaceFile(accessToken, original, originalSize, originalPath); err != nil {
+			mp.cloudService.ForgetFolder(accessToken, mediaInfo.OriginalsFolder)
+			return nil, fmt.Errorf("failed to upload original to cloud: %w", err)
+		}
+		mediaInfo.OriginalPath = originalPath
+	}
+
+	strippedSize, err := rewindSpoolFile(stripped)
+	if err != nil {
+		return nil, err
+	}
+	result, err := mp.putFile(accessToken, stripped, strippedSize, mediaInfo)
+	if err != nil {
+		mp.cloudService.ForgetFolder(accessToken, mediaInfo.CloudFolderPath)
+		return nil, fmt.Errorf("failed to upload file to cloud: %w", err)
+	}
+	return result, nil
+}
+
+// createSpoolFile создает временный файл для очистки изображения
+func createSpoolFile() (*os.File, error) {
+	f, err := os.CreateTemp("", "strip-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	return f, nil
+}
+
+// rewindSpoolFile возвращает временный файл в начало и отдает его размер
+func rewindSpoolFile(f *os.File) (int64, error) {
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read temp file: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("failed to read temp file: %w", err)
+	}
+	return size, nil
+}
+
+func removeSpoolFile(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// putFile загружает содержимое медиа по его пути в облаке. Файл прошлой
+// версии перезаписывается, только если это явно запрошено.
+func (mp *MediaProcessor) putFile(accessToken string, reader io.Reader, size int64, mediaInfo *MediaInfo) (*cloud_service.UploadResult, error) {
+	if mediaInfo.Overwrite {
+		return mp.cloudService.ReplaceFile(accessToken, reader, size, mediaInfo.CloudFilePath())
+	}
+
+	result, err := mp.cloudService.UploadContent(accessToken, reader, size)
+	if err != nil {
+		return nil, err
+	}
+	if err := mp.addRenaming(accessToken, result.Hash, result.Size, mediaInfo); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// addRenaming регистрирует содержимое по пути медиа. Путь мог занять файл,
+// о котором бот не знает: загруженный вручную или оставшийся после сбоя
+// между загрузкой и записью в историю. Тогда к имени добавляется суффикс
+// _2, _3 и т.д., новое имя остается в mediaInfo.
+func (mp *MediaProcessor) addRenaming(accessToken, contentHash string, size int64, mediaInfo *MediaInfo) error {
+	name := mediaInfo.FileName
+	for n := 2; ; n++ {
+		err := mp.cloudService.AddFileByHash(accessToken, contentHash, size, mediaInfo.CloudFilePath())
+		var apiErr *cloud_service.APIError
+		if !errors.As(err, &apiErr) || !apiErr.Conflict() || n > maxRenameAttempts {
+			return err
+		}
+		mediaInfo.FileName = naming.WithSuffix(name, n)
+		log.Printf("Cloud path of %s is taken, trying %s", name, mediaInfo.FileName)
+	}
+}
+
+// openMedia открывает содержимое медиа: файл на диске бота или файл из Telegram
+func (mp *MediaProcessor) openMedia(mediaInfo *MediaInfo) (io.ReadCloser, int64, error) {
+	if mediaInfo.LocalPath != "" {
+		return openLocalFile(mediaInfo.LocalPath)
+	}
+	return mp.openTelegramFileByID(mediaInfo.FileID, mediaInfo.FileSize)
+}
+
+// openTelegramFileByID запрашивает файл через getFile и открывает его.
+// knownSize - размер по данным Telegram, 0 - неизвестен.
+func (mp *MediaProcessor) openTelegramFileByID(fileID string, knownSize int64) (io.ReadCloser, int64, error) {
+	// Файл больше лимита текущего режима Bot API получить не удастся
+	limit := mp.apiConfig.MaxFileSize()
+	if knownSize > limit {
+		return nil, 0, &FileTooBigError{Size: knownSize, Limit: limit}
+	}
+
 	// Получаем файл из Telegram
-	fileConfig := tgbotapi.FileConfig{FileID: mediaInfo.FileID}
+	fileConfig := tgbotapi.FileConfig{FileID: fileID}
 	file, err := mp.botAPI.GetFile(fileConfig)
 	if err != nil {
-		return fmt.Errorf("failed to get file from Telegram: %v", err)
+		return nil, 0, wrapGetFileError(err, limit)
+	}
+
+	return mp.openTelegramFile(file.FilePath)
+}
+
+// SaveTelegramFile скачивает файл из Telegram в localPath и возвращает его размер
+func (mp *MediaProcessor) SaveTelegramFile(fileID string, knownSize int64, localPath string) (int64, error) {
+	body, _, err := mp.openTelegramFileByID(fileID, knownSize)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	out, err := os.Create(localPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", localPath, err)
 	}
 
-	// Получаем URL для скачивания файла
-	fileURL := fmt.Sprintf("https://api.telegram.org/file/bot%s/%s", mp.botAPI.Token, file.FilePath)
+	written, err := io.Copy(out, body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(localPath)
+		return 0, fmt.Errorf("failed to save file from Telegram: %w", err)
+	}
+	return written, nil
+}
+
+// openTelegramFile открывает файл, полученный через getFile. Локальный Bot API
+// сервер отдает абсолютный путь на диске, и файл читается напрямую; иначе
+// файл скачивается по HTTP. Размер равен -1, если он неизвестен.
+func (mp *MediaProcessor) openTelegramFile(filePath string) (io.ReadCloser, int64, error) {
+	if mp.apiConfig.Local && filepath.IsAbs(filePath) {
+		return openLocalFile(filePath)
+	}
 
 	// Скачиваем файл из Telegram
-	resp, err := http.Get(fileURL)
+	resp, err := mp.downloadClient.Get(mp.apiConfig.FileURL(mp.botAPI.Token, filePath))
 	if err != nil {
-		return fmt.Errorf("failed to download file from Telegram: %v", err)
+		return nil, 0, fmt.Errorf("failed to download file from Telegram: %w", err)
 	}
-	defer resp.Body.Close()
 
-	// Читаем содержимое файла
-	fileData, err := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, 0, &DownloadError{StatusCode: resp.StatusCode}
+	}
+
+	// ContentLength равен -1, если Telegram не сообщил размер
+	return resp.Body, resp.ContentLength, nil
+}
+
+// openLocalFile открывает файл на диске и возвращает его размер
+func openLocalFile(filePath string) (io.ReadCloser, int64, error) {
+	file, err := os.Open(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to read file content: %v", err)
+		return nil, 0, fmt.Errorf("failed to open local file: %w", err)
 	}
 
-	// Формируем полный путь к файлу в облаке
-	cloudFilePath := fmt.Sprintf("%s/%s", mediaInfo.CloudFolderPath, mediaInfo.FileName)
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, fmt.Errorf("failed to stat local file: %w", err)
+	}
+	return file, info.Size(), nil
+}
 
-	// Загружаем файл в облако
-	err = mp.cloudService.UploadFileFromBytes(accessToken, fileData, cloudFilePath)
+// AddExistingMedia кладет в папку группы файл, содержимое которого уже есть
+// в облаке владельца, без скачивания из Telegram и повторной загрузки
+func (mp *MediaProcessor) AddExistingMedia(accessToken string, mediaInfo *MediaInfo, contentHash string, size int64) error {
+	if err := mp.cloudService.EnsureFolder(accessToken, mediaInfo.CloudFolderPath); err != nil {
+		return fmt.Errorf("failed to create cloud folder: %w", err)
+	}
+	var err error
+	if mediaInfo.Overwrite {
+		err = mp.cloudService.ReplaceFileByHash(accessToken, contentHash, size, mediaInfo.CloudFilePath())
+	} else {
+		err = mp.addRenaming(accessToken, contentHash, size, mediaInfo)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to upload file to cloud: %v", err)
+		mp.cloudService.ForgetFolder(accessToken, mediaInfo.CloudFolderPath)
+		return fmt.Errorf("failed to add existing file to cloud: %w", err)
 	}
+	return nil
+}
 
+// UploadMetaFile кладет рядом с медиа служебный файл (спутник, манифест папки),
+// перезаписывая прошлую версию
+func (mp *MediaProcessor) UploadMetaFile(accessToken, cloudPath string, data []byte) error {
+	if err := mp.cloudService.ReplaceFileFromBytes(accessToken, data, cloudPath); err != nil {
+		return fmt.Errorf("failed to upload %s to cloud: %w", cloudPath, err)
+	}
 	return nil
 }
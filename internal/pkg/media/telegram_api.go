@@ -0,0 +1,60 @@
+package media
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// DefaultBotAPIURL - публичный сервер Telegram Bot API
+	DefaultBotAPIURL = "https://api.telegram.org"
+
+	// cloudMaxFileSize - лимит скачивания файлов через публичный Bot API
+	cloudMaxFileSize int64 = 20 << 20
+
+	// localMaxFileSize - лимит локального Bot API сервера (--local)
+	localMaxFileSize int64 = 2000 << 20
+)
+
+// BotAPIConfig - адрес сервера Telegram Bot API и режим его работы.
+// В локальном режиме getFile возвращает путь к файлу на диске сервера,
+// и файл читается с диска, а не скачивается по HTTP.
+type BotAPIConfig struct {
+	BaseURL string
+	Local   bool
+}
+
+// NewBotAPIConfig возвращает настройки для сервера baseURL; пустой адрес - публичный сервер
+func NewBotAPIConfig(baseURL string, local bool) BotAPIConfig {
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if baseURL == "" {
+		baseURL = DefaultBotAPIURL
+	}
+	return BotAPIConfig{BaseURL: baseURL, Local: local}
+}
+
+// Endpoint возвращает шаблон адреса методов для tgbotapi.NewBotAPIWithAPIEndpoint
+func (c BotAPIConfig) Endpoint() string {
+	return c.BaseURL + "/bot%s/%s"
+}
+
+// FileURL возвращает адрес скачивания файла по file_path из getFile
+func (c BotAPIConfig) FileURL(token, filePath string) string {
+	return fmt.Sprintf("%s/file/bot%s/%s", c.BaseURL, token, strings.TrimLeft(filePath, "/"))
+}
+
+// MaxFileSize возвращает максимальный размер файла, который можно получить в этом режиме
+func (c BotAPIConfig) MaxFileSize() int64 {
+	if c.Local {
+		return localMaxFileSize
+	}
+	return cloudMaxFileSize
+}
+
+// ModeName возвращает название режима для сообщений пользователю
+func (c BotAPIConfig) ModeName() string {
+	if c.Local {
+		return "локальный Bot API сервер"
+	}
+	return "публичный Bot API"
+}
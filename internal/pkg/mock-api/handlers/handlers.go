@@ -2,18 +2,28 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"mail_helper_bot/internal/pkg/mock-api/models"
+	"mail_helper_bot/internal/pkg/mock-api/storage"
 	"math/rand"
 	"net/http"
 	"strings"
 	"time"
 )
 
+var blobStore *storage.BlobStore
+
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
+// SetBlobStore задает хранилище загруженных файлов
+func SetBlobStore(store *storage.BlobStore) {
+	blobStore = store
+}
+
 // MkdirHandler обрабатывает создание папки
 func MkdirHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -28,6 +38,8 @@ func MkdirHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	blobStore.MakeFolder(path)
+
 	// Создаем mock response
 	response := models.MkdirResponse{
 		Hidden: false,
@@ -69,7 +81,33 @@ func MkdirHandler(w http.ResponseWriter, r *http.Request) {
 	sendJSON(w, response)
 }
 
-// AddHandler обрабатывает добавление файла
+// UploadHandler принимает содержимое файла и возвращает его хеш
+func UploadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Content-Length известен не всегда: при chunked-загрузке он равен -1
+	hash, size, err := blobStore.PutBlob(r.Body, r.ContentLength)
+	if errors.Is(err, storage.ErrSizeMismatch) {
+		sendError(w, "Body size does not match Content-Length", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error storing blob: %v", err)
+		sendError(w, "Failed to store file", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Stored blob %s (%d bytes)", hash, size)
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(hash))
+}
+
+// AddHandler обрабатывает добавление файла по хешу ранее загруженного содержимого
 func AddHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -88,6 +126,28 @@ func AddHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	_, err := blobStore.AddFile(request.Path, request.Hash, request.Size, request.Overwrite)
+	switch {
+	case errors.Is(err, storage.ErrUnknownHash):
+		sendError(w, "Unknown hash: upload the file content first", http.StatusBadRequest)
+		return
+	case errors.Is(err, storage.ErrSizeMismatch):
+		sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, storage.ErrAlreadyExists):
+		sendError(w, "File already exists", http.StatusConflict)
+		return
+	case errors.Is(err, storage.ErrNoFolder):
+		sendError(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, storage.ErrQuota):
+		sendError(w, "Not enough space", http.StatusInsufficientStorage)
+		return
+	case err != nil:
+		sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	// Формируем ответ с теми же данными
 	response := models.AddRequest{
 		Hash:            request.Hash,
@@ -106,6 +166,68 @@ func AddHandler(w http.ResponseWriter, r *http.Request) {
 	sendJSON(w, response)
 }
 
+// FileHandler возвращает информацию о файле, зарегистрированном по пути
+func FileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/private/file/")
+	entry, ok := blobStore.GetFile(path)
+	if !ok {
+		sendError(w, "File not found", http.StatusNotFound)
+		return
+	}
+
+	sendJSON(w, models.FileInfo{
+		Path:  entry.Path,
+		Hash:  entry.Hash,
+		Size:  entry.Size,
+		Mtime: entry.Mtime,
+	})
+}
+
+// DownloadHandler отдает содержимое файла, зарегистрированного по пути
+func DownloadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/private/download/")
+	f, entry, err := blobStore.OpenFile(path)
+	if err != nil {
+		sendError(w, "File not found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	http.ServeContent(w, r, entry.Path, time.Unix(entry.Mtime, 0), f)
+}
+
+// RemoveHandler удаляет файл или папку вместе с содержимым
+func RemoveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/private/remove/")
+	if path == "" {
+		sendError(w, "Path is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := blobStore.RemoveFile(path); err != nil {
+		sendError(w, "File not found", http.StatusNotFound)
+		return
+	}
+
+	log.Printf("Removed file %s", path)
+	sendJSON(w, models.FileInfo{Path: "/" + strings.Trim(path, "/")})
+}
+
 // ShareHandler обрабатывает создание публичной ссылки
 func ShareHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mail_helper_bot/internal/pkg/mock-api/storage"
+)
+
+func setupStore(t *testing.T) *storage.BlobStore {
+	t.Helper()
+	store, err := storage.NewBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewBlobStore: %v", err)
+	}
+	SetBlobStore(store)
+	return store
+}
+
+func upload(t *testing.T, content string) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPut, "/upload/", strings.NewReader(content))
+	rec := httptest.NewRecorder()
+	UploadHandler(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("upload status = %d, body = %s", rec.Code, rec.Body)
+	}
+	return rec.Body.String()
+}
+
+func add(path, hash string, size int) *httptest.ResponseRecorder {
+	body := fmt.Sprintf(`{"hash":%q,"path":%q,"size":%d}`, hash, path, size)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/private/add", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	AddHandler(rec, req)
+	return rec
+}
+
+func TestUploadReturnsSHA1(t *testing.T) {
+	setupStore(t)
+	content := "video bytes"
+
+	sum := sha1.Sum([]byte(content))
+	if got, want := upload(t, content), strings.ToUpper(hex.EncodeToString(sum[:])); got != want {
+		t.Fatalf("hash = %s, want %s", got, want)
+	}
+}
+
+func TestUploadSizeMismatch(t *testing.T) {
+	setupStore(t)
+	req := httptest.NewRequest(http.MethodPut, "/upload/", strings.NewReader("short"))
+	req.ContentLength = 100
+	rec := httptest.NewRecorder()
+	UploadHandler(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAddStatuses(t *testing.T) {
+	store := setupStore(t)
+	store.SetQuota(12)
+	store.MakeFolder("/group")
+	hash := upload(t, "content")
+	other := upload(t, "another")
+
+	tests := []struct {
+		name string
+		path string
+		hash string
+		size int
+		want int
+	}{
+		{"added", "/group/a.jpg", hash, 7, http.StatusOK},
+		{"unknown hash", "/group/b.jpg", strings.Repeat("A", 40), 7, http.StatusBadRequest},
+		{"size mismatch", "/group/b.jpg", hash, 8, http.StatusBadRequest},
+		{"existing path", "/group/a.jpg", other, 7, http.StatusConflict},
+		{"missing folder", "/deleted/b.jpg", hash, 7, http.StatusNotFound},
+		{"quota", "/group/b.jpg", other, 7, http.StatusInsufficientStorage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := add(tt.path, tt.hash, tt.size); rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d, body = %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestAddedFileDownloads(t *testing.T) {
+	store := setupStore(t)
+	store.MakeFolder("/group")
+	content := "document bytes"
+	hash := upload(t, content)
+	if rec := add("/group/doc.pdf", hash, len(content)); rec.Code != http.StatusOK {
+		t.Fatalf("add status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/private/download/group/doc.pdf", nil)
+	rec := httptest.NewRecorder()
+	DownloadHandler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("download status = %d", rec.Code)
+	}
+	got, _ := io.ReadAll(rec.Body)
+	if string(got) != content {
+		t.Fatalf("content = %q, want %q", got, content)
+	}
+}
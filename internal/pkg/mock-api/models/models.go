@@ -61,11 +61,19 @@ type AddRequest struct {
 	} `json:"options"`
 	Overwrite       bool   `json:"overwrite"`
 	Path            string `json:"path"`
-	Size            int    `json:"size"`
+	Size            int64  `json:"size"`
 	UnlimitedUpload bool   `json:"unlimited_upload"`
 	UploadType      string `json:"upload_type"`
 }
 
+// FileInfo структура ответа с информацией о загруженном файле
+type FileInfo struct {
+	Path  string `json:"path"`
+	Hash  string `json:"hash"`
+	Size  int64  `json:"size"`
+	Mtime int64  `json:"mtime"`
+}
+
 // Link структура для share/unshare ответов
 type Link struct {
 	Ctime     int    `json:"ctime"`
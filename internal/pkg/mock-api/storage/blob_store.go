@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileEntry описывает файл, зарегистрированный в облаке через add
+type FileEntry struct {
+	Path  string
+	Hash  string
+	Size  int64
+	Mtime int64
+}
+
+// BlobStore хранит загруженные блобы на диске по их хешу
+// и отображение путей облака на эти блобы
+type BlobStore struct {
+	mu      sync.RWMutex
+	dir     string
+	blobs   map[string]int64
+	files   map[string]FileEntry
+	folders map[string]bool
+	quota   int64 // Место в облаке в байтах, 0 - без ограничения
+}
+
+func NewBlobStore(dir string) (*BlobStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create blob dir: %v", err)
+	}
+
+	return &BlobStore{
+		dir:     dir,
+		blobs:   make(map[string]int64),
+		files:   make(map[string]FileEntry),
+		folders: make(map[string]bool),
+	}, nil
+}
+
+// SetQuota ограничивает суммарный размер файлов в облаке, 0 - без ограничения
+func (s *BlobStore) SetQuota(quota int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quota = quota
+}
+
+// MakeFolder создает папку вместе с родительскими, как mkdir -p
+func (s *BlobStore) MakeFolder(folder string) {
+	folder = normalizePath(folder)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ; folder != "/"; folder = parentFolder(folder) {
+		s.folders[folder] = true
+	}
+}
+
+// PutBlob сохраняет содержимое потока и возвращает его SHA1 и размер.
+// Если expectedSize не отрицательный и не совпал с прочитанным, блоб не
+// сохраняется: на оборванную загрузку нельзя будет сослаться в add.
+func (s *BlobStore) PutBlob(reader io.Reader, expectedSize int64) (string, int64, error) {
+	tmp, err := os.CreateTemp(s.dir, "upload-*")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := sha1.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), reader)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to store blob: %v", err)
+	}
+	if expectedSize >= 0 && expectedSize != size {
+		return "", size, fmt.Errorf("%w: body=%d, expected=%d", ErrSizeMismatch, size, expectedSize)
+	}
+
+	hash := strings.ToUpper(hex.EncodeToString(hasher.Sum(nil)))
+	if err := os.Rename(tmp.Name(), s.blobPath(hash)); err != nil {
+		return "", 0, fmt.Errorf("failed to store blob: %v", err)
+	}
+
+	s.mu.Lock()
+	s.blobs[hash] = size
+	s.mu.Unlock()
+
+	return hash, size, nil
+}
+
+// BlobSize возвращает размер блоба, если блоб с таким хешем был загружен
+func (s *BlobStore) BlobSize(hash string) (int64, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	size, ok := s.blobs[strings.ToUpper(hash)]
+	return size, ok
+}
+
+// AddFile регистрирует загруженный блоб по пути в облаке
+func (s *BlobStore) AddFile(path, hash string, size int64, overwrite bool) (FileEntry, error) {
+	path = normalizePath(path)
+	hash = strings.ToUpper(hash)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	blobSize, ok := s.blobs[hash]
+	if !ok {
+		return FileEntry{}, ErrUnknownHash
+	}
+	if blobSize != size {
+		return FileEntry{}, fmt.Errorf("%w: blob=%d, request=%d", ErrSizeMismatch, blobSize, size)
+	}
+
+	if parent := parentFolder(path); parent != "/" && !s.folders[parent] {
+		return FileEntry{}, fmt.Errorf("%w: %s", ErrNoFolder, parent)
+	}
+
+	existing, exists := s.files[path]
+	if exists && !overwrite && existing.Hash != hash {
+		return FileEntry{}, ErrAlreadyExists
+	}
+
+	if s.quota > 0 {
+		used := s.usedLocked()
+		if exists {
+			used -= existing.Size
+		}
+		if used+size > s.quota {
+			return FileEntry{}, fmt.Errorf("%w: used=%d, size=%d, quota=%d", ErrQuota, used, size, s.quota)
+		}
+	}
+
+	entry := FileEntry{
+		Path:  path,
+		Hash:  hash,
+		Size:  size,
+		Mtime: time.Now().Unix(),
+	}
+	s.files[path] = entry
+
+	return entry, nil
+}
+
+// GetFile возвращает файл, зарегистрированный по пути
+func (s *BlobStore) GetFile(path string) (FileEntry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, ok := s.files[normalizePath(path)]
+	return entry, ok
+}
+
+// RemoveFile удаляет файл или папку со всем содержимым. Блобы остаются:
+// на них могут ссылаться другие пути.
+func (s *BlobStore) RemoveFile(path string) error {
+	path = normalizePath(path)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.folders[path] {
+		for folder := range s.folders {
+			if folder == path || strings.HasPrefix(folder, path+"/") {
+				delete(s.folders, folder)
+			}
+		}
+		for file := range s.files {
+			if strings.HasPrefix(file, path+"/") {
+				delete(s.files, file)
+			}
+		}
+		return nil
+	}
+
+	if _, ok := s.files[path]; !ok {
+		return ErrNotFound
+	}
+	delete(s.files, path)
+	return nil
+}
+
+// usedLocked считает место, занятое файлами. Вызывается под s.mu.
+func (s *BlobStore) usedLocked() int64 {
+	var used int64
+	for _, entry := range s.files {
+		used += entry.Size
+	}
+	return used
+}
+
+// OpenFile открывает содержимое файла, зарегистрированного по пути
+func (s *BlobStore) OpenFile(path string) (*os.File, FileEntry, error) {
+	entry, ok := s.GetFile(path)
+	if !ok {
+		return nil, FileEntry{}, ErrNotFound
+	}
+
+	f, err := os.Open(s.blobPath(entry.Hash))
+	if err != nil {
+		return nil, FileEntry{}, err
+	}
+	return f, entry, nil
+}
+
+func (s *BlobStore) blobPath(hash string) string {
+	return filepath.Join(s.dir, hash)
+}
+
+func normalizePath(path string) string {
+	return "/" + strings.Trim(path, "/")
+}
+
+// parentFolder возвращает родительскую папку нормализованного пути облака
+func parentFolder(p string) string {
+	if i := strings.LastIndex(p, "/"); i > 0 {
+		return p[:i]
+	}
+	return "/"
+}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func newTestStore(t *testing.T) *BlobStore {
+	t.Helper()
+	store, err := NewBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewBlobStore: %v", err)
+	}
+	return store
+}
+
+func putBlob(t *testing.T, store *BlobStore, content string) string {
+	t.Helper()
+	hash, size, err := store.PutBlob(strings.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatalf("PutBlob: %v", err)
+	}
+	if size != int64(len(content)) {
+		t.Fatalf("PutBlob size = %d, want %d", size, len(content))
+	}
+	return hash
+}
+
+func TestPutAddDownload(t *testing.T) {
+	store := newTestStore(t)
+	content := "photo bytes"
+
+	hash := putBlob(t, store, content)
+	sum := sha1.Sum([]byte(content))
+	if want := strings.ToUpper(hex.EncodeToString(sum[:])); hash != want {
+		t.Fatalf("hash = %s, want %s", hash, want)
+	}
+
+	store.MakeFolder("/group/2024")
+	if _, err := store.AddFile("/group/2024/a.jpg", strings.ToLower(hash), int64(len(content)), false); err != nil {
+		t.Fatalf("AddFile: %v", err)
+	}
+
+	f, entry, err := store.OpenFile("group/2024/a.jpg")
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	defer f.Close()
+
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !bytes.Equal(got, []byte(content)) {
+		t.Fatalf("content = %q, want %q", got, content)
+	}
+	if entry.Hash != hash || entry.Size != int64(len(content)) {
+		t.Fatalf("entry = %+v", entry)
+	}
+}
+
+func TestPutBlobSizeMismatch(t *testing.T) {
+	store := newTestStore(t)
+
+	hash, _, err := store.PutBlob(strings.NewReader("short"), 100)
+	if !errors.Is(err, ErrSizeMismatch) {
+		t.Fatalf("err = %v, want ErrSizeMismatch", err)
+	}
+	if _, ok := store.BlobSize(hash); ok {
+		t.Fatal("truncated blob must not be registered")
+	}
+}
+
+func TestAddFileErrors(t *testing.T) {
+	store := newTestStore(t)
+	store.MakeFolder("/group")
+	hash := putBlob(t, store, "12345")
+	other := putBlob(t, store, "67890")
+	if _, err := store.AddFile("/group/taken.jpg", hash, 5, false); err != nil {
+		t.Fatalf("AddFile: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		hash      string
+		size      int64
+		overwrite bool
+		want      error
+	}{
+		{"unknown hash", "/group/a.jpg", strings.Repeat("0", 40), 5, false, ErrUnknownHash},
+		{"size mismatch", "/group/a.jpg", hash, 6, false, ErrSizeMismatch},
+		{"existing path", "/group/taken.jpg", other, 5, false, ErrAlreadyExists},
+		{"missing folder", "/missing/a.jpg", hash, 5, false, ErrNoFolder},
+		{"same content", "/group/taken.jpg", hash, 5, false, nil},
+		{"overwrite", "/group/taken.jpg", other, 5, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.AddFile(tt.path, tt.hash, tt.size, tt.overwrite)
+			if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddFileQuota(t *testing.T) {
+	store := newTestStore(t)
+	store.SetQuota(10)
+	store.MakeFolder("/group")
+	hash := putBlob(t, store, "123456")
+
+	if _, err := store.AddFile("/group/a.jpg", hash, 6, false); err != nil {
+		t.Fatalf("AddFile: %v", err)
+	}
+	if _, err := store.AddFile("/group/b.jpg", hash, 6, false); !errors.Is(err, ErrQuota) {
+		t.Fatalf("err = %v, want ErrQuota", err)
+	}
+	// Перезапись файла не занимает места сверх его прошлого размера
+	if _, err := store.AddFile("/group/a.jpg", hash, 6, true); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+}
+
+func TestRemoveFolder(t *testing.T) {
+	store := newTestStore(t)
+	store.MakeFolder("/group/sub")
+	hash := putBlob(t, store, "abc")
+	if _, err := store.AddFile("/group/sub/a.jpg", hash, 3, false); err != nil {
+		t.Fatalf("AddFile: %v", err)
+	}
+
+	if err := store.RemoveFile("/group"); err != nil {
+		t.Fatalf("RemoveFile: %v", err)
+	}
+	if _, ok := store.GetFile("/group/sub/a.jpg"); ok {
+		t.Fatal("file in removed folder is still registered")
+	}
+	if _, err := store.AddFile("/group/sub/b.jpg", hash, 3, false); !errors.Is(err, ErrNoFolder) {
+		t.Fatalf("err = %v, want ErrNoFolder", err)
+	}
+}
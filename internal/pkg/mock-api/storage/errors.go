@@ -0,0 +1,12 @@
+package storage
+
+import "errors"
+
+var (
+	ErrUnknownHash   = errors.New("blob with this hash was not uploaded")
+	ErrSizeMismatch  = errors.New("size does not match uploaded blob")
+	ErrAlreadyExists = errors.New("file already exists")
+	ErrNotFound      = errors.New("file not found")
+	ErrNoFolder      = errors.New("parent folder not found")
+	ErrQuota         = errors.New("storage quota exceeded")
+)
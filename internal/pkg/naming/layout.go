@@ -0,0 +1,83 @@
+package naming
+
+import (
+	"path"
+	"strconv"
+)
+
+// Раскладка файлов по подпапкам внутри папки группы
+const (
+	LayoutFlat      = "flat"       // Все файлы в папке группы
+	LayoutYearMonth = "year_month" // 2006/01
+	LayoutDate      = "date"       // 2006-01-02
+	LayoutSender    = "sender"     // Папка на каждого отправителя
+	LayoutMediaType = "media_type" // photos/, videos/, documents/ и т.д.
+)
+
+// Layouts - поддерживаемые раскладки в порядке показа
+var Layouts = []struct {
+	Name  string
+	Title string
+}{
+	{LayoutFlat, "📄 Без подпапок"},
+	{LayoutYearMonth, "🗓 ГГГГ/ММ"},
+	{LayoutDate, "📅 ГГГГ-ММ-ДД"},
+	{LayoutSender, "👤 По отправителю"},
+	{LayoutMediaType, "🗂 По типу медиа"},
+}
+
+// unknownSender - папка для сообщений без отправителя
+const unknownSender = "unknown_sender"
+
+// IsValidLayout сообщает, поддерживается ли раскладка
+func IsValidLayout(layout string) bool {
+	for _, l := range Layouts {
+		if l.Name == layout {
+			return true
+		}
+	}
+	return false
+}
+
+// LayoutTitle возвращает название раскладки для показа пользователю
+func LayoutTitle(layout string) string {
+	for _, l := range Layouts {
+		if l.Name == layout {
+			return l.Title
+		}
+	}
+	return Layouts[0].Title
+}
+
+// Subfolder возвращает относительный путь подпапки для файла с полями f.
+// Для раскладки flat и неизвестных раскладок возвращается пустая строка.
+func Subfolder(layout string, f Fields) string {
+	switch layout {
+	case LayoutYearMonth:
+		return path.Join(strconv.Itoa(f.Date.Year()), f.Date.Format("01"))
+	case LayoutDate:
+		return f.Date.Format("2006-01-02")
+	case LayoutSender:
+		if sender := Sanitize(f.Sender); sender != "" {
+			return sender
+		}
+		return unknownSender
+	case LayoutMediaType:
+		if folder, ok := mediaTypeFolders[f.Type]; ok {
+			return folder
+		}
+		return Sanitize(f.Type) + "s"
+	default:
+		return ""
+	}
+}
+
+var mediaTypeFolders = map[string]string{
+	"photo":      "photos",
+	"video":      "videos",
+	"animation":  "animations",
+	"video_note": "video_notes",
+	"audio":      "audio",
+	"voice":      "voice",
+	"document":   "documents",
+}
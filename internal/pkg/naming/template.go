@@ -0,0 +1,189 @@
+package naming
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultTemplate - шаблон имени файла, если группа не задала свой
+const DefaultTemplate = "{type}_{date}_{time}_{message_id}"
+
+// maxNameLength - ограничение длины имени файла в символах
+const maxNameLength = 200
+
+// Placeholders - поддерживаемые подстановки и их описание
+var Placeholders = []struct {
+	Name        string
+	Description string
+}{
+	{"{date}", "дата сообщения, 2006-01-02"},
+	{"{time}", "время сообщения, 15-04-05"},
+	{"{sender}", "имя отправителя"},
+	{"{message_id}", "ID сообщения"},
+	{"{album}", "ID альбома"},
+	{"{original_name}", "исходное имя файла без расширения"},
+	{"{type}", "тип медиа"},
+	{"{ext}", "расширение файла"},
+}
+
+var placeholderRe = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// Fields - значения для подстановки в шаблон
+type Fields struct {
+	Type         string
+	Date         time.Time
+	Sender       string
+	MessageID    int
+	Album        string
+	OriginalName string
+	Ext          string // Без точки
+}
+
+// Validate проверяет, что шаблон непустой и содержит только известные подстановки
+func Validate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("шаблон пустой")
+	}
+	if len([]rune(template)) > maxNameLength {
+		return fmt.Errorf("шаблон длиннее %d символов", maxNameLength)
+	}
+
+	for _, p := range placeholderRe.FindAllString(template, -1) {
+		if !isKnownPlaceholder(p) {
+			return fmt.Errorf("неизвестная подстановка %s", p)
+		}
+	}
+	return nil
+}
+
+// Render подставляет значения в шаблон и возвращает безопасное имя файла.
+// Если шаблон не содержит {ext}, расширение добавляется в конец.
+func Render(template string, f Fields) string {
+	if template == "" {
+		template = DefaultTemplate
+	}
+
+	originalName := strings.TrimSuffix(f.OriginalName, path.Ext(f.OriginalName))
+	if originalName == "" {
+		originalName = f.Type
+	}
+
+	values := map[string]string{
+		"{date}":          f.Date.Format("2006-01-02"),
+		"{time}":          f.Date.Format("15-04-05"),
+		"{sender}":        f.Sender,
+		"{message_id}":    strconv.Itoa(f.MessageID),
+		"{album}":         f.Album,
+		"{original_name}": originalName,
+		"{type}":          f.Type,
+		"{ext}":           f.Ext,
+	}
+
+	name := placeholderRe.ReplaceAllStringFunc(template, func(p string) string {
+		if v, ok := values[p]; ok {
+			return Sanitize(v)
+		}
+		return ""
+	})
+
+	name = Sanitize(name)
+	if name == "" {
+		name = "file"
+	}
+
+	if !strings.Contains(template, "{ext}") && f.Ext != "" {
+		return name + "." + Sanitize(f.Ext)
+	}
+	return name
+}
+
+// Sanitize убирает из имени разделители путей, управляющие и запрещенные
+// в облаке символы и ограничивает длину
+func Sanitize(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case unicode.IsControl(r):
+			continue
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			b.WriteRune('_')
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	result := strings.Trim(strings.TrimSpace(b.String()), ".")
+	if runes := []rune(result); len(runes) > maxNameLength {
+		result = string(runes[:maxNameLength])
+	}
+	return result
+}
+
+// WithSuffix добавляет к имени суффикс перед расширением: photo.jpg -> photo_2.jpg
+func WithSuffix(name string, n int) string {
+	ext := path.Ext(name)
+	return fmt.Sprintf("%s_%d%s", strings.TrimSuffix(name, ext), n, ext)
+}
+
+// versionSuffix - суффикс версии в конце имени без расширения: photo_v2
+var versionSuffix = regexp.MustCompile(`_v\d+$`)
+
+// WithVersion возвращает имя версии файла с новым расширением: photo.jpg -> photo_v2.png.
+// Суффикс прошлой версии заменяется.
+func WithVersion(name string, version int, ext string) string {
+	base := versionSuffix.ReplaceAllString(strings.TrimSuffix(name, path.Ext(name)), "")
+	return fmt.Sprintf("%s_v%d.%s", base, version, ext)
+}
+
+// ExtFor определяет расширение файла по исходному имени, MIME-типу или типу медиа
+func ExtFor(originalName, mimeType, mediaType string) string {
+	if ext := strings.TrimPrefix(path.Ext(originalName), "."); ext != "" {
+		return strings.ToLower(ext)
+	}
+	if ext, ok := mimeExtensions[mimeType]; ok {
+		return ext
+	}
+	if ext, ok := mediaTypeExtensions[mediaType]; ok {
+		return ext
+	}
+	return "bin"
+}
+
+var mimeExtensions = map[string]string{
+	"image/jpeg":      "jpg",
+	"image/png":       "png",
+	"image/gif":       "gif",
+	"image/webp":      "webp",
+	"image/heic":      "heic",
+	"video/mp4":       "mp4",
+	"video/quicktime": "mov",
+	"video/webm":      "webm",
+	"audio/mpeg":      "mp3",
+	"audio/ogg":       "ogg",
+	"audio/mp4":       "m4a",
+	"application/pdf": "pdf",
+	"application/zip": "zip",
+}
+
+var mediaTypeExtensions = map[string]string{
+	"photo":      "jpg",
+	"video":      "mp4",
+	"animation":  "mp4",
+	"video_note": "mp4",
+	"audio":      "mp3",
+	"voice":      "ogg",
+}
+
+func isKnownPlaceholder(p string) bool {
+	for _, known := range Placeholders {
+		if known.Name == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package naming
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var testDate = time.Date(2024, 1, 15, 10, 20, 30, 0, time.UTC)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		template string
+		wantErr  bool
+	}{
+		{DefaultTemplate, false},
+		{"{sender}-{original_name}.{ext}", false},
+		{"photo {Date}", false}, // не подстановка: только строчные буквы
+		{"", true},
+		{"   ", true},
+		{"{date}_{unknown}", true},
+		{strings.Repeat("a", maxNameLength+1), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			if err := Validate(tt.template); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate(%q) = %v, wantErr %v", tt.template, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	base := Fields{Type: "photo", Date: testDate, MessageID: 42, Ext: "jpg"}
+	with := func(change func(f *Fields)) Fields {
+		f := base
+		change(&f)
+		return f
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		fields   Fields
+		want     string
+	}{
+		{"default template", "", base, "photo_2024-01-15_10-20-30_42.jpg"},
+		{"all placeholders", "{date}_{time}_{sender}_{message_id}_{album}_{original_name}_{type}.{ext}",
+			with(func(f *Fields) { f.Sender = "Иван"; f.Album = "a1"; f.OriginalName = "IMG_1.JPG" }),
+			"2024-01-15_10-20-30_Иван_42_a1_IMG_1_photo.jpg"},
+		{"unknown placeholder is dropped", "{unknown}_{message_id}", base, "_42.jpg"},
+		{"uppercase is not a placeholder", "{Date}_{message_id}", base, "{Date}_42.jpg"},
+		{"slash in sender", "{sender}_{message_id}", with(func(f *Fields) { f.Sender = "a/b\\c" }), "a_b_c_42.jpg"},
+		{"dots in sender", "{sender}", with(func(f *Fields) { f.Sender = "../../etc/passwd" }), "_.._etc_passwd.jpg"},
+		{"sender is only dots", "{sender}", with(func(f *Fields) { f.Sender = ".." }), "file.jpg"},
+		{"empty result", "{album}", base, "file.jpg"},
+		{"empty result without ext", "{album}", with(func(f *Fields) { f.Ext = "" }), "file"},
+		{"original name falls back to type", "{original_name}", base, "photo.jpg"},
+		{"ext in template", "{original_name}.{ext}", with(func(f *Fields) { f.OriginalName = "doc.pdf"; f.Ext = "pdf" }), "doc.pdf"},
+		{"ext is sanitized", "{message_id}", with(func(f *Fields) { f.Ext = "a/b" }), "42.a_b"},
+		{"no ext", "{message_id}", with(func(f *Fields) { f.Ext = "" }), "42"},
+		{"control characters", "{sender}", with(func(f *Fields) { f.Sender = "a\nb\tc" }), "abc.jpg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.template, tt.fields); got != tt.want {
+				t.Fatalf("Render(%q) = %q, want %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderLongName(t *testing.T) {
+	f := Fields{Type: "photo", Sender: strings.Repeat("я", 300), Ext: "jpg"}
+
+	got := Render("{sender}", f)
+	if n := len([]rune(got)); n != maxNameLength+len(".jpg") {
+		t.Fatalf("length = %d, want %d", n, maxNameLength+len(".jpg"))
+	}
+	if !strings.HasSuffix(got, ".jpg") {
+		t.Fatalf("extension is lost: %q", got)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"photo.jpg", "photo.jpg"},
+		{"Фото с моря", "Фото с моря"},
+		{`a/b\c:d*e?f"g<h>i|j`, "a_b_c_d_e_f_g_h_i_j"},
+		{"..", ""},
+		{"../secret", "_secret"},
+		{"  .hidden.  ", "hidden"},
+		{"a\x00b\x7fc", "abc"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := Sanitize(tt.input); got != tt.want {
+				t.Fatalf("Sanitize(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithSuffix(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		want string
+	}{
+		{"photo.jpg", 2, "photo_2.jpg"},
+		{"photo", 3, "photo_3"},
+		{"archive.tar.gz", 2, "archive.tar_2.gz"},
+	}
+	for _, tt := range tests {
+		if got := WithSuffix(tt.name, tt.n); got != tt.want {
+			t.Errorf("WithSuffix(%q, %d) = %q, want %q", tt.name, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestWithVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		ext     string
+		want    string
+	}{
+		{"photo.jpg", 2, "png", "photo_v2.png"},
+		{"photo_v2.png", 3, "jpg", "photo_v3.jpg"},
+		{"photo_v2x.jpg", 2, "jpg", "photo_v2x_v2.jpg"},
+		{"photo", 2, "jpg", "photo_v2.jpg"},
+	}
+	for _, tt := range tests {
+		if got := WithVersion(tt.name, tt.version, tt.ext); got != tt.want {
+			t.Errorf("WithVersion(%q, %d, %q) = %q, want %q", tt.name, tt.version, tt.ext, got, tt.want)
+		}
+	}
+}
+
+func TestExtFor(t *testing.T) {
+	tests := []struct {
+		originalName string
+		mimeType     string
+		mediaType    string
+		want         string
+	}{
+		{"IMG_1.JPG", "image/png", "photo", "jpg"},
+		{"", "video/quicktime", "video", "mov"},
+		{"noext", "application/pdf", "document", "pdf"},
+		{"", "", "voice", "ogg"},
+		{"", "application/x-unknown", "document", "bin"},
+		{"", "", "", "bin"},
+	}
+	for _, tt := range tests {
+		if got := ExtFor(tt.originalName, tt.mimeType, tt.mediaType); got != tt.want {
+			t.Errorf("ExtFor(%q, %q, %q) = %q, want %q", tt.originalName, tt.mimeType, tt.mediaType, got, tt.want)
+		}
+	}
+}
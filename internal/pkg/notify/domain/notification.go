@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+// Виды уведомлений владельцу облака
+const (
+	// KindAuthExpired - токен владельца недействителен, нужна повторная авторизация
+	KindAuthExpired = "auth_expired"
+	// KindUploadFailures - файлы группы не загрузились после всех повторов
+	KindUploadFailures = "upload_failures"
+	// KindFolderMissing - папку группы удалили из облака
+	KindFolderMissing = "folder_missing"
+	// KindQuotaExceeded - в облаке закончилось место
+	KindQuotaExceeded = "quota_exceeded"
+	// KindFileTooBig - файл группы больше лимита текущего режима Bot API
+	KindFileTooBig = "file_too_big"
+)
+
+// Cooldown возвращает, как часто можно повторять уведомление одного вида
+// об одном и том же: пока владелец не исправил причину, событие повторяется
+// на каждом файле.
+func Cooldown(kind string) time.Duration {
+	switch kind {
+	case KindAuthExpired:
+		return 12 * time.Hour
+	case KindUploadFailures, KindFileTooBig:
+		return time.Hour
+	default:
+		return 6 * time.Hour
+	}
+}
+
+// Notification - уведомление владельцу. Key отделяет однотипные события,
+// которые стоит сообщать отдельно, например, сбои разных групп.
+type Notification struct {
+	OwnerChatID int64
+	Kind        string
+	Key         string
+	Text        string
+	// ReloginButton добавляет кнопку повторной авторизации
+	ReloginButton bool
+}
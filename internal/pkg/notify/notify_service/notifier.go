@@ -0,0 +1,86 @@
+package notify_service
+
+import (
+	"fmt"
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mail_helper_bot/internal/pkg/notify/domain"
+	"mail_helper_bot/internal/pkg/notify/repository"
+	"mail_helper_bot/internal/pkg/oauth/oauth_service"
+)
+
+// DefaultMaxPerHour - сколько уведомлений владелец получает за час самое большее
+const DefaultMaxPerHour = 5
+
+// Notifier сообщает владельцам облака о проблемах с выгрузкой в личный чат.
+// Повторы одного события подавляются, а общее число сообщений ограничено,
+// чтобы сбой на каждом файле не превращался в поток уведомлений.
+type Notifier struct {
+	api        *tgbotapi.BotAPI
+	repo       repository.NotificationRepository
+	oauth      *oauth_service.OAuthService
+	maxPerHour int
+}
+
+func NewNotifier(api *tgbotapi.BotAPI, repo repository.NotificationRepository, oauth *oauth_service.OAuthService) *Notifier {
+	return &Notifier{
+		api:        api,
+		repo:       repo,
+		oauth:      oauth,
+		maxPerHour: DefaultMaxPerHour,
+	}
+}
+
+// SetMaxPerHour задает общий лимит уведомлений одному владельцу за час
+func (n *Notifier) SetMaxPerHour(maxPerHour int) {
+	if maxPerHour > 0 {
+		n.maxPerHour = maxPerHour
+	}
+}
+
+// Notify отправляет уведомление, если его не подавляют дедупликация и лимит
+func (n *Notifier) Notify(notification domain.Notification) {
+	sent, suppressed, err := n.repo.Acquire(notification.OwnerChatID, notification.Kind, notification.Key,
+		domain.Cooldown(notification.Kind), n.maxPerHour)
+	if err != nil {
+		log.Printf("Error checking notification %s for %d: %v", notification.Kind, notification.OwnerChatID, err)
+		return
+	}
+	if !sent {
+		log.Printf("Notification %s:%s for %d suppressed", notification.Kind, notification.Key, notification.OwnerChatID)
+		return
+	}
+
+	text := notification.Text
+	if suppressed > 0 {
+		text += fmt.Sprintf("\n\nПохожих событий с прошлого уведомления: %d", suppressed)
+	}
+
+	msg := tgbotapi.NewMessage(notification.OwnerChatID, text)
+	if notification.ReloginButton {
+		authURL, _, err := n.oauth.GenerateAuthURL(notification.OwnerChatID)
+		if err != nil {
+			log.Printf("Error generating auth URL for %d: %v", notification.OwnerChatID, err)
+			msg.Text += "\n\nАвторизуйтесь снова командой /login"
+		} else {
+			msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+				tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonURL("🔐 Авторизоваться снова", authURL),
+				),
+			)
+		}
+	}
+
+	if _, err := n.api.Send(msg); err != nil {
+		log.Printf("Error sending notification %s to %d: %v", notification.Kind, notification.OwnerChatID, err)
+	}
+}
+
+// Resolve сбрасывает подавление уведомлений вида: если проблема повторится
+// после исправления, владелец узнает о ней сразу
+func (n *Notifier) Resolve(ownerChatID int64, kind string) {
+	if err := n.repo.Resolve(ownerChatID, kind); err != nil {
+		log.Printf("Error resolving notification %s for %d: %v", kind, ownerChatID, err)
+	}
+}
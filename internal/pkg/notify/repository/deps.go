@@ -0,0 +1,13 @@
+package repository
+
+import "time"
+
+type NotificationRepository interface {
+	// Acquire решает, можно ли отправить уведомление: не чаще cooldown для
+	// одного вида и ключа и не больше maxPerHour уведомлений владельцу за час.
+	// Если можно, отмечает отправку и возвращает, сколько таких событий было
+	// пропущено с прошлого раза; если нельзя, учитывает событие как пропущенное.
+	Acquire(ownerChatID int64, kind, key string, cooldown time.Duration, maxPerHour int) (bool, int, error)
+	// Resolve забывает уведомления вида, когда владелец устранил причину
+	Resolve(ownerChatID int64, kind string) error
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+)
+
+type NotificationStorage struct {
+	db *sql.DB
+}
+
+func NewNotificationStorage(db *sql.DB) *NotificationStorage {
+	return &NotificationStorage{db: db}
+}
+
+func (n *NotificationStorage) Acquire(ownerChatID int64, kind, key string, cooldown time.Duration, maxPerHour int) (bool, int, error) {
+	tx, err := n.db.Begin()
+	if err != nil {
+		return false, 0, err
+	}
+	defer tx.Rollback()
+
+	// Воркеры загрузки сообщают о сбоях одновременно: решения по одному
+	// владельцу принимаются по очереди, иначе дубли пройдут проверку вместе
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, ownerChatID); err != nil {
+		return false, 0, err
+	}
+
+	var lastSentAt sql.NullTime
+	var suppressed int
+	err = tx.QueryRow(`
+        SELECT last_sent_at, suppressed_count
+        FROM owner_notifications
+        WHERE owner_chat_id = $1 AND kind = $2 AND dedupe_key = $3
+    `, ownerChatID, kind, key).Scan(&lastSentAt, &suppressed)
+	if err != nil && err != sql.ErrNoRows {
+		return false, 0, err
+	}
+
+	allowed := !lastSentAt.Valid || time.Since(lastSentAt.Time) >= cooldown
+	if allowed {
+		var sentLastHour int
+		err := tx.QueryRow(`
+            SELECT COUNT(*) FROM owner_notifications
+            WHERE owner_chat_id = $1 AND last_sent_at > now() - interval '1 hour'
+        `, ownerChatID).Scan(&sentLastHour)
+		if err != nil {
+			return false, 0, err
+		}
+		allowed = sentLastHour < maxPerHour
+	}
+
+	if !allowed {
+		if _, err := tx.Exec(`
+            INSERT INTO owner_notifications (owner_chat_id, kind, dedupe_key, suppressed_count)
+            VALUES ($1, $2, $3, 1)
+            ON CONFLICT (owner_chat_id, kind, dedupe_key) DO UPDATE
+            SET suppressed_count = owner_notifications.suppressed_count + 1
+        `, ownerChatID, kind, key); err != nil {
+			return false, 0, err
+		}
+		return false, 0, tx.Commit()
+	}
+
+	if _, err := tx.Exec(`
+        INSERT INTO owner_notifications (owner_chat_id, kind, dedupe_key, last_sent_at, suppressed_count)
+        VALUES ($1, $2, $3, now(), 0)
+        ON CONFLICT (owner_chat_id, kind, dedupe_key) DO UPDATE
+        SET last_sent_at = now(), suppressed_count = 0
+    `, ownerChatID, kind, key); err != nil {
+		return false, 0, err
+	}
+	return true, suppressed, tx.Commit()
+}
+
+func (n *NotificationStorage) Resolve(ownerChatID int64, kind string) error {
+	_, err := n.db.Exec(`
+        DELETE FROM owner_notifications WHERE owner_chat_id = $1 AND kind = $2
+    `, ownerChatID, kind)
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -35,6 +36,10 @@ type TokenResponse struct {
 	TokenType    string `json:"token_type"`
 }
 
+// errRefreshRejected - OAuth-сервер отказал в обновлении токена: refresh token
+// отозван или истек
+var errRefreshRejected = errors.New("refresh token rejected")
+
 type OAuthConfig struct {
 	ClientID     string
 	ClientSecret string
@@ -158,8 +163,13 @@ func (s *OAuthService) RefreshToken(refreshToken string) (*TokenResponse, error)
 		return nil, err
 	}
 
+	// Отказ в обновлении (invalid_grant) приходит с 400 или 401. Остальные
+	// ответы, например 5xx и 429 при сбое сервера, временные.
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("%w: %s", errRefreshRejected, string(body))
+	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("refresh token error: %s", string(body))
+		return nil, fmt.Errorf("refresh token error: status %d: %s", resp.StatusCode, string(body))
 	}
 
 	var tokenResp TokenResponse
@@ -0,0 +1,170 @@
+package oauth_service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// tokenRefreshSkew - за сколько до истечения токен обновляется заранее
+const tokenRefreshSkew = 5 * time.Minute
+
+var (
+	// ErrNotAuthorized - у пользователя нет активной сессии
+	ErrNotAuthorized = errors.New("user is not authorized")
+	// ErrSessionExpired - refresh token отозван или истек, нужна повторная авторизация
+	ErrSessionExpired = errors.New("session expired")
+)
+
+// unauthorizedError реализуют ошибки API, означающие недействительный токен
+type unauthorizedError interface {
+	Unauthorized() bool
+}
+
+// TokenProvider выдает действующий access token пользователя,
+// при необходимости обновляя его через refresh token. Одновременные
+// обновления токена одного пользователя объединяются в один запрос.
+type TokenProvider struct {
+	oauth *OAuthService
+
+	mu       sync.Mutex
+	inflight map[int64]*refreshCall
+}
+
+type refreshCall struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+func NewTokenProvider(oauth *OAuthService) *TokenProvider {
+	return &TokenProvider{
+		oauth:    oauth,
+		inflight: make(map[int64]*refreshCall),
+	}
+}
+
+// GetToken возвращает access token пользователя, обновляя его заранее,
+// если до истечения осталось меньше tokenRefreshSkew. Если заранее обновить
+// не удалось из-за временного сбоя, возвращается текущий, еще не истекший токен.
+func (p *TokenProvider) GetToken(chatID int64) (string, error) {
+	session, err := p.oauth.GetUserSession(chatID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get session: %w", err)
+	}
+	if session == nil || session.AccessToken == "" {
+		return "", ErrNotAuthorized
+	}
+
+	if session.TokenExpiresAt == nil || time.Until(*session.TokenExpiresAt) > tokenRefreshSkew {
+		return session.AccessToken, nil
+	}
+
+	token, err := p.refresh(chatID, session.AccessToken)
+	if err != nil && !errors.Is(err, ErrSessionExpired) && !errors.Is(err, ErrNotAuthorized) &&
+		time.Until(*session.TokenExpiresAt) > 0 {
+		// Временный сбой заранее начатого обновления: текущий токен еще действует
+		log.Printf("Early refresh of access token of %d failed, using current token: %v", chatID, err)
+		return session.AccessToken, nil
+	}
+	return token, err
+}
+
+// Do выполняет fn с действующим токеном пользователя. Если API ответило,
+// что токен недействителен, токен обновляется и fn повторяется один раз.
+func (p *TokenProvider) Do(chatID int64, fn func(accessToken string) error) error {
+	token, err := p.GetToken(chatID)
+	if err != nil {
+		return err
+	}
+
+	err = fn(token)
+	if !isUnauthorized(err) {
+		return err
+	}
+
+	log.Printf("Access token of %d was rejected, refreshing: %v", chatID, err)
+	token, err = p.refresh(chatID, token)
+	if err != nil {
+		return err
+	}
+
+	return fn(token)
+}
+
+// refresh обновляет токен, ставший недействительным. Если обновление для этого
+// пользователя уже идет, дожидается его результата вместо второго запроса.
+func (p *TokenProvider) refresh(chatID int64, staleToken string) (string, error) {
+	p.mu.Lock()
+	if call, ok := p.inflight[chatID]; ok {
+		p.mu.Unlock()
+		<-call.done
+		return call.token, call.err
+	}
+
+	call := &refreshCall{done: make(chan struct{})}
+	p.inflight[chatID] = call
+	p.mu.Unlock()
+
+	call.token, call.err = p.doRefresh(chatID, staleToken)
+
+	p.mu.Lock()
+	delete(p.inflight, chatID)
+	p.mu.Unlock()
+	close(call.done)
+
+	return call.token, call.err
+}
+
+func (p *TokenProvider) doRefresh(chatID int64, staleToken string) (string, error) {
+	session, err := p.oauth.GetUserSession(chatID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get session: %w", err)
+	}
+	if session == nil || session.AccessToken == "" {
+		return "", ErrNotAuthorized
+	}
+
+	// Токен уже обновили, пока мы ждали
+	if session.AccessToken != staleToken {
+		return session.AccessToken, nil
+	}
+
+	if session.RefreshToken == "" {
+		return "", ErrSessionExpired
+	}
+
+	tokenResp, err := p.oauth.RefreshToken(session.RefreshToken)
+	if err != nil {
+		if errors.Is(err, errRefreshRejected) {
+			return "", fmt.Errorf("%w: %v", ErrSessionExpired, err)
+		}
+		return "", fmt.Errorf("failed to refresh token: %w", err)
+	}
+
+	// Mail.ru может не выдавать новый refresh token - тогда продолжаем использовать старый
+	refreshToken := tokenResp.RefreshToken
+	if refreshToken == "" {
+		refreshToken = session.RefreshToken
+	}
+
+	var expiresAt *time.Time
+	if tokenResp.ExpiresIn > 0 {
+		exp := time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+		expiresAt = &exp
+	}
+
+	if err := p.oauth.storage.UpdateTokens(chatID, tokenResp.AccessToken, refreshToken, expiresAt); err != nil {
+		return "", fmt.Errorf("failed to save refreshed token: %v", err)
+	}
+
+	log.Printf("Access token of %d refreshed", chatID)
+	return tokenResp.AccessToken, nil
+}
+
+func isUnauthorized(err error) bool {
+	var unauthorized unauthorizedError
+	return errors.As(err, &unauthorized) && unauthorized.Unauthorized()
+}
@@ -0,0 +1,258 @@
+package privacy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Маркеры JPEG
+const (
+	markerSOI   = 0xD8
+	markerEOI   = 0xD9
+	markerSOS   = 0xDA
+	markerTEM   = 0x01
+	markerRST0  = 0xD0
+	markerRST7  = 0xD7
+	markerAPP0  = 0xE0
+	markerAPP1  = 0xE1
+	markerAPP2  = 0xE2
+	markerAPP14 = 0xEE
+	markerAPP15 = 0xEF
+	markerCOM   = 0xFE
+)
+
+// tagOrientation - тег EXIF с поворотом снимка
+const tagOrientation = 0x0112
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	iccHeader  = []byte("ICC_PROFILE\x00")
+)
+
+// stripJPEG вырезает блоки APP1 (EXIF, XMP), APP13 (IPTC) и прочие APPn
+// кроме JFIF, ICC-профиля и Adobe, а также комментарии. Из EXIF сохраняется
+// только поворот снимка, иначе фотография отобразится повернутой.
+// Файл читается потоком: в памяти держится только текущий сегмент заголовка
+// (не больше 64 КБ). Сжатые данные сканов копируются как есть, но сегменты
+// между сканами (в progressive JPEG) проверяются так же, как до первого.
+// Все после EOI (MPF-превью, хвосты камер со своим EXIF) отбрасывается.
+func stripJPEG(w io.Writer, r *bufio.Reader) (bool, error) {
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil || soi[0] != 0xFF || soi[1] != markerSOI {
+		return false, fmt.Errorf("%w: not a JPEG", ErrMalformed)
+	}
+	if _, err := w.Write(soi[:]); err != nil {
+		return false, err
+	}
+	changed := false
+
+	marker, err := readJPEGMarker(r)
+	for {
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case marker == markerEOI:
+			if _, err := w.Write([]byte{0xFF, marker}); err != nil {
+				return false, err
+			}
+			// Хвост дочитывается, чтобы скачивание завершилось целиком
+			trailing, err := io.Copy(io.Discard, r)
+			if err != nil {
+				return false, err
+			}
+			return changed || trailing > 0, nil
+		case marker == markerTEM || (marker >= markerRST0 && marker <= markerRST7):
+			if _, err := w.Write([]byte{0xFF, marker}); err != nil {
+				return false, err
+			}
+			marker, err = readJPEGMarker(r)
+			continue
+		}
+
+		var header [4]byte
+		header[0], header[1] = 0xFF, marker
+		if _, err := io.ReadFull(r, header[2:]); err != nil {
+			return false, readError(err, "truncated JPEG segment")
+		}
+		length := int(binary.BigEndian.Uint16(header[2:]))
+		if length < 2 {
+			return false, fmt.Errorf("%w: bad JPEG segment length", ErrMalformed)
+		}
+
+		payload := make([]byte, length-2)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return false, readError(err, "bad JPEG segment length")
+		}
+
+		// После заголовка скана идут сжатые данные до следующего маркера
+		if marker == markerSOS {
+			if _, err := w.Write(header[:]); err != nil {
+				return false, err
+			}
+			if _, err := w.Write(payload); err != nil {
+				return false, err
+			}
+			marker, err = copyJPEGScan(w, r)
+			continue
+		}
+
+		switch {
+		case marker == markerAPP1 && bytes.HasPrefix(payload, exifHeader):
+			changed = true
+			if orientation, ok := exifOrientation(payload[len(exifHeader):]); ok && orientation != 1 {
+				if _, err := w.Write(orientationSegment(orientation)); err != nil {
+					return false, err
+				}
+			}
+		case keepJPEGSegment(marker, payload):
+			if _, err := w.Write(header[:]); err != nil {
+				return false, err
+			}
+			if _, err := w.Write(payload); err != nil {
+				return false, err
+			}
+		default:
+			changed = true
+		}
+
+		marker, err = readJPEGMarker(r)
+	}
+}
+
+// readJPEGMarker читает маркер сегмента, пропуская байты-заполнители 0xFF
+func readJPEGMarker(r *bufio.Reader) (byte, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, readError(err, "unexpected end of JPEG")
+	}
+	if b != 0xFF {
+		return 0, fmt.Errorf("%w: expected JPEG marker", ErrMalformed)
+	}
+
+	marker := byte(0xFF)
+	for marker == 0xFF {
+		if marker, err = r.ReadByte(); err != nil {
+			return 0, readError(err, "unexpected end of JPEG")
+		}
+	}
+	return marker, nil
+}
+
+// copyJPEGScan копирует сжатые данные скана вместе с экранированными 0xFF
+// и маркерами RST и возвращает первый маркер после них
+func copyJPEGScan(w io.Writer, r *bufio.Reader) (byte, error) {
+	for {
+		data, err := r.ReadSlice(0xFF)
+		if err == bufio.ErrBufferFull {
+			if _, err := w.Write(data); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if err != nil {
+			return 0, readError(err, "unexpected end of JPEG")
+		}
+		if _, err := w.Write(data[:len(data)-1]); err != nil {
+			return 0, err
+		}
+
+		marker := byte(0xFF)
+		for marker == 0xFF {
+			if marker, err = r.ReadByte(); err != nil {
+				return 0, readError(err, "unexpected end of JPEG")
+			}
+		}
+		if marker == 0x00 || (marker >= markerRST0 && marker <= markerRST7) {
+			if _, err := w.Write([]byte{0xFF, marker}); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		return marker, nil
+	}
+}
+
+// keepJPEGSegment сообщает, нужен ли сегмент для корректного отображения
+func keepJPEGSegment(marker byte, payload []byte) bool {
+	switch {
+	case marker == markerCOM:
+		return false
+	case marker == markerAPP0, marker == markerAPP14:
+		return true
+	case marker == markerAPP2:
+		return bytes.HasPrefix(payload, iccHeader)
+	case marker >= markerAPP0 && marker <= markerAPP15:
+		return false
+	default:
+		// Таблицы квантования, Хаффмана, заголовок кадра и т.п.
+		return true
+	}
+}
+
+// exifOrientation читает тег поворота из IFD0 блока TIFF внутри EXIF
+func exifOrientation(tiff []byte) (uint16, bool) {
+	if len(tiff) < 8 {
+		return 0, false
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0, false
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset+2 > len(tiff) || offset < 8 {
+		return 0, false
+	}
+	count := int(order.Uint16(tiff[offset : offset+2]))
+
+	for n := 0; n < count; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 0, false
+		}
+		if order.Uint16(tiff[entry:entry+2]) != tagOrientation {
+			continue
+		}
+		// Тип SHORT, значение лежит в первых двух байтах поля значения
+		if order.Uint16(tiff[entry+2:entry+4]) != 3 {
+			return 0, false
+		}
+		value := order.Uint16(tiff[entry+8 : entry+10])
+		if value < 1 || value > 8 {
+			return 0, false
+		}
+		return value, true
+	}
+	return 0, false
+}
+
+// orientationSegment собирает минимальный APP1 EXIF с единственным тегом поворота
+func orientationSegment(orientation uint16) []byte {
+	var tiff []byte
+	tiff = append(tiff, 'M', 'M', 0x00, 0x2A) // Big-endian, магическое число 42
+	tiff = binary.BigEndian.AppendUint32(tiff, 8)
+	tiff = binary.BigEndian.AppendUint16(tiff, 1) // Один тег в IFD0
+	tiff = binary.BigEndian.AppendUint16(tiff, tagOrientation)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0x00, 0x00)
+	tiff = binary.BigEndian.AppendUint32(tiff, 0) // Следующего IFD нет
+
+	payload := append(append([]byte{}, exifHeader...), tiff...)
+
+	segment := []byte{0xFF, markerAPP1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
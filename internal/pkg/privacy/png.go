@@ -0,0 +1,65 @@
+package privacy
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// pngMetadataChunks - чанки PNG с текстом, EXIF и временем изменения
+var pngMetadataChunks = map[string]bool{
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"eXIf": true,
+	"tIME": true,
+}
+
+// maxPNGChunkLength - наибольшая длина чанка по спецификации PNG
+const maxPNGChunkLength = 1<<31 - 1
+
+// stripPNG вырезает текстовые чанки, EXIF и время изменения. Контрольные
+// суммы считаются по каждому чанку отдельно, поэтому остальные чанки
+// копируются как есть. Чанки не буферизуются: данные идут потоком.
+func stripPNG(w io.Writer, r *bufio.Reader) (bool, error) {
+	signature := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(r, signature); err != nil || string(signature) != string(pngSignature) {
+		return false, fmt.Errorf("%w: not a PNG", ErrMalformed)
+	}
+	if _, err := w.Write(signature); err != nil {
+		return false, err
+	}
+	changed := false
+
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err == io.EOF {
+				return false, fmt.Errorf("%w: PNG has no IEND chunk", ErrMalformed)
+			}
+			return false, readError(err, "truncated PNG chunk header")
+		}
+		length := int64(binary.BigEndian.Uint32(header[:4]))
+		chunkType := string(header[4:])
+		if length > maxPNGChunkLength {
+			return false, fmt.Errorf("%w: bad PNG chunk length", ErrMalformed)
+		}
+
+		// Данные и CRC
+		dst := w
+		if pngMetadataChunks[chunkType] {
+			changed = true
+			dst = io.Discard
+		} else if _, err := w.Write(header[:]); err != nil {
+			return false, err
+		}
+		if _, err := io.CopyN(dst, r, length+4); err != nil {
+			return false, readError(err, "bad PNG chunk length")
+		}
+
+		if chunkType == "IEND" {
+			return changed, nil
+		}
+	}
+}
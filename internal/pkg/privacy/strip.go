@@ -0,0 +1,69 @@
+package privacy
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Format - формат изображения, из которого умеем убирать метаданные
+type Format string
+
+const (
+	FormatUnknown Format = ""
+	FormatJPEG    Format = "jpeg"
+	FormatPNG     Format = "png"
+)
+
+// HeaderSize - сколько первых байт файла нужно для Detect
+const HeaderSize = 8
+
+var (
+	// ErrMalformed - файл похож на JPEG/PNG, но разобрать его не удалось
+	ErrMalformed = errors.New("malformed image, metadata cannot be stripped")
+
+	jpegSignature = []byte{0xFF, 0xD8, 0xFF}
+	pngSignature  = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}
+)
+
+// Detect определяет формат изображения по первым байтам файла
+func Detect(header []byte) Format {
+	switch {
+	case bytes.HasPrefix(header, jpegSignature):
+		return FormatJPEG
+	case bytes.HasPrefix(header, pngSignature):
+		return FormatPNG
+	default:
+		return FormatUnknown
+	}
+}
+
+// StripTo копирует изображение из r в w без метаданных о месте съемки,
+// устройстве и авторе. Пиксели не перекодируются: из файла только вырезаются
+// блоки метаданных, остальное идет потоком. Файлы других форматов копируются
+// без изменений. changed сообщает, было ли что вырезать.
+func StripTo(w io.Writer, r io.Reader) (changed bool, err error) {
+	buffered := bufio.NewReader(r)
+	header, _ := buffered.Peek(HeaderSize)
+
+	switch Detect(header) {
+	case FormatJPEG:
+		return stripJPEG(w, buffered)
+	case FormatPNG:
+		return stripPNG(w, buffered)
+	default:
+		_, err := io.Copy(w, buffered)
+		return false, err
+	}
+}
+
+// readError превращает обрыв файла в ErrMalformed, остальные ошибки чтения
+// (например, сбой сети при скачивании) возвращает как есть
+func readError(err error, reason string) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: %s", ErrMalformed, reason)
+	}
+	return err
+}
@@ -0,0 +1,291 @@
+package privacy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// gpsMarker - содержимое блока GPS в тестовом EXIF, его не должно остаться
+var gpsMarker = []byte("GPS 55.7558N 37.6173E")
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for x := 0; x < 16; x++ {
+		for y := 0; y < 8; y++ {
+			img.Set(x, y, color.RGBA{uint8(x * 16), uint8(y * 32), 128, 255})
+		}
+	}
+	return img
+}
+
+func jpegSegment(marker byte, payload []byte) []byte {
+	segment := []byte{0xFF, marker}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// exifPayload собирает EXIF с поворотом и ссылкой на блок GPS
+func exifPayload(orientation uint16) []byte {
+	var tiff []byte
+	tiff = append(tiff, 'M', 'M', 0x00, 0x2A)
+	tiff = binary.BigEndian.AppendUint32(tiff, 8)
+	tiff = binary.BigEndian.AppendUint16(tiff, 2)
+	// Orientation, SHORT
+	tiff = binary.BigEndian.AppendUint16(tiff, tagOrientation)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0x00, 0x00)
+	// GPSInfo, LONG - смещение блока GPS
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x8825)
+	tiff = binary.BigEndian.AppendUint16(tiff, 4)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint32(tiff, 38)
+	tiff = binary.BigEndian.AppendUint32(tiff, 0)
+	tiff = append(tiff, gpsMarker...)
+
+	return append(append([]byte{}, exifHeader...), tiff...)
+}
+
+var (
+	iccPayload   = append(append([]byte{}, iccHeader...), 1, 1, 'p', 'r', 'o', 'f')
+	adobePayload = []byte{'A', 'd', 'o', 'b', 'e', 0, 100, 0, 0, 0, 0, 1}
+)
+
+// testJPEG кодирует изображение и вставляет после SOI заданные сегменты
+func testJPEG(t *testing.T, segments ...[]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(), nil); err != nil {
+		t.Fatalf("jpeg.Encode: %v", err)
+	}
+	encoded := buf.Bytes()
+
+	out := append([]byte{}, encoded[:2]...)
+	for _, segment := range segments {
+		out = append(out, segment...)
+	}
+	return append(out, encoded[2:]...)
+}
+
+func strip(t *testing.T, data []byte) ([]byte, bool) {
+	t.Helper()
+	var out bytes.Buffer
+	changed, err := StripTo(&out, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("StripTo: %v", err)
+	}
+	return out.Bytes(), changed
+}
+
+func decodes(t *testing.T, data []byte) {
+	t.Helper()
+	if _, _, err := image.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatalf("stripped image does not decode: %v", err)
+	}
+}
+
+func TestStripJPEG(t *testing.T) {
+	data := testJPEG(t,
+		jpegSegment(markerAPP1, exifPayload(6)),
+		jpegSegment(markerAPP2, iccPayload),
+		jpegSegment(markerAPP14, adobePayload),
+		jpegSegment(markerCOM, []byte("taken by Ivan")),
+		jpegSegment(markerAPP1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>")),
+	)
+
+	out, changed := strip(t, data)
+	if !changed {
+		t.Fatal("changed = false, want true")
+	}
+	if bytes.Contains(out, gpsMarker) {
+		t.Error("GPS data is still in the file")
+	}
+	if bytes.Contains(out, []byte("taken by Ivan")) {
+		t.Error("comment is still in the file")
+	}
+	if bytes.Contains(out, []byte("xmpmeta")) {
+		t.Error("XMP is still in the file")
+	}
+	if !bytes.Contains(out, orientationSegment(6)) {
+		t.Error("orientation is lost")
+	}
+	if !bytes.Contains(out, jpegSegment(markerAPP2, iccPayload)) {
+		t.Error("ICC profile is lost")
+	}
+	if !bytes.Contains(out, jpegSegment(markerAPP14, adobePayload)) {
+		t.Error("Adobe segment is lost")
+	}
+	decodes(t, out)
+}
+
+func TestStripJPEGWithoutMetadata(t *testing.T) {
+	data := testJPEG(t)
+
+	out, changed := strip(t, data)
+	if changed {
+		t.Error("changed = true for a JPEG without metadata")
+	}
+	if !bytes.Equal(out, data) {
+		t.Error("JPEG without metadata was modified")
+	}
+}
+
+func TestStripJPEGDefaultOrientation(t *testing.T) {
+	out, changed := strip(t, testJPEG(t, jpegSegment(markerAPP1, exifPayload(1))))
+	if !changed {
+		t.Fatal("changed = false, want true")
+	}
+	// Поворот по умолчанию не нужно сохранять
+	if bytes.Contains(out, exifHeader) {
+		t.Error("EXIF with default orientation is kept")
+	}
+	decodes(t, out)
+}
+
+// sosSegment возвращает заголовок скана из закодированного JPEG
+func sosSegment(t *testing.T, data []byte) []byte {
+	t.Helper()
+	i := bytes.Index(data, []byte{0xFF, markerSOS})
+	if i < 0 {
+		t.Fatal("SOS not found")
+	}
+	length := int(binary.BigEndian.Uint16(data[i+2:]))
+	return data[i : i+2+length]
+}
+
+func TestStripJPEGBetweenScans(t *testing.T) {
+	data := testJPEG(t)
+	eoi := len(data) - 2
+	scanData := []byte{0x12, 0xFF, 0x00, 0x34, 0xFF, markerRST0, 0x56}
+
+	// Второй скан, за ним EXIF перед EOI, как бывает в progressive JPEG
+	var withScan []byte
+	withScan = append(withScan, data[:eoi]...)
+	withScan = append(withScan, sosSegment(t, data)...)
+	withScan = append(withScan, scanData...)
+	withScan = append(withScan, jpegSegment(markerAPP1, exifPayload(6))...)
+	withScan = append(withScan, 0xFF, markerEOI)
+
+	out, changed := strip(t, withScan)
+	if !changed {
+		t.Fatal("changed = false, want true")
+	}
+	if bytes.Contains(out, gpsMarker) {
+		t.Error("GPS data after the second scan is still in the file")
+	}
+	if !bytes.Contains(out, scanData) {
+		t.Error("second scan data is lost")
+	}
+	if !bytes.HasSuffix(out, []byte{0xFF, markerEOI}) {
+		t.Error("EOI is lost")
+	}
+}
+
+func TestStripJPEGAfterEOI(t *testing.T) {
+	data := testJPEG(t)
+	trailer := jpegSegment(markerAPP1, exifPayload(6))
+
+	out, changed := strip(t, append(append([]byte{}, data...), trailer...))
+	if !changed {
+		t.Fatal("changed = false, want true")
+	}
+	if bytes.Contains(out, gpsMarker) {
+		t.Error("GPS data after EOI is still in the file")
+	}
+	if !bytes.Equal(out, data) {
+		t.Error("image data before EOI was modified")
+	}
+	decodes(t, out)
+}
+
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// testPNG кодирует изображение и вставляет чанки перед IDAT и перед IEND
+func testPNG(t *testing.T, beforeIDAT, beforeIEND [][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage()); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	encoded := buf.Bytes()
+
+	idat := bytes.Index(encoded, []byte("IDAT")) - 4
+	iend := bytes.Index(encoded, []byte("IEND")) - 4
+
+	out := append([]byte{}, encoded[:idat]...)
+	for _, chunk := range beforeIDAT {
+		out = append(out, chunk...)
+	}
+	out = append(out, encoded[idat:iend]...)
+	for _, chunk := range beforeIEND {
+		out = append(out, chunk...)
+	}
+	return append(out, encoded[iend:]...)
+}
+
+func TestStripPNG(t *testing.T) {
+	data := testPNG(t,
+		[][]byte{pngChunk("eXIf", exifPayload(6)[len(exifHeader):]), pngChunk("tEXt", []byte("Author\x00Ivan"))},
+		[][]byte{pngChunk("tIME", []byte{0x07, 0xE8, 1, 2, 3, 4, 5}), pngChunk("iTXt", []byte("Comment\x00\x00\x00\x00\x00late"))},
+	)
+
+	out, changed := strip(t, data)
+	if !changed {
+		t.Fatal("changed = false, want true")
+	}
+	for _, chunkType := range []string{"eXIf", "tEXt", "tIME", "iTXt"} {
+		if bytes.Contains(out, []byte(chunkType)) {
+			t.Errorf("%s chunk is still in the file", chunkType)
+		}
+	}
+	if !bytes.HasSuffix(out, pngChunk("IEND", nil)) {
+		t.Error("IEND chunk is lost")
+	}
+	decodes(t, out)
+}
+
+func TestStripMalformed(t *testing.T) {
+	jpegData := testJPEG(t, jpegSegment(markerAPP1, exifPayload(6)))
+	pngData := testPNG(t, [][]byte{pngChunk("tEXt", []byte("Author\x00Ivan"))}, nil)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"JPEG cut inside EXIF", jpegData[:20]},
+		{"JPEG cut inside segment length", jpegData[:5]},
+		{"JPEG without marker", append([]byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00}, 0x01)},
+		{"PNG cut inside chunk", pngData[:40]},
+		{"PNG without IEND", pngData[:len(pngData)-12]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := StripTo(&bytes.Buffer{}, bytes.NewReader(tt.data))
+			if !errors.Is(err, ErrMalformed) {
+				t.Fatalf("err = %v, want ErrMalformed", err)
+			}
+		})
+	}
+}
+
+func TestStripOtherFormats(t *testing.T) {
+	data := []byte("GIF89a not an image we touch")
+
+	out, changed := strip(t, data)
+	if changed || !bytes.Equal(out, data) {
+		t.Fatalf("other format changed: changed=%v, out=%q", changed, out)
+	}
+}
@@ -0,0 +1,211 @@
+package routing
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"mail_helper_bot/internal/pkg/naming"
+)
+
+const (
+	// KindHashtag - правило срабатывает, если в подписи есть хештег
+	KindHashtag = "hashtag"
+	// KindPrefix - правило срабатывает, если подпись начинается с префикса
+	KindPrefix = "prefix"
+
+	// DefaultRouteName - имя маршрута для медиа без подходящего правила
+	DefaultRouteName = "default"
+
+	// maxRules - ограничение числа правил группы
+	maxRules = 50
+)
+
+// Rule - правило маршрутизации: хештег или префикс подписи -> подпапка
+type Rule struct {
+	Kind   string `json:"kind"`
+	Match  string `json:"match"`  // Хештег без # или префикс подписи, сравнение без учета регистра
+	Folder string `json:"folder"` // Путь относительно папки группы
+}
+
+// Name возвращает имя правила, под которым оно записывается в processed_media
+func (r Rule) Name() string {
+	if r.Kind == KindHashtag {
+		return "#" + r.Match
+	}
+	return fmt.Sprintf("prefix:%s", r.Match)
+}
+
+// Rules - правила маршрутизации группы. Хранятся в group_sessions.routing_rules как JSON.
+type Rules struct {
+	Rules   []Rule `json:"rules,omitempty"`
+	Default string `json:"default,omitempty"` // Папка для медиа без тега, пустая - корень группы
+}
+
+// Route - результат маршрутизации. Пустое Name - правил нет и папка не меняется.
+type Route struct {
+	Name   string
+	Folder string
+}
+
+// IsEmpty сообщает, что маршрутизация не настроена
+func (r *Rules) IsEmpty() bool {
+	return len(r.Rules) == 0 && r.Default == ""
+}
+
+// Match выбирает подпапку по подписи: первое подходящее правило в порядке
+// добавления, иначе папка по умолчанию
+func (r *Rules) Match(caption string) Route {
+	if r.IsEmpty() {
+		return Route{}
+	}
+
+	tags := Hashtags(caption)
+	lowerCaption := strings.ToLower(strings.TrimSpace(caption))
+
+	for _, rule := range r.Rules {
+		switch rule.Kind {
+		case KindHashtag:
+			for _, tag := range tags {
+				if tag == rule.Match {
+					return Route{Name: rule.Name(), Folder: rule.Folder}
+				}
+			}
+		case KindPrefix:
+			if strings.HasPrefix(lowerCaption, rule.Match) {
+				return Route{Name: rule.Name(), Folder: rule.Folder}
+			}
+		}
+	}
+
+	return Route{Name: DefaultRouteName, Folder: r.Default}
+}
+
+// Add добавляет правило или заменяет папку правила с тем же тегом или префиксом
+func (r *Rules) Add(kind, match, folder string) error {
+	match = strings.ToLower(strings.TrimSpace(match))
+	switch kind {
+	case KindHashtag:
+		match = strings.TrimPrefix(match, "#")
+		if !isHashtag(match) {
+			return fmt.Errorf("некорректный хештег %q: допустимы буквы, цифры и _", match)
+		}
+	case KindPrefix:
+		if match == "" {
+			return fmt.Errorf("префикс не может быть пустым")
+		}
+	default:
+		return fmt.Errorf("неизвестный тип правила %q", kind)
+	}
+
+	folder, err := CleanFolder(folder)
+	if err != nil {
+		return err
+	}
+	if folder == "" {
+		return fmt.Errorf("укажите папку для правила")
+	}
+
+	for i, rule := range r.Rules {
+		if rule.Kind == kind && rule.Match == match {
+			r.Rules[i].Folder = folder
+			return nil
+		}
+	}
+
+	if len(r.Rules) >= maxRules {
+		return fmt.Errorf("не больше %d правил", maxRules)
+	}
+	r.Rules = append(r.Rules, Rule{Kind: kind, Match: match, Folder: folder})
+	return nil
+}
+
+// Remove удаляет правило по номеру из Describe, начиная с 1
+func (r *Rules) Remove(n int) error {
+	if n < 1 || n > len(r.Rules) {
+		return fmt.Errorf("нет правила с номером %d", n)
+	}
+	r.Rules = append(r.Rules[:n-1], r.Rules[n:]...)
+	return nil
+}
+
+// SetDefault задает папку для медиа без тега; пустая строка - корень группы
+func (r *Rules) SetDefault(folder string) error {
+	folder, err := CleanFolder(folder)
+	if err != nil {
+		return err
+	}
+	r.Default = folder
+	return nil
+}
+
+// Describe возвращает правила построчно для показа пользователю
+func (r *Rules) Describe() []string {
+	lines := make([]string, 0, len(r.Rules)+1)
+	for i, rule := range r.Rules {
+		lines = append(lines, fmt.Sprintf("%d. %s → %s", i+1, rule.Name(), rule.Folder))
+	}
+
+	defaultFolder := r.Default
+	if defaultFolder == "" {
+		defaultFolder = "папка группы"
+	}
+	lines = append(lines, fmt.Sprintf("Без тега → %s", defaultFolder))
+	return lines
+}
+
+// CleanFolder приводит путь подпапки к безопасному виду: части пути очищаются
+// от запрещенных символов, пустые части и переходы наверх отбрасываются
+func CleanFolder(folder string) (string, error) {
+	var parts []string
+	for _, part := range strings.Split(strings.ReplaceAll(folder, "\\", "/"), "/") {
+		part = strings.TrimSpace(part)
+		if part == "" || part == "." {
+			continue
+		}
+		if part == ".." {
+			return "", fmt.Errorf("путь папки не может содержать ..")
+		}
+		if part = naming.Sanitize(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/"), nil
+}
+
+// Hashtags возвращает хештеги подписи в нижнем регистре без #
+func Hashtags(caption string) []string {
+	var tags []string
+	runes := []rune(caption)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '#' || (i > 0 && isTagRune(runes[i-1])) {
+			continue
+		}
+
+		j := i + 1
+		for j < len(runes) && isTagRune(runes[j]) {
+			j++
+		}
+		if j > i+1 {
+			tags = append(tags, strings.ToLower(string(runes[i+1:j])))
+		}
+		i = j - 1
+	}
+	return tags
+}
+
+func isHashtag(tag string) bool {
+	if tag == "" {
+		return false
+	}
+	for _, r := range tag {
+		if !isTagRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isTagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
@@ -0,0 +1,128 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+)
+
+func mustRules(t *testing.T, defaultFolder string, rules ...Rule) *Rules {
+	t.Helper()
+	r := &Rules{}
+	for _, rule := range rules {
+		if err := r.Add(rule.Kind, rule.Match, rule.Folder); err != nil {
+			t.Fatalf("Add(%q, %q): %v", rule.Kind, rule.Match, err)
+		}
+	}
+	if err := r.SetDefault(defaultFolder); err != nil {
+		t.Fatalf("SetDefault: %v", err)
+	}
+	return r
+}
+
+func TestMatch(t *testing.T) {
+	trips := mustRules(t, "Разное",
+		Rule{Kind: KindHashtag, Match: "#Отпуск", Folder: "Отпуск"},
+		Rule{Kind: KindHashtag, Match: "sea", Folder: "Море"},
+		Rule{Kind: KindPrefix, Match: "Чек", Folder: "Чеки"},
+	)
+	prefixFirst := mustRules(t, "",
+		Rule{Kind: KindPrefix, Match: "фото", Folder: "Фото"},
+		Rule{Kind: KindHashtag, Match: "sea", Folder: "Море"},
+	)
+
+	tests := []struct {
+		name    string
+		rules   *Rules
+		caption string
+		want    Route
+	}{
+		{"no rules", &Rules{}, "#sea", Route{}},
+		{"hashtag", trips, "закат #sea", Route{Name: "#sea", Folder: "Море"}},
+		{"first rule wins, not first tag", trips, "#sea #отпуск", Route{Name: "#отпуск", Folder: "Отпуск"}},
+		{"hashtag case folding", trips, "#ОТПУСК 2024", Route{Name: "#отпуск", Folder: "Отпуск"}},
+		{"hashtag is a whole tag", trips, "#seaside", Route{Name: DefaultRouteName, Folder: "Разное"}},
+		{"hashtag inside a word", trips, "email#sea", Route{Name: DefaultRouteName, Folder: "Разное"}},
+		{"hashtag without #", trips, "sea view", Route{Name: DefaultRouteName, Folder: "Разное"}},
+		{"hashtag followed by punctuation", trips, "#sea, вечер", Route{Name: "#sea", Folder: "Море"}},
+		{"prefix", trips, "Чек из магазина", Route{Name: "prefix:чек", Folder: "Чеки"}},
+		{"prefix case folding and spaces", trips, "  ЧЕКИ за май", Route{Name: "prefix:чек", Folder: "Чеки"}},
+		{"prefix is not a substring", trips, "Вот чек", Route{Name: DefaultRouteName, Folder: "Разное"}},
+		{"prefix rule before hashtag", prefixFirst, "фото #sea", Route{Name: "prefix:фото", Folder: "Фото"}},
+		{"hashtag after unmatched prefix", prefixFirst, "закат #sea", Route{Name: "#sea", Folder: "Море"}},
+		{"default is group root", prefixFirst, "", Route{Name: DefaultRouteName, Folder: ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rules.Match(tt.caption); got != tt.want {
+				t.Fatalf("Match(%q) = %+v, want %+v", tt.caption, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdd(t *testing.T) {
+	r := mustRules(t, "", Rule{Kind: KindHashtag, Match: "sea", Folder: "Море"})
+
+	// Тот же тег в другом регистре заменяет папку, а не добавляет правило
+	if err := r.Add(KindHashtag, "#SEA", "Море/2024"); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if len(r.Rules) != 1 || r.Rules[0].Folder != "Море/2024" {
+		t.Fatalf("rules = %+v", r.Rules)
+	}
+
+	invalid := []struct {
+		kind, match, folder string
+	}{
+		{KindHashtag, "#", "a"},
+		{KindHashtag, "два слова", "a"},
+		{KindPrefix, "  ", "a"},
+		{"regexp", "a", "a"},
+		{KindHashtag, "sea", ""},
+		{KindHashtag, "sea", "../secret"},
+	}
+	for _, e := range invalid {
+		if err := r.Add(e.kind, e.match, e.folder); err == nil {
+			t.Errorf("Add(%q, %q, %q) succeeded, want error", e.kind, e.match, e.folder)
+		}
+	}
+}
+
+func TestHashtags(t *testing.T) {
+	tests := []struct {
+		caption string
+		want    []string
+	}{
+		{"", nil},
+		{"#Море и #sea_2024", []string{"море", "sea_2024"}},
+		{"##двойной", []string{"двойной"}},
+		{"a#b #", nil},
+		{"#a#b", []string{"a"}},
+	}
+	for _, tt := range tests {
+		if got := Hashtags(tt.caption); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Hashtags(%q) = %q, want %q", tt.caption, got, tt.want)
+		}
+	}
+}
+
+func TestCleanFolder(t *testing.T) {
+	tests := []struct {
+		folder  string
+		want    string
+		wantErr bool
+	}{
+		{"Отпуск/2024", "Отпуск/2024", false},
+		{` /a//b\c/ `, "a/b/c", false},
+		{"./a/.", "a", false},
+		{"a:b", "a_b", false},
+		{"", "", false},
+		{"a/../b", "", true},
+	}
+	for _, tt := range tests {
+		got, err := CleanFolder(tt.folder)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("CleanFolder(%q) = %q, %v; want %q, error %v", tt.folder, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
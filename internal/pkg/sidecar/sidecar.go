@@ -0,0 +1,167 @@
+package sidecar
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// FormatOff - файлы-спутники не пишутся
+	FormatOff = ""
+	// FormatJSON - спутник <имя файла>.json
+	FormatJSON = "json"
+	// FormatText - спутник <имя файла>.txt
+	FormatText = "txt"
+
+	// IndexFileName - манифест папки со всеми ее файлами
+	IndexFileName = "index.json"
+)
+
+// IsValidFormat проверяет формат спутника; пустой формат выключает спутники
+func IsValidFormat(format string) bool {
+	return format == FormatOff || format == FormatJSON || format == FormatText
+}
+
+// FileName возвращает имя спутника для файла: photo.jpg -> photo.jpg.json
+func FileName(mediaFileName, format string) string {
+	return mediaFileName + "." + format
+}
+
+// Entity - форматирование подписи (ссылки, упоминания, хештеги)
+type Entity struct {
+	Type     string `json:"type"`
+	Offset   int    `json:"offset"` // В UTF-16 единицах, как в Telegram
+	Length   int    `json:"length"`
+	URL      string `json:"url,omitempty"`
+	UserID   int64  `json:"user_id,omitempty"`
+	Language string `json:"language,omitempty"`
+}
+
+// Forward - откуда переслано сообщение
+type Forward struct {
+	From      string `json:"from,omitempty"` // Имя пользователя, канала или скрытого отправителя
+	FromID    int64  `json:"from_id,omitempty"`
+	ChatID    int64  `json:"chat_id,omitempty"`
+	MessageID int    `json:"message_id,omitempty"`
+	Date      int64  `json:"date,omitempty"` // Unix time
+}
+
+// Reply - сообщение, на которое отвечает медиа
+type Reply struct {
+	MessageID  int    `json:"message_id"`
+	SenderID   int64  `json:"sender_id,omitempty"`
+	SenderName string `json:"sender_name,omitempty"`
+	Text       string `json:"text,omitempty"`
+}
+
+// Context - контекст сообщения, которого нет в самом файле
+type Context struct {
+	CaptionEntities []Entity `json:"caption_entities,omitempty"`
+	Forward         *Forward `json:"forward,omitempty"`
+	Reply           *Reply   `json:"reply,omitempty"`
+}
+
+// Record - описание загруженного файла: содержимое спутника и строка манифеста
+type Record struct {
+	FileName        string   `json:"file_name"`
+	Type            string   `json:"type"`
+	MimeType        string   `json:"mime_type,omitempty"`
+	Size            int64    `json:"size,omitempty"`
+	MessageID       int      `json:"message_id,omitempty"`
+	Date            string   `json:"date,omitempty"` // RFC 3339, UTC
+	SenderID        int64    `json:"sender_id,omitempty"`
+	SenderName      string   `json:"sender_name,omitempty"`
+	Caption         string   `json:"caption,omitempty"`
+	CaptionEntities []Entity `json:"caption_entities,omitempty"`
+	Forward         *Forward `json:"forward,omitempty"`
+	Reply           *Reply   `json:"reply,omitempty"`
+	AlbumID         string   `json:"album_id,omitempty"`
+	Route           string   `json:"route,omitempty"`
+	Version         int      `json:"version,omitempty"` // Версия медиа отредактированного сообщения
+}
+
+// FormatDate приводит Unix time сообщения к виду, в котором он пишется в спутники
+func FormatDate(unix int64) string {
+	if unix <= 0 {
+		return ""
+	}
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
+
+// Render возвращает содержимое спутника в заданном формате
+func (r *Record) Render(format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(r, "", "  ")
+	case FormatText:
+		return []byte(r.text()), nil
+	default:
+		return nil, fmt.Errorf("unknown sidecar format %q", format)
+	}
+}
+
+// text - человекочитаемое описание файла для формата txt
+func (r *Record) text() string {
+	var b strings.Builder
+	line := func(title, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%s: %s\n", title, value)
+		}
+	}
+
+	line("Файл", r.FileName)
+	line("Тип", r.Type)
+	line("Дата", r.Date)
+	if r.SenderName != "" || r.SenderID != 0 {
+		line("Автор", strings.TrimSpace(fmt.Sprintf("%s (id %d)", r.SenderName, r.SenderID)))
+	}
+	if r.Forward != nil {
+		forward := r.Forward.From
+		if r.Forward.Date > 0 {
+			forward = strings.TrimSpace(forward + ", " + FormatDate(r.Forward.Date))
+		}
+		line("Переслано от", forward)
+	}
+	if r.Reply != nil {
+		reply := fmt.Sprintf("сообщение %d", r.Reply.MessageID)
+		if r.Reply.SenderName != "" {
+			reply += " от " + r.Reply.SenderName
+		}
+		if r.Reply.Text != "" {
+			reply += ": " + r.Reply.Text
+		}
+		line("Ответ на", reply)
+	}
+	line("Альбом", r.AlbumID)
+	if r.Version > 1 {
+		line("Версия", fmt.Sprintf("%d", r.Version))
+	}
+	if r.MessageID != 0 {
+		line("Сообщение", fmt.Sprintf("%d", r.MessageID))
+	}
+	if r.Caption != "" {
+		fmt.Fprintf(&b, "\n%s\n", r.Caption)
+	}
+	return b.String()
+}
+
+// Index - манифест папки: все загруженные в нее файлы с контекстом
+type Index struct {
+	Folder    string            `json:"folder"`
+	UpdatedAt string            `json:"updated_at"`
+	Files     []json.RawMessage `json:"files"`
+}
+
+// RenderIndex собирает index.json из сохраненных описаний файлов папки
+func RenderIndex(folder string, records []json.RawMessage) ([]byte, error) {
+	if records == nil {
+		records = []json.RawMessage{}
+	}
+	return json.MarshalIndent(Index{
+		Folder:    folder,
+		UpdatedAt: time.Now().UTC().Format(time.RFC3339),
+		Files:     records,
+	}, "", "  ")
+}
@@ -0,0 +1,170 @@
+package tgexport
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// ErrNoResult - в архиве нет result.json
+var ErrNoResult = errors.New("result.json not found in archive, export the chat in JSON format")
+
+// ErrResultTooLarge - result.json больше maxResultSize
+var ErrResultTooLarge = errors.New("result.json is too large")
+
+// maxResultSize - наибольший размер result.json. Экспорт разбирается в
+// память целиком, поэтому сжатый в маленький архив огромный result.json
+// не должен исчерпать память бота.
+var maxResultSize int64 = 512 << 20
+
+// ErrFileTooLarge - файл в архиве больше лимита на один файл
+var ErrFileTooLarge = errors.New("file in archive is too large")
+
+// ErrTotalTooLarge - распакованные файлы превысили общий лимит импорта
+var ErrTotalTooLarge = errors.New("archive total extracted size limit exceeded")
+
+// Archive - zip-архив экспорта Telegram Desktop
+type Archive struct {
+	Export *Export
+
+	zip   *zip.ReadCloser
+	root  string // Папка внутри архива, в которой лежит result.json
+	files map[string]*zip.File
+
+	maxFileSize  int64 // 0 - без ограничения
+	maxTotalSize int64 // 0 - без ограничения
+	extracted    int64
+}
+
+// OpenArchive открывает архив и разбирает result.json. Экспорт может лежать
+// как в корне архива, так и в одной вложенной папке (ChatExport_...).
+func OpenArchive(zipPath string) (*Archive, error) {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+
+	a := &Archive{zip: r, files: make(map[string]*zip.File, len(r.File))}
+	var result *zip.File
+	for _, f := range r.File {
+		name := strings.TrimPrefix(path.Clean("/"+f.Name), "/")
+		a.files[name] = f
+		if path.Base(name) == ResultFileName && strings.Count(name, "/") <= 1 &&
+			(result == nil || len(name) < len(result.Name)) {
+			result = f
+			a.root = path.Dir(name)
+		}
+	}
+	if result == nil {
+		r.Close()
+		return nil, ErrNoResult
+	}
+
+	if result.UncompressedSize64 > uint64(maxResultSize) {
+		r.Close()
+		return nil, fmt.Errorf("%w: %d bytes", ErrResultTooLarge, result.UncompressedSize64)
+	}
+
+	rc, err := result.Open()
+	if err != nil {
+		r.Close()
+		return nil, fmt.Errorf("failed to open %s: %w", ResultFileName, err)
+	}
+	defer rc.Close()
+
+	// Размер в заголовке zip может не совпадать с настоящим
+	limited := &io.LimitedReader{R: rc, N: maxResultSize + 1}
+	a.Export = &Export{}
+	if err := json.NewDecoder(limited).Decode(a.Export); err != nil {
+		r.Close()
+		if limited.N <= 0 {
+			return nil, ErrResultTooLarge
+		}
+		return nil, fmt.Errorf("failed to parse %s: %w", ResultFileName, err)
+	}
+	return a, nil
+}
+
+// Close закрывает архив
+func (a *Archive) Close() error {
+	return a.zip.Close()
+}
+
+// SetLimits ограничивает размер одного распакованного файла и суммарный
+// размер всех файлов, распакованных из архива. Размеры в заголовках zip
+// не проверяются на честность, поэтому лимиты соблюдаются и при копировании.
+func (a *Archive) SetLimits(maxFileSize, maxTotalSize int64) {
+	a.maxFileSize = maxFileSize
+	a.maxTotalSize = maxTotalSize
+}
+
+// Extract распаковывает файл экспорта по относительному пути в dst и
+// возвращает его размер и SHA1 в том же виде, в каком его считает облако.
+// Путь ищется только среди файлов архива, поэтому выйти за пределы dst нельзя.
+func (a *Archive) Extract(relPath, dst string) (size int64, contentHash string, err error) {
+	name := strings.TrimPrefix(path.Clean("/"+path.Join(a.root, relPath)), "/")
+	f, ok := a.files[name]
+	if !ok {
+		return 0, "", fmt.Errorf("file %s is not in archive", relPath)
+	}
+
+	limit := int64(-1)
+	if a.maxFileSize > 0 {
+		if f.UncompressedSize64 > uint64(a.maxFileSize) {
+			return 0, "", fmt.Errorf("%s: %w", relPath, ErrFileTooLarge)
+		}
+		limit = a.maxFileSize
+	}
+	if a.maxTotalSize > 0 {
+		left := a.maxTotalSize - a.extracted
+		if f.UncompressedSize64 > uint64(max(left, 0)) {
+			return 0, "", fmt.Errorf("%s: %w", relPath, ErrTotalTooLarge)
+		}
+		if limit < 0 || left < limit {
+			limit = left
+		}
+	}
+
+	src, err := f.Open()
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to open %s in archive: %w", relPath, err)
+	}
+	defer src.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+
+	var reader io.Reader = src
+	if limit >= 0 {
+		// Лишний байт показывает, что файл больше, чем указано в заголовке
+		reader = io.LimitReader(src, limit+1)
+	}
+
+	hasher := sha1.New()
+	size, err = io.Copy(io.MultiWriter(out, hasher), reader)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && limit >= 0 && size > limit {
+		err = ErrFileTooLarge
+		if a.maxFileSize <= 0 || size <= a.maxFileSize {
+			err = ErrTotalTooLarge
+		}
+	}
+	if err != nil {
+		os.Remove(dst)
+		return 0, "", fmt.Errorf("failed to extract %s: %w", relPath, err)
+	}
+	a.extracted += size
+
+	return size, strings.ToUpper(hex.EncodeToString(hasher.Sum(nil))), nil
+}
@@ -0,0 +1,140 @@
+package tgexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testResult = `{"name": "Отпуск", "type": "private_supergroup", "id": 1, "messages": [
+	{"id": 1, "type": "message", "photo": "photos/a.jpg", "text": ""},
+	{"id": 2, "type": "message", "file": "files/b.bin", "text": ""}
+]}`
+
+// writeArchive создает zip экспорта с файлами в папке ChatExport
+func writeArchive(t *testing.T, files map[string][]byte) string {
+	t.Helper()
+	zipPath := filepath.Join(t.TempDir(), "export.zip")
+	f, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		fw, err := w.Create("ChatExport_2024-01-01/" + name)
+		if err != nil {
+			t.Fatalf("zip create: %v", err)
+		}
+		if _, err := fw.Write(content); err != nil {
+			t.Fatalf("zip write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+	return zipPath
+}
+
+func openTestArchive(t *testing.T) *Archive {
+	t.Helper()
+	a, err := OpenArchive(writeArchive(t, map[string][]byte{
+		ResultFileName: []byte(testResult),
+		"photos/a.jpg": bytes.Repeat([]byte{1}, 100),
+		"files/b.bin":  bytes.Repeat([]byte{2}, 100),
+	}))
+	if err != nil {
+		t.Fatalf("OpenArchive: %v", err)
+	}
+	t.Cleanup(func() { a.Close() })
+	return a
+}
+
+func TestOpenArchive(t *testing.T) {
+	a := openTestArchive(t)
+	if a.Export.Name != "Отпуск" || len(a.Export.Messages) != 2 {
+		t.Fatalf("export = %+v", a.Export)
+	}
+}
+
+func TestOpenArchiveWithoutResult(t *testing.T) {
+	_, err := OpenArchive(writeArchive(t, map[string][]byte{"photos/a.jpg": {1}}))
+	if !errors.Is(err, ErrNoResult) {
+		t.Fatalf("err = %v, want ErrNoResult", err)
+	}
+}
+
+func TestOpenArchiveResultTooLarge(t *testing.T) {
+	defer func(size int64) { maxResultSize = size }(maxResultSize)
+	maxResultSize = 16
+
+	_, err := OpenArchive(writeArchive(t, map[string][]byte{ResultFileName: []byte(testResult)}))
+	if !errors.Is(err, ErrResultTooLarge) {
+		t.Fatalf("err = %v, want ErrResultTooLarge", err)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	a := openTestArchive(t)
+	dst := filepath.Join(t.TempDir(), "a.jpg")
+
+	size, hash, err := a.Extract("photos/a.jpg", dst)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if size != 100 || len(hash) != 40 {
+		t.Fatalf("size = %d, hash = %q", size, hash)
+	}
+	if data, _ := os.ReadFile(dst); !bytes.Equal(data, bytes.Repeat([]byte{1}, 100)) {
+		t.Fatal("extracted content differs")
+	}
+
+	// Выйти из папки экспорта нельзя: ищутся только файлы архива
+	if _, _, err := a.Extract("../../etc/passwd", filepath.Join(t.TempDir(), "x")); err == nil {
+		t.Fatal("Extract outside of archive succeeded")
+	}
+}
+
+func TestExtractLimits(t *testing.T) {
+	t.Run("file too large", func(t *testing.T) {
+		a := openTestArchive(t)
+		a.SetLimits(50, 0)
+		dst := filepath.Join(t.TempDir(), "a.jpg")
+
+		if _, _, err := a.Extract("photos/a.jpg", dst); !errors.Is(err, ErrFileTooLarge) {
+			t.Fatalf("err = %v, want ErrFileTooLarge", err)
+		}
+		if _, err := os.Stat(dst); !os.IsNotExist(err) {
+			t.Fatal("rejected file was written")
+		}
+	})
+
+	t.Run("total too large", func(t *testing.T) {
+		a := openTestArchive(t)
+		a.SetLimits(100, 150)
+		dir := t.TempDir()
+
+		if _, _, err := a.Extract("photos/a.jpg", filepath.Join(dir, "a.jpg")); err != nil {
+			t.Fatalf("first Extract: %v", err)
+		}
+		if _, _, err := a.Extract("files/b.bin", filepath.Join(dir, "b.bin")); !errors.Is(err, ErrTotalTooLarge) {
+			t.Fatalf("err = %v, want ErrTotalTooLarge", err)
+		}
+	})
+
+	t.Run("within limits", func(t *testing.T) {
+		a := openTestArchive(t)
+		a.SetLimits(100, 200)
+		dir := t.TempDir()
+
+		for _, name := range []string{"photos/a.jpg", "files/b.bin"} {
+			if _, _, err := a.Extract(name, filepath.Join(dir, filepath.Base(name))); err != nil {
+				t.Fatalf("Extract %s: %v", name, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package tgexport
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResultFileName - файл с сообщениями в экспорте Telegram Desktop
+const ResultFileName = "result.json"
+
+// Значения media_type в экспорте
+const (
+	MediaVideoFile    = "video_file"
+	MediaAnimation    = "animation"
+	MediaVideoMessage = "video_message"
+	MediaVoiceMessage = "voice_message"
+	MediaAudioFile    = "audio_file"
+	MediaSticker      = "sticker"
+)
+
+// exportDateLayout - формат поля date; время локальное для экспортировавшего
+const exportDateLayout = "2006-01-02T15:04:05"
+
+// Export - содержимое result.json экспорта одного чата
+type Export struct {
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`
+	ID       int64     `json:"id"`
+	Messages []Message `json:"messages"`
+}
+
+// Message - сообщение экспорта. Медиа лежит по относительному пути в Photo или File.
+type Message struct {
+	ID        int             `json:"id"`
+	Type      string          `json:"type"` // message или service
+	Date      string          `json:"date"`
+	DateUnix  string          `json:"date_unixtime"`
+	From      string          `json:"from"`
+	FromID    string          `json:"from_id"` // user123, channel123
+	Text      json.RawMessage `json:"text"`    // Строка или массив строк и объектов с полем text
+	Photo     string          `json:"photo"`
+	File      string          `json:"file"`
+	FileName  string          `json:"file_name"`
+	MediaType string          `json:"media_type"`
+	MimeType  string          `json:"mime_type"`
+	Width     int             `json:"width"`
+	Height    int             `json:"height"`
+	Duration  int             `json:"duration_seconds"`
+}
+
+// MediaPath возвращает относительный путь к файлу медиа или пустую строку,
+// если медиа нет или файл не был включен в экспорт
+func (m *Message) MediaPath() string {
+	p := m.File
+	if m.Photo != "" {
+		p = m.Photo
+	}
+	// Не выгруженные файлы экспорт помечает текстом в скобках
+	if p == "" || strings.HasPrefix(p, "(") {
+		return ""
+	}
+	return p
+}
+
+// Unix возвращает время сообщения; 0 - время не удалось разобрать
+func (m *Message) Unix() int64 {
+	if v, err := strconv.ParseInt(m.DateUnix, 10, 64); err == nil {
+		return v
+	}
+	if t, err := time.ParseInLocation(exportDateLayout, m.Date, time.Local); err == nil {
+		return t.Unix()
+	}
+	return 0
+}
+
+// SenderID возвращает числовой ID отправителя из from_id
+func (m *Message) SenderID() int64 {
+	id := strings.TrimLeft(m.FromID, "abcdefghijklmnopqrstuvwxyz")
+	v, _ := strconv.ParseInt(id, 10, 64)
+	return v
+}
+
+// Caption возвращает текст сообщения без форматирования
+func (m *Message) Caption() string {
+	if len(m.Text) == 0 {
+		return ""
+	}
+
+	var plain string
+	if err := json.Unmarshal(m.Text, &plain); err == nil {
+		return plain
+	}
+
+	var parts []json.RawMessage
+	if err := json.Unmarshal(m.Text, &parts); err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, part := range parts {
+		var s string
+		if err := json.Unmarshal(part, &s); err == nil {
+			b.WriteString(s)
+			continue
+		}
+		var entity struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(part, &entity); err == nil {
+			b.WriteString(entity.Text)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,49 @@
+package tgexport
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMessageCaption(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"empty", ``, ""},
+		{"string", `"Море 🌊"`, "Море 🌊"},
+		{"array", `["Смотрите ", {"type": "bold", "text": "сюда"}, " и #отпуск"]`, "Смотрите сюда и #отпуск"},
+		{"array with link", `[{"type": "link", "text": "https://example.com"}]`, "https://example.com"},
+		{"unexpected type", `42`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Message{Text: json.RawMessage(tt.text)}
+			if got := m.Caption(); got != tt.want {
+				t.Fatalf("Caption() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageMediaPath(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Message
+		want string
+	}{
+		{"photo", Message{Photo: "photos/photo_1.jpg"}, "photos/photo_1.jpg"},
+		{"file", Message{File: "files/doc.pdf"}, "files/doc.pdf"},
+		{"no media", Message{}, ""},
+		{"file not included", Message{File: "(File not included. Change data exporting settings to download.)"}, ""},
+		{"photo not included", Message{Photo: "(File not included. Change data exporting settings to download.)"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.MediaPath(); got != tt.want {
+				t.Fatalf("MediaPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package domain
+
+import "errors"
+
+// PermanentError - ошибка, которую повтор не исправит.
+// Такое задание сразу уходит в dead letter.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent помечает ошибку как постоянную
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent сообщает, помечена ли ошибка как постоянная
+func IsPermanent(err error) bool {
+	var permanentErr *PermanentError
+	return errors.As(err, &permanentErr)
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"mail_helper_bot/internal/pkg/media"
+	"time"
+)
+
+const (
+	JobStatusQueued  = "queued"
+	JobStatusRunning = "running"
+	JobStatusDone    = "done"
+	JobStatusFailed  = "failed"
+)
+
+type UploadJob struct {
+	ID         int64            `json:"id"`
+	GroupID    int64            `json:"group_id"`
+	Status     string           `json:"status"` // "queued", "running", "done", "failed"
+	Media      *media.MediaInfo `json:"media"`
+	Attempts   int              `json:"attempts"`
+	LastError  string           `json:"last_error"`
+	RunAt      time.Time        `json:"run_at"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+	StartedAt  *time.Time       `json:"started_at"`
+	FinishedAt *time.Time       `json:"finished_at"`
+}
+
+// DeadLetter - загрузка, исчерпавшая попытки или упавшая с постоянной ошибкой
+type DeadLetter struct {
+	ID         int64            `json:"id"`
+	JobID      int64            `json:"job_id"`
+	GroupID    int64            `json:"group_id"`
+	GroupTitle string           `json:"group_title"`
+	Media      *media.MediaInfo `json:"media"`
+	Attempts   int              `json:"attempts"`
+	LastError  string           `json:"last_error"`
+	Permanent  bool             `json:"permanent"`
+	FailedAt   time.Time        `json:"failed_at"`
+}
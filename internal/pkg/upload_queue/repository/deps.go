@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"mail_helper_bot/internal/pkg/upload_queue/domain"
+	"time"
+)
+
+type QueueRepository interface {
+	// Enqueue ставит задание в очередь и возвращает false,
+	// если этот файл уже ждет загрузки в ту же группу
+	Enqueue(job *domain.UploadJob) (bool, error)
+	// IsCloudPathPending сообщает, ждет ли загрузки в группу файл с таким путем
+	IsCloudPathPending(cloudPath string, groupID int64) (bool, error)
+	// ClaimNext забирает следующее задание из очереди и переводит его в running.
+	// Возвращает nil, если свободных заданий нет.
+	ClaimNext() (*domain.UploadJob, error)
+	MarkDone(jobID int64) error
+	// Retry возвращает задание в очередь с отложенным запуском
+	Retry(jobID int64, errMsg string, runAt time.Time) error
+	// MoveToDeadLetter помечает задание failed и записывает его в dead letter
+	MoveToDeadLetter(job *domain.UploadJob, errMsg string, permanent bool) error
+	// RequeueRunning возвращает в очередь задания, прерванные остановкой процесса
+	RequeueRunning() (int64, error)
+
+	GetOwnerDeadLetters(ownerChatID int64) ([]*domain.DeadLetter, error)
+	// CountGroupDeadLetters считает загрузки группы, ушедшие в dead letter с момента since
+	CountGroupDeadLetters(groupID int64, since time.Time) (int, error)
+	// RequeueDeadLetter возвращает в очередь задание из dead letter, если его группа принадлежит владельцу.
+	// requeued - задание снова в очереди, resolved - запись dead letter удалена: без повтора,
+	// если файл уже ждет загрузки или пришел из импорта.
+	RequeueDeadLetter(deadLetterID, ownerChatID int64) (requeued, resolved bool, err error)
+	RequeueOwnerDeadLetters(ownerChatID int64) (int64, error)
+}
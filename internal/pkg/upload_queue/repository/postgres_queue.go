@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"mail_helper_bot/internal/pkg/upload_queue/domain"
+	"time"
+)
+
+type QueueStorage struct {
+	db *sql.DB
+}
+
+func NewQueueStorage(db *sql.DB) *QueueStorage {
+	return &QueueStorage{db: db}
+}
+
+func (q *QueueStorage) Enqueue(job *domain.UploadJob) (bool, error) {
+	payload, err := json.Marshal(job.Media)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal job payload: %v", err)
+	}
+
+	row := q.db.QueryRow(`
+        INSERT INTO upload_jobs (group_id, file_unique_id, cloud_path, status, payload)
+        VALUES ($1, NULLIF($2, ''), $3, 'queued', $4)
+        ON CONFLICT (group_id, file_unique_id) WHERE status IN ('queued', 'running') DO NOTHING
+        RETURNING id, status, run_at, created_at
+    `, job.GroupID, job.Media.FileUniqueID, job.Media.CloudFilePath(), payload)
+
+	err = row.Scan(&job.ID, &job.Status, &job.RunAt, &job.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (q *QueueStorage) IsCloudPathPending(cloudPath string, groupID int64) (bool, error) {
+	row := q.db.QueryRow(`
+        SELECT EXISTS(
+            SELECT 1 FROM upload_jobs
+            WHERE cloud_path = $1 AND group_id = $2 AND status IN ('queued', 'running')
+        )
+    `, cloudPath, groupID)
+
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+func (q *QueueStorage) ClaimNext() (*domain.UploadJob, error) {
+	// SKIP LOCKED позволяет нескольким воркерам разбирать очередь параллельно,
+	// не блокируя друг друга на одной и той же строке
+	row := q.db.QueryRow(`
+        UPDATE upload_jobs
+        SET status = 'running',
+            attempts = attempts + 1,
+            started_at = now(),
+            updated_at = now()
+        WHERE id = (
+            SELECT id FROM upload_jobs
+            WHERE status = 'queued' AND run_at <= now()
+            ORDER BY run_at, id
+            LIMIT 1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING id, group_id, status, payload, attempts, COALESCE(last_error, ''),
+                  run_at, created_at, updated_at, started_at, finished_at
+    `)
+
+	job := &domain.UploadJob{}
+	var payload []byte
+	err := row.Scan(&job.ID, &job.GroupID, &job.Status, &payload, &job.Attempts, &job.LastError,
+		&job.RunAt, &job.CreatedAt, &job.UpdatedAt, &job.StartedAt, &job.FinishedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(payload, &job.Media); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job %d payload: %v", job.ID, err)
+	}
+	return job, nil
+}
+
+func (q *QueueStorage) MarkDone(jobID int64) error {
+	_, err := q.db.Exec(`
+        UPDATE upload_jobs
+        SET status = 'done',
+            last_error = NULL,
+            finished_at = now()
+        WHERE id = $1
+    `, jobID)
+	return err
+}
+
+func (q *QueueStorage) Retry(jobID int64, errMsg string, runAt time.Time) error {
+	_, err := q.db.Exec(`
+        UPDATE upload_jobs
+        SET status = 'queued',
+            last_error = $2,
+            run_at = $3,
+            started_at = NULL
+        WHERE id = $1
+    `, jobID, errMsg, runAt)
+	return err
+}
+
+func (q *QueueStorage) MoveToDeadLetter(job *domain.UploadJob, errMsg string, permanent bool) error {
+	payload, err := json.Marshal(job.Media)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job payload: %v", err)
+	}
+
+	tx, err := q.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+        UPDATE upload_jobs
+        SET status = 'failed',
+            last_error = $2,
+            finished_at = now()
+        WHERE id = $1
+    `, job.ID, errMsg)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO upload_dead_letters (job_id, group_id, payload, attempts, last_error, permanent)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (job_id) DO UPDATE
+        SET attempts = $4,
+            last_error = $5,
+            permanent = $6,
+            failed_at = now()
+    `, job.ID, job.GroupID, payload, job.Attempts, errMsg, permanent)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (q *QueueStorage) RequeueRunning() (int64, error) {
+	res, err := q.db.Exec(`
+        UPDATE upload_jobs
+        SET status = 'queued',
+            started_at = NULL
+        WHERE status = 'running'
+    `)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (q *QueueStorage) CountGroupDeadLetters(groupID int64, since time.Time) (int, error) {
+	var count int
+	err := q.db.QueryRow(`
+        SELECT COUNT(*) FROM upload_dead_letters
+        WHERE group_id = $1 AND failed_at >= $2
+    `, groupID, since).Scan(&count)
+	return count, err
+}
+
+func (q *QueueStorage) GetOwnerDeadLetters(ownerChatID int64) ([]*domain.DeadLetter, error) {
+	rows, err := q.db.Query(`
+        SELECT d.id, d.job_id, d.group_id, g.group_title, d.payload, d.attempts,
+               COALESCE(d.last_error, ''), d.permanent, d.failed_at
+        FROM upload_dead_letters d
+        JOIN group_sessions g ON g.group_id = d.group_id
+        WHERE g.owner_chat_id = $1
+        ORDER BY d.failed_at DESC
+    `, ownerChatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var letters []*domain.DeadLetter
+	for rows.Next() {
+		d := &domain.DeadLetter{}
+		var payload []byte
+		err := rows.Scan(&d.ID, &d.JobID, &d.GroupID, &d.GroupTitle, &payload, &d.Attempts,
+			&d.LastError, &d.Permanent, &d.FailedAt)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(payload, &d.Media); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal dead letter %d payload: %v", d.ID, err)
+		}
+		letters = append(letters, d)
+	}
+	return letters, rows.Err()
+}
+
+func (q *QueueStorage) RequeueDeadLetter(deadLetterID, ownerChatID int64) (bool, bool, error) {
+	requeued, resolved, err := q.requeueDeadLetters(`d.id = $1 AND g.owner_chat_id = $2`, deadLetterID, ownerChatID)
+	return requeued > 0, resolved > 0, err
+}
+
+func (q *QueueStorage) RequeueOwnerDeadLetters(ownerChatID int64) (int64, error) {
+	requeued, _, err := q.requeueDeadLetters(`g.owner_chat_id = $1`, ownerChatID)
+	return requeued, err
+}
+
+// requeueDeadLetters удаляет записи dead letter, подходящие под условие
+// condition (d - dead letter, g - его группа), и возвращает их задания в очередь
+// с обнуленным счетчиком попыток. Файл, который уже ждет загрузки, и повторы
+// одного файла в очередь не ставятся - их записи просто удаляются: иначе
+// уникальный индекс idx_upload_jobs_pending_file сорвал бы весь запрос.
+// Файлы импорта тоже не повторяются: распакованный файл удален вместе с
+// переносом в dead letter, и повтор упал бы на чтении.
+// Возвращает число поставленных в очередь заданий и удаленных записей.
+func (q *QueueStorage) requeueDeadLetters(condition string, args ...interface{}) (int64, int64, error) {
+	var requeued, resolved int64
+	err := q.db.QueryRow(`
+        WITH candidates AS (
+            SELECT d.id, d.job_id, j.group_id, j.file_unique_id,
+                   COALESCE(d.payload->>'local_path', '') <> '' AS imported
+            FROM upload_dead_letters d
+            JOIN group_sessions g ON g.group_id = d.group_id
+            JOIN upload_jobs j ON j.id = d.job_id
+            WHERE `+condition+`
+        ),
+        chosen AS (
+            SELECT DISTINCT ON (c.group_id, COALESCE(c.file_unique_id, 'job:' || c.job_id))
+                   c.job_id
+            FROM candidates c
+            WHERE NOT c.imported AND (c.file_unique_id IS NULL OR NOT EXISTS (
+                SELECT 1 FROM upload_jobs p
+                WHERE p.group_id = c.group_id
+                  AND p.file_unique_id = c.file_unique_id
+                  AND p.status IN ('queued', 'running')
+            ))
+            ORDER BY c.group_id, COALESCE(c.file_unique_id, 'job:' || c.job_id), c.job_id DESC
+        ),
+        deleted AS (
+            DELETE FROM upload_dead_letters
+            WHERE id IN (SELECT id FROM candidates)
+            RETURNING id
+        ),
+        updated AS (
+            UPDATE upload_jobs
+            SET status = 'queued',
+                attempts = 0,
+                last_error = NULL,
+                run_at = now(),
+                started_at = NULL,
+                finished_at = NULL
+            WHERE id IN (SELECT job_id FROM chosen)
+            RETURNING id
+        )
+        SELECT (SELECT COUNT(*) FROM updated), (SELECT COUNT(*) FROM deleted)
+    `, args...).Scan(&requeued, &resolved)
+	return requeued, resolved, err
+}
@@ -0,0 +1,30 @@
+package worker
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	backoffBase = 30 * time.Second
+	backoffMax  = 30 * time.Minute
+)
+
+// Backoff возвращает задержку перед повтором после attempt неудачных попыток:
+// экспоненциальный рост от backoffBase до backoffMax со случайным разбросом
+// в нижнюю половину интервала, чтобы повторы разных заданий не совпадали
+func Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := backoffMax
+	if attempt <= 16 {
+		if d := backoffBase << uint(attempt-1); d < backoffMax {
+			delay = d
+		}
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
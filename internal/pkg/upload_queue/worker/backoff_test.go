@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{-1, 30 * time.Second},
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{5, 8 * time.Minute},
+		{6, 16 * time.Minute},
+		{7, 30 * time.Minute},
+		{16, 30 * time.Minute},
+		{17, 30 * time.Minute},
+		{1000, 30 * time.Minute},
+	}
+	for _, tt := range tests {
+		// Разброс случайный, поэтому проверяется много значений
+		for i := 0; i < 200; i++ {
+			if got := Backoff(tt.attempt); got < tt.max/2 || got > tt.max {
+				t.Fatalf("Backoff(%d) = %v, want between %v and %v", tt.attempt, got, tt.max/2, tt.max)
+			}
+		}
+	}
+}
+
+func TestBackoffGrows(t *testing.T) {
+	// Нижняя граница следующей попытки равна верхней границе предыдущей,
+	// пока задержка не упрется в backoffMax
+	for attempt := 1; attempt < 6; attempt++ {
+		for i := 0; i < 200; i++ {
+			if prev, next := Backoff(attempt), Backoff(attempt+1); next < prev {
+				t.Fatalf("Backoff(%d) = %v is less than Backoff(%d) = %v", attempt+1, next, attempt, prev)
+			}
+		}
+	}
+}
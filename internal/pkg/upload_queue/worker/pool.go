@@ -0,0 +1,169 @@
+package worker
+
+import (
+	"log"
+	"mail_helper_bot/internal/pkg/upload_queue/domain"
+	"mail_helper_bot/internal/pkg/upload_queue/repository"
+	"sync"
+	"time"
+)
+
+const (
+	pollInterval = 5 * time.Second
+
+	// DefaultMaxAttempts - сколько раз задание пробуют выполнить до переноса в dead letter
+	DefaultMaxAttempts = 5
+)
+
+// Handler выполняет одно задание на загрузку
+type Handler func(job *domain.UploadJob) error
+
+// DeadLetterHandler вызывается, когда задание окончательно не удалось выполнить
+type DeadLetterHandler func(job *domain.UploadJob, err error)
+
+// Pool - набор фоновых воркеров, разбирающих очередь upload_jobs
+type Pool struct {
+	repo        repository.QueueRepository
+	handler     Handler
+	onDead      DeadLetterHandler
+	workers     int
+	maxAttempts int
+
+	wakeup chan struct{}
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func NewPool(repo repository.QueueRepository, handler Handler, workers int) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &Pool{
+		repo:        repo,
+		handler:     handler,
+		workers:     workers,
+		maxAttempts: DefaultMaxAttempts,
+		wakeup:      make(chan struct{}, workers),
+		stop:        make(chan struct{}),
+	}
+}
+
+// SetMaxAttempts задает число попыток до переноса задания в dead letter
+func (p *Pool) SetMaxAttempts(maxAttempts int) {
+	if maxAttempts > 0 {
+		p.maxAttempts = maxAttempts
+	}
+}
+
+// SetDeadLetterHandler задает обработчик заданий, перенесенных в dead letter
+func (p *Pool) SetDeadLetterHandler(handler DeadLetterHandler) {
+	p.onDead = handler
+}
+
+// Start возвращает в очередь задания, прерванные прошлой остановкой, и запускает воркеры
+func (p *Pool) Start() {
+	requeued, err := p.repo.RequeueRunning()
+	if err != nil {
+		log.Printf("Error requeueing interrupted upload jobs: %v", err)
+	} else if requeued > 0 {
+		log.Printf("Requeued %d interrupted upload jobs", requeued)
+	}
+
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.run(i)
+	}
+
+	log.Printf("Upload worker pool started with %d workers", p.workers)
+}
+
+// Stop дожидается завершения текущих заданий и останавливает воркеры
+func (p *Pool) Stop() {
+	close(p.stop)
+	p.wg.Wait()
+}
+
+// Notify будит свободный воркер, не дожидаясь очередного опроса очереди
+func (p *Pool) Notify() {
+	select {
+	case p.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Pool) run(workerID int) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Разбираем очередь, пока в ней есть задания
+		for p.processNext(workerID) {
+			select {
+			case <-p.stop:
+				return
+			default:
+			}
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-p.wakeup:
+		case <-ticker.C:
+		}
+	}
+}
+
+// processNext выполняет одно задание и сообщает, было ли что выполнять
+func (p *Pool) processNext(workerID int) bool {
+	job, err := p.repo.ClaimNext()
+	if err != nil {
+		log.Printf("Worker %d: error claiming upload job: %v", workerID, err)
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	log.Printf("Worker %d: processing upload job %d (attempt %d)", workerID, job.ID, job.Attempts)
+
+	if err := p.handler(job); err != nil {
+		p.handleFailure(workerID, job, err)
+		return true
+	}
+
+	if err := p.repo.MarkDone(job.ID); err != nil {
+		log.Printf("Worker %d: error marking job %d done: %v", workerID, job.ID, err)
+	}
+	return true
+}
+
+// handleFailure откладывает повтор временной ошибки или переносит задание в dead letter
+func (p *Pool) handleFailure(workerID int, job *domain.UploadJob, jobErr error) {
+	permanent := domain.IsPermanent(jobErr)
+
+	if !permanent && job.Attempts < p.maxAttempts {
+		delay := Backoff(job.Attempts)
+		log.Printf("Worker %d: upload job %d failed (attempt %d/%d), retry in %s: %v",
+			workerID, job.ID, job.Attempts, p.maxAttempts, delay, jobErr)
+
+		if err := p.repo.Retry(job.ID, jobErr.Error(), time.Now().Add(delay)); err != nil {
+			log.Printf("Worker %d: error scheduling retry of job %d: %v", workerID, job.ID, err)
+		}
+		return
+	}
+
+	log.Printf("Worker %d: upload job %d moved to dead letter (attempts=%d, permanent=%t): %v",
+		workerID, job.ID, job.Attempts, permanent, jobErr)
+
+	if err := p.repo.MoveToDeadLetter(job, jobErr.Error(), permanent); err != nil {
+		log.Printf("Worker %d: error moving job %d to dead letter: %v", workerID, job.ID, err)
+	}
+
+	if p.onDead != nil {
+		p.onDead(job, jobErr)
+	}
+}
@@ -10,9 +10,10 @@ import (
 
 // todo: прописать нормальные интерфейсы
 type WebServer struct {
-	oauth  *oauth_service.OAuthService
-	botAPI *tgbotapi.BotAPI
-	port   string
+	oauth   *oauth_service.OAuthService
+	botAPI  *tgbotapi.BotAPI
+	port    string
+	onLogin func(chatID int64)
 }
 
 func NewWebServer(oauth *oauth_service.OAuthService, botAPI *tgbotapi.BotAPI, port string) *WebServer {
@@ -23,6 +24,11 @@ func NewWebServer(oauth *oauth_service.OAuthService, botAPI *tgbotapi.BotAPI, po
 	}
 }
 
+// SetLoginHandler задает обработчик успешной авторизации пользователя
+func (ws *WebServer) SetLoginHandler(handler func(chatID int64)) {
+	ws.onLogin = handler
+}
+
 // todo: вынести в отдельный main и ручки в router
 func (ws *WebServer) Start() error {
 	http.HandleFunc("/oauth/callback/", ws.handleOAuthCallback)
@@ -91,6 +97,10 @@ func (ws *WebServer) handleOAuthCallback(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if ws.onLogin != nil {
+		ws.onLogin(chatID)
+	}
+
 	successMsg := fmt.Sprintf("✅ Авторизация успешна!\n\n👤 Имя: %s\n📧 Email: %s",
 		userInfo.Name, userInfo.Email)
 